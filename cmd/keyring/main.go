@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/internal/jwt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Управление связкой ключей подписи токенов.
+
+Использование:
+  keyring <команда> [флаги]
+
+Команды:
+  generate  создать следующий ключ приложения
+  promote   сделать следующий ключ активным, прежний вывести
+  prune     удалить ключи, которые больше не принимаются
+  list      показать ключи связки
+`
+
+var errRequiredPath = errors.New("требуется путь к связке ключей: --keyring")
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch cmd {
+	case "generate":
+		err = generate(args)
+	case "promote":
+		err = promote(args)
+	case "prune":
+		err = prune(args)
+	case "list":
+		err = list(args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func generate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	path := keyringFlag(flags)
+	alg := flags.String("alg", jwt.AlgRS256, "алгоритм: RS256, ES256 или EdDSA")
+	appID := flags.Int("app-id", 0, "id приложения, 0 — ключ по умолчанию")
+	_ = flags.Parse(args)
+
+	m, err := readManifest(*path)
+	if err != nil {
+		return err
+	}
+
+	key, err := m.Generate(*path, *alg, *appID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err = jwt.WriteManifest(*path, m); err != nil {
+		return err
+	}
+
+	if key.State == jwt.StateActive {
+		fmt.Printf("создан активный ключ %s (%s)\n", key.ID, key.Alg)
+		fmt.Println("подписывать токены приложения было нечем, поэтому он активен сразу")
+
+		return nil
+	}
+
+	fmt.Printf("создан следующий ключ %s (%s)\n", key.ID, key.Alg)
+	fmt.Println("он уже публикуется в JWKS; сделайте его активным командой promote")
+
+	return nil
+}
+
+func promote(args []string) error {
+	flags := flag.NewFlagSet("promote", flag.ExitOnError)
+	path := keyringFlag(flags)
+	appID := flags.Int("app-id", 0, "id приложения, 0 — ключ по умолчанию")
+	overlap := flags.Duration(
+		"overlap",
+		24*time.Hour,
+		"сколько еще принимать прежний ключ, не меньше token_ttl",
+	)
+	_ = flags.Parse(args)
+
+	if *path == "" {
+		return errRequiredPath
+	}
+
+	m, err := jwt.ReadManifest(*path)
+	if err != nil {
+		return err
+	}
+
+	if err = m.Promote(*appID, time.Now(), *overlap); err != nil {
+		return err
+	}
+
+	if err = jwt.WriteManifest(*path, m); err != nil {
+		return err
+	}
+
+	fmt.Println("следующий ключ стал активным")
+
+	return nil
+}
+
+func prune(args []string) error {
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	path := keyringFlag(flags)
+	_ = flags.Parse(args)
+
+	if *path == "" {
+		return errRequiredPath
+	}
+
+	m, err := jwt.ReadManifest(*path)
+	if err != nil {
+		return err
+	}
+
+	pruned := m.Prune(time.Now())
+	if len(pruned) == 0 {
+		fmt.Println("нет ключей для удаления")
+
+		return nil
+	}
+
+	if err = jwt.WriteManifest(*path, m); err != nil {
+		return err
+	}
+
+	for _, k := range pruned {
+		err = os.Remove(filepath.Join(filepath.Dir(*path), k.Path))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		fmt.Printf("удален ключ %s\n", k.ID)
+	}
+
+	return nil
+}
+
+func list(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	path := keyringFlag(flags)
+	_ = flags.Parse(args)
+
+	if *path == "" {
+		return errRequiredPath
+	}
+
+	m, err := jwt.ReadManifest(*path)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tALG\tAPP\tSTATE\tNOT BEFORE\tNOT AFTER")
+
+	for _, k := range m.Keys {
+		notAfter := "-"
+		if k.NotAfter != nil {
+			notAfter = k.NotAfter.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%d\t%s\t%s\t%s\n",
+			k.ID,
+			k.Alg,
+			k.AppID,
+			k.State,
+			k.NotBefore.Format(time.RFC3339),
+			notAfter,
+		)
+	}
+
+	return w.Flush()
+}
+
+func keyringFlag(flags *flag.FlagSet) *string {
+	return flags.String("keyring", "", "путь к манифесту связки ключей")
+}
+
+// readManifest читает манифест, а если его еще нет — возвращает пустой,
+// чтобы первой командой generate можно было создать связку с нуля.
+func readManifest(path string) (jwt.Manifest, error) {
+	if path == "" {
+		return jwt.Manifest{}, errRequiredPath
+	}
+
+	m, err := jwt.ReadManifest(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return jwt.Manifest{}, os.MkdirAll(filepath.Dir(path), 0o700)
+	}
+
+	return m, err
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage/sqlite"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Управление сервисными аккаунтами приложений.
+
+Использование:
+  serviceaccount <команда> [флаги]
+
+Команды:
+  create  создать аккаунт и выдать его client id и секрет
+  list    показать аккаунты
+  delete  удалить аккаунт, его токены перестанут приниматься
+`
+
+var errRequiredPath = errors.New("требуется путь к хранилищу: --storage-path")
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch cmd {
+	case "create":
+		err = create(args)
+	case "list":
+		err = list(args)
+	case "delete":
+		err = remove(args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func create(args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	path := storageFlag(flags)
+	appID := flags.Int("app-id", 0, "id приложения, которому принадлежит аккаунт")
+	name := flags.String("name", "", "название аккаунта")
+	scopes := flags.String("scopes", "", "разрешенные области через пробел")
+	_ = flags.Parse(args)
+
+	if *appID == 0 || *name == "" {
+		return errors.New("требуются --app-id и --name")
+	}
+
+	storage, err := openStorage(*path)
+	if err != nil {
+		return err
+	}
+
+	c := context.Background()
+
+	if _, err = storage.App(c, int32(*appID)); err != nil {
+		return err
+	}
+
+	clientID, err := newClientID()
+	if err != nil {
+		return err
+	}
+
+	secret, err := opaque.New()
+	if err != nil {
+		return err
+	}
+
+	_, err = storage.SaveServiceAccount(c, models.ServiceAccount{
+		AppID:      *appID,
+		Name:       *name,
+		ClientID:   clientID,
+		SecretHash: opaque.Hash(secret),
+		Scopes:     strings.Fields(*scopes),
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("client_id:     %s\n", clientID)
+	fmt.Printf("client_secret: %s\n", secret)
+	fmt.Println("секрет не хранится и больше не будет показан")
+
+	return nil
+}
+
+func list(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	path := storageFlag(flags)
+	appID := flags.Int("app-id", 0, "id приложения, 0 — все приложения")
+	_ = flags.Parse(args)
+
+	storage, err := openStorage(*path)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := storage.ServiceAccounts(context.Background(), *appID)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CLIENT ID\tAPP\tNAME\tSCOPES\tCREATED")
+
+	for _, a := range accounts {
+		fmt.Fprintf(
+			w,
+			"%s\t%d\t%s\t%s\t%s\n",
+			a.ClientID,
+			a.AppID,
+			a.Name,
+			strings.Join(a.Scopes, " "),
+			a.CreatedAt.Format(time.RFC3339),
+		)
+	}
+
+	return w.Flush()
+}
+
+func remove(args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ExitOnError)
+	path := storageFlag(flags)
+	clientID := flags.String("client-id", "", "client id аккаунта")
+	_ = flags.Parse(args)
+
+	if *clientID == "" {
+		return errors.New("требуется --client-id")
+	}
+
+	storage, err := openStorage(*path)
+	if err != nil {
+		return err
+	}
+
+	err = storage.DeleteServiceAccount(context.Background(), *clientID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("удален аккаунт %s\n", *clientID)
+
+	return nil
+}
+
+func storageFlag(flags *flag.FlagSet) *string {
+	return flags.String("storage-path", "", "путь к хранилищу")
+}
+
+func openStorage(path string) (*sqlite.Storage, error) {
+	if path == "" {
+		return nil, errRequiredPath
+	}
+
+	return sqlite.New(path)
+}
+
+// newClientID генерирует публичный идентификатор аккаунта.
+func newClientID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "svc-" + hex.EncodeToString(b), nil
+}
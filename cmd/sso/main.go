@@ -31,9 +31,10 @@ func main() {
 
 	log.Info("запуск приложения")
 
-	a := app.New(log, cfg.GRPC.Port, cfg.StoragePath, cfg.TokenTTL)
+	a := app.New(log, cfg)
 
 	go a.GRPCServer.MustRun()
+	go a.HTTPServer.MustRun()
 
 	// Graceful shutdown
 
@@ -44,6 +45,7 @@ func main() {
 
 	log.Info("остановка приложения", slog.String("signal", sign.String()))
 
+	a.HTTPServer.Stop()
 	a.GRPCServer.Stop()
 
 	log.Info("приложение остановленно")
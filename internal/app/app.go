@@ -1,31 +1,224 @@
 package app
 
 import (
+	"fmt"
 	grpcapp "github.com/h1lton/sso-grpc-ntc/internal/app/grpc"
+	httpapp "github.com/h1lton/sso-grpc-ntc/internal/app/http"
+	"github.com/h1lton/sso-grpc-ntc/internal/config"
+	"github.com/h1lton/sso-grpc-ntc/internal/grpc/ratelimit"
+	"github.com/h1lton/sso-grpc-ntc/internal/http/jwks"
+	"github.com/h1lton/sso-grpc-ntc/internal/http/oauth"
+	"github.com/h1lton/sso-grpc-ntc/internal/http/oidc"
+	"github.com/h1lton/sso-grpc-ntc/internal/jwt"
+	"github.com/h1lton/sso-grpc-ntc/internal/notifier/outbox"
+	"github.com/h1lton/sso-grpc-ntc/internal/notifier/smtp"
+	"github.com/h1lton/sso-grpc-ntc/internal/passwordhash"
+	"github.com/h1lton/sso-grpc-ntc/internal/passwordpolicy"
+	"github.com/h1lton/sso-grpc-ntc/internal/profileschema"
 	"github.com/h1lton/sso-grpc-ntc/internal/services/auth"
 	"github.com/h1lton/sso-grpc-ntc/internal/storage/sqlite"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
 	"log/slog"
+	"net/http"
 	"time"
 )
 
 type App struct {
 	GRPCServer *grpcapp.App
+	HTTPServer *httpapp.App
 }
 
-func New(
-	log *slog.Logger,
-	grpcPort int,
-	storagePath string,
-	tokenTTL time.Duration,
-) *App {
-	storage, err := sqlite.New(storagePath)
+func New(log *slog.Logger, cfg *config.Config) *App {
+	storage, err := sqlite.New(cfg.StoragePath)
 	if err != nil {
 		panic(err)
 	}
 
-	authService := auth.New(log, storage, storage, storage, tokenTTL)
+	keys, err := jwt.OpenKeyRing(cfg.Signing.KeyRing)
+	if err != nil {
+		panic(err)
+	}
+
+	if cfg.Signing.KeyRing != "" && cfg.Signing.ReloadInterval > 0 {
+		go reloadKeys(log, keys, cfg.Signing.ReloadInterval)
+	}
+
+	notifier, err := newNotifier(cfg.Notifier)
+	if err != nil {
+		panic(err)
+	}
+
+	policy, err := passwordpolicy.New(passwordpolicy.Config{
+		MinLength:           cfg.PasswordPolicy.MinLength,
+		MaxBytes:            cfg.PasswordPolicy.MaxBytes,
+		RequireLower:        cfg.PasswordPolicy.RequireLower,
+		RequireUpper:        cfg.PasswordPolicy.RequireUpper,
+		RequireDigit:        cfg.PasswordPolicy.RequireDigit,
+		RequireSymbol:       cfg.PasswordPolicy.RequireSymbol,
+		DisallowEmail:       cfg.PasswordPolicy.DisallowEmail,
+		CommonPasswordsPath: cfg.PasswordPolicy.CommonPasswordsPath,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	schema, err := profileschema.New(newProfileSchemaConfig(cfg.Profile))
+	if err != nil {
+		panic(err)
+	}
+
+	hasher, err := passwordhash.New(passwordhash.Config{
+		Algorithm:  cfg.PasswordHash.Algorithm,
+		BcryptCost: cfg.PasswordHash.BcryptCost,
+		Argon2: passwordhash.Argon2Config{
+			Memory:      cfg.PasswordHash.Argon2.Memory,
+			Iterations:  cfg.PasswordHash.Argon2.Iterations,
+			Parallelism: cfg.PasswordHash.Argon2.Parallelism,
+			SaltLength:  cfg.PasswordHash.Argon2.SaltLength,
+			KeyLength:   cfg.PasswordHash.Argon2.KeyLength,
+		},
+		Scrypt: passwordhash.ScryptConfig{
+			N:          cfg.PasswordHash.Scrypt.N,
+			R:          cfg.PasswordHash.Scrypt.R,
+			P:          cfg.PasswordHash.Scrypt.P,
+			SaltLength: cfg.PasswordHash.Scrypt.SaltLength,
+			KeyLength:  cfg.PasswordHash.Scrypt.KeyLength,
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
 
-	grpcApp := grpcapp.New(log, authService, grpcPort)
+	authService := auth.New(
+		log,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		notifier,
+		hasher,
+		keys,
+		auth.Config{
+			TokenTTL:                    cfg.TokenTTL,
+			RefreshTTL:                  cfg.RefreshTokenTTL,
+			AuthCodeTTL:                 cfg.OAuth.AuthCodeTTL,
+			Issuer:                      cfg.OAuth.Issuer,
+			TOTPIssuer:                  cfg.MFA.Issuer,
+			ChallengeTTL:                cfg.MFA.ChallengeTTL,
+			RequireAdminMFA:             cfg.MFA.RequireForAdmins,
+			PasswordResetTTL:            cfg.PasswordReset.TokenTTL,
+			PasswordResetURL:            cfg.PasswordReset.URL,
+			EmailVerificationTTL:        cfg.EmailVerification.TokenTTL,
+			EmailVerificationURL:        cfg.EmailVerification.URL,
+			InvitationTTL:               cfg.Invitation.TokenTTL,
+			InvitationURL:               cfg.Invitation.URL,
+			PasswordPolicy:              policy,
+			ProfileSchema:               schema,
+			EnumerationSafeRegistration: cfg.Registration.EnumerationSafe,
+			Lockout: auth.Lockout{
+				UserDelayAfter: cfg.Lockout.UserDelayAfter,
+				UserLockAfter:  cfg.Lockout.UserLockAfter,
+				IPDelayAfter:   cfg.Lockout.IPDelayAfter,
+				IPLockAfter:    cfg.Lockout.IPLockAfter,
+				BaseDelay:      cfg.Lockout.BaseDelay,
+				MaxDelay:       cfg.Lockout.MaxDelay,
+				LockDuration:   cfg.Lockout.LockDuration,
+				ResetAfter:     cfg.Lockout.ResetAfter,
+			},
+		},
+	)
 
-	return &App{GRPCServer: grpcApp}
+	limiter := ratelimit.New(log, newRateLimitConfig(cfg.RateLimit), storage)
+
+	grpcApp := grpcapp.New(log, authService, limiter, cfg.GRPC.Port)
+
+	mux := http.NewServeMux()
+	jwks.Register(mux, authService)
+	oauth.Register(mux, log, authService)
+	oidc.Register(mux, log, authService, cfg.OAuth.Issuer)
+
+	httpApp := httpapp.New(log, mux, cfg.HTTP.Port, cfg.HTTP.Timeout)
+
+	return &App{GRPCServer: grpcApp, HTTPServer: httpApp}
+}
+
+// newNotifier создает способ доставки писем, выбранный в конфиге.
+func newNotifier(cfg config.NotifierConfig) (auth.Notifier, error) {
+	switch cfg.Type {
+	case "smtp":
+		return smtp.New(smtp.Config{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+		}), nil
+	case "outbox":
+		return outbox.New(cfg.OutboxPath)
+	default:
+		return nil, fmt.Errorf("неизвестный способ доставки писем %q", cfg.Type)
+	}
+}
+
+// newProfileSchemaConfig переводит схему профиля из конфига
+// в настройки profileschema.
+func newProfileSchemaConfig(cfg config.ProfileConfig) profileschema.Config {
+	attributes := make(map[string]profileschema.Attribute, len(cfg.Attributes))
+	for name, attr := range cfg.Attributes {
+		attributes[name] = profileschema.Attribute{
+			Type:      attr.Type,
+			MaxLength: attr.MaxLength,
+			Values:    attr.Values,
+		}
+	}
+
+	return profileschema.Config{Attributes: attributes}
+}
+
+// newRateLimitConfig переводит лимиты из конфига в настройки ratelimit.
+func newRateLimitConfig(cfg config.RateLimitConfig) ratelimit.Config {
+	methods := make(map[string]ratelimit.Limit, len(cfg.Methods))
+	for method, limit := range cfg.Methods {
+		methods[method] = ratelimit.Limit{
+			Requests: limit.Requests,
+			Per:      limit.Per,
+		}
+	}
+
+	return ratelimit.Config{
+		Default: ratelimit.Limit{
+			Requests: cfg.Default.Requests,
+			Per:      cfg.Default.Per,
+		},
+		Methods:   methods,
+		AppsPerIP: cfg.AppsPerIP,
+	}
+}
+
+// reloadKeys периодически перечитывает связку ключей,
+// чтобы ротация через cmd/keyring применялась без перезапуска.
+func reloadKeys(log *slog.Logger, keys *jwt.KeyRing, interval time.Duration) {
+	const op = "app.reloadKeys"
+
+	log = log.With(slog.String("op", op))
+
+	for range time.Tick(interval) {
+		if err := keys.Reload(); err != nil {
+			log.Error("не удалось перечитать связку ключей", sl.Err(err))
+		}
+	}
 }
@@ -3,6 +3,7 @@ package grpcapp
 import (
 	"fmt"
 	authgrpc "github.com/h1lton/sso-grpc-ntc/internal/grpc/auth"
+	"github.com/h1lton/sso-grpc-ntc/internal/grpc/ratelimit"
 	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
 	"google.golang.org/grpc"
 	"log/slog"
@@ -18,9 +19,12 @@ type App struct {
 func New(
 	log *slog.Logger,
 	authService authgrpc.Auth,
+	limiter *ratelimit.Limiter,
 	port int,
 ) *App {
-	gRPCServer := grpc.NewServer()
+	gRPCServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(limiter.UnaryInterceptor()),
+	)
 
 	authgrpc.Register(gRPCServer, authService)
 
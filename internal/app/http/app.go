@@ -0,0 +1,69 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+type App struct {
+	log        *slog.Logger
+	httpServer *http.Server
+	port       int
+}
+
+func New(
+	log *slog.Logger,
+	mux *http.ServeMux,
+	port int,
+	timeout time.Duration,
+) *App {
+	return &App{
+		log: log,
+		httpServer: &http.Server{
+			Handler:      mux,
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
+		},
+		port: port,
+	}
+}
+
+func (a App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a App) Run() error {
+	const op = "httpapp.Run"
+
+	log := a.log.With(slog.String("op", op))
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log.Info("HTTP-сервер запущен", slog.String("addr", l.Addr().String()))
+
+	err = a.httpServer.Serve(l)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+func (a App) Stop() {
+	const op = "httpapp.Stop"
+
+	a.log.With(slog.String("op", op)).Info("остановка HTTP-сервера")
+
+	_ = a.httpServer.Shutdown(context.Background())
+}
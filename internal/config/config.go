@@ -8,10 +8,25 @@ import (
 )
 
 type Config struct {
-	Env         string        `yaml:"env" env-required:"true"`
-	StoragePath string        `yaml:"storage_path" env-required:"true"`
-	TokenTTL    time.Duration `yaml:"token_ttl" env-required:"true"`
-	GRPC        GRPCConfig    `yaml:"grpc"`
+	Env               string                  `yaml:"env" env-required:"true"`
+	StoragePath       string                  `yaml:"storage_path" env-required:"true"`
+	TokenTTL          time.Duration           `yaml:"token_ttl" env-required:"true"`
+	RefreshTokenTTL   time.Duration           `yaml:"refresh_token_ttl" env-default:"720h"`
+	GRPC              GRPCConfig              `yaml:"grpc"`
+	HTTP              HTTPConfig              `yaml:"http"`
+	Signing           SigningConfig           `yaml:"signing"`
+	OAuth             OAuthConfig             `yaml:"oauth"`
+	MFA               MFAConfig               `yaml:"mfa"`
+	Notifier          NotifierConfig          `yaml:"notifier"`
+	PasswordReset     PasswordResetConfig     `yaml:"password_reset"`
+	EmailVerification EmailVerificationConfig `yaml:"email_verification"`
+	Invitation        InvitationConfig        `yaml:"invitation"`
+	Profile           ProfileConfig           `yaml:"profile"`
+	PasswordPolicy    PasswordPolicyConfig    `yaml:"password_policy"`
+	PasswordHash      PasswordHashConfig      `yaml:"password_hash"`
+	Lockout           LockoutConfig           `yaml:"lockout"`
+	RateLimit         RateLimitConfig         `yaml:"rate_limit"`
+	Registration      RegistrationConfig      `yaml:"registration"`
 }
 
 type GRPCConfig struct {
@@ -19,6 +34,162 @@ type GRPCConfig struct {
 	Timeout time.Duration `yaml:"timeout"`
 }
 
+type HTTPConfig struct {
+	Port    int           `yaml:"port"`
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+type OAuthConfig struct {
+	// Issuer внешний адрес HTTP-сервиса, публикуется в документе
+	// обнаружения OpenID Connect и попадает в iss токенов.
+	Issuer string `yaml:"issuer" env-default:"http://localhost:8080"`
+	// AuthCodeTTL время жизни кода авторизации.
+	AuthCodeTTL time.Duration `yaml:"auth_code_ttl" env-default:"1m"`
+}
+
+// MFAConfig двухфакторная аутентификация (TOTP).
+type MFAConfig struct {
+	// Issuer название сервиса в приложении-аутентификаторе.
+	Issuer string `yaml:"issuer" env-default:"SSO"`
+	// ChallengeTTL сколько ждать код второго фактора после пароля.
+	ChallengeTTL time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+	// RequireForAdmins лишает администраторов прав,
+	// пока они не включат второй фактор.
+	RequireForAdmins bool `yaml:"require_for_admins" env-default:"true"`
+}
+
+// NotifierConfig доставка писем пользователям.
+type NotifierConfig struct {
+	// Type способ доставки: smtp или outbox (запись в файл,
+	// для локальной разработки и тестов).
+	Type string `yaml:"type" env-default:"outbox"`
+	// OutboxPath файл, в который outbox дописывает письма.
+	OutboxPath string     `yaml:"outbox_path" env-default:"./storage/outbox.jsonl"`
+	SMTP       SMTPConfig `yaml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port" env-default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	From     string `yaml:"from"`
+}
+
+type PasswordResetConfig struct {
+	// TokenTTL время жизни ссылки для сброса пароля.
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"1h"`
+	// URL страница сброса пароля, токен передается в параметре token.
+	URL string `yaml:"url" env-default:"http://localhost:3000/reset-password"`
+}
+
+type EmailVerificationConfig struct {
+	// TokenTTL время жизни ссылки подтверждения email.
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"24h"`
+	// URL страница подтверждения email, токен передается в параметре token.
+	URL string `yaml:"url" env-default:"http://localhost:3000/verify-email"`
+}
+
+type InvitationConfig struct {
+	// TokenTTL время жизни приглашения.
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"72h"`
+	// URL страница принятия приглашения, токен передается в параметре token.
+	URL string `yaml:"url" env-default:"http://localhost:3000/accept-invitation"`
+}
+
+// ProfileConfig профиль пользователя.
+type ProfileConfig struct {
+	// Attributes схема дополнительных атрибутов профиля по их именам.
+	// Атрибуты вне схемы не принимаются.
+	Attributes map[string]ProfileAttributeConfig `yaml:"attributes"`
+}
+
+type ProfileAttributeConfig struct {
+	// Type string, int или bool. Пустой — string.
+	Type string `yaml:"type"`
+	// MaxLength максимальная длина строки, 0 — без ограничения.
+	MaxLength int `yaml:"max_length"`
+	// Values допустимые значения строки, пустой — любые.
+	Values []string `yaml:"values"`
+}
+
+// PasswordPolicyConfig требования к паролям при регистрации,
+// смене и сбросе пароля.
+type PasswordPolicyConfig struct {
+	MinLength int `yaml:"min_length" env-default:"8"`
+	// MaxBytes при bcrypt не больше 72: он молча отбрасывает остаток.
+	MaxBytes      int  `yaml:"max_bytes" env-default:"72"`
+	RequireLower  bool `yaml:"require_lower"`
+	RequireUpper  bool `yaml:"require_upper"`
+	RequireDigit  bool `yaml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol"`
+	// DisallowEmail запрещает пароль, содержащий имя из email.
+	DisallowEmail bool `yaml:"disallow_email" env-default:"true"`
+	// CommonPasswordsPath файл с утекшими и распространенными паролями,
+	// по одному в строке. Пустой — список не проверяется.
+	CommonPasswordsPath string `yaml:"common_passwords_path"`
+}
+
+// PasswordHashConfig алгоритм хэширования новых паролей.
+// Хэши других алгоритмов и со слабыми параметрами
+// пересчитываются при входе пользователя.
+type PasswordHashConfig struct {
+	// Algorithm bcrypt, argon2id или scrypt.
+	Algorithm  string       `yaml:"algorithm" env-default:"bcrypt"`
+	BcryptCost int          `yaml:"bcrypt_cost" env-default:"10"`
+	Argon2     Argon2Config `yaml:"argon2"`
+	Scrypt     ScryptConfig `yaml:"scrypt"`
+}
+
+type Argon2Config struct {
+	// Memory объем памяти в КиБ.
+	Memory      uint32 `yaml:"memory" env-default:"19456"`
+	Iterations  uint32 `yaml:"iterations" env-default:"2"`
+	Parallelism uint8  `yaml:"parallelism" env-default:"1"`
+	SaltLength  uint32 `yaml:"salt_length" env-default:"16"`
+	KeyLength   uint32 `yaml:"key_length" env-default:"32"`
+}
+
+type ScryptConfig struct {
+	// N параметр стоимости, степень двойки.
+	N          int `yaml:"n" env-default:"32768"`
+	R          int `yaml:"r" env-default:"8"`
+	P          int `yaml:"p" env-default:"1"`
+	SaltLength int `yaml:"salt_length" env-default:"16"`
+	KeyLength  int `yaml:"key_length" env-default:"32"`
+}
+
+// LockoutConfig ограничение подбора паролей по пользователю и IP.
+//
+// После *DelayAfter неудач подряд каждая следующая откладывает
+// следующую попытку: base_delay, дальше вдвое больше, но не больше
+// max_delay. После *LockAfter неудач вход блокируется на lock_duration.
+// Счетчик обнуляется, если неудач не было дольше reset_after.
+// Отрицательный порог отключает ограничение: ноль заменяется
+// значением по умолчанию.
+type LockoutConfig struct {
+	UserDelayAfter int           `yaml:"user_delay_after" env-default:"3"`
+	UserLockAfter  int           `yaml:"user_lock_after" env-default:"10"`
+	IPDelayAfter   int           `yaml:"ip_delay_after" env-default:"10"`
+	IPLockAfter    int           `yaml:"ip_lock_after" env-default:"50"`
+	BaseDelay      time.Duration `yaml:"base_delay" env-default:"1s"`
+	MaxDelay       time.Duration `yaml:"max_delay" env-default:"1m"`
+	LockDuration   time.Duration `yaml:"lock_duration" env-default:"15m"`
+	ResetAfter     time.Duration `yaml:"reset_after" env-default:"1h"`
+}
+
+// SigningConfig связка ключей асимметричной подписи токенов.
+//
+// Если связка не указана, токены подписываются секретом приложения (HS256).
+type SigningConfig struct {
+	// KeyRing путь к JSON-манифесту связки ключей,
+	// которым управляет cmd/keyring.
+	KeyRing string `yaml:"keyring"`
+	// ReloadInterval как часто перечитывать связку с диска.
+	// 0 отключает перечитывание.
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+}
+
 // MustLoad загружает конфиг по пути который указан
 // в переменной окружения "CONFIG_PATH"
 // или в флаге командной строки "--config".
@@ -69,3 +240,28 @@ func fetchConfigPath() string {
 
 	return res
 }
+
+// RateLimitConfig лимиты вызовов gRPC-методов с одного IP-адреса
+// для одного приложения. Methods задает лимиты по короткому имени
+// метода ("Login"), остальным методам достается Default.
+//
+// Со всего адреса, по всем приложениям и без них, можно сделать
+// в AppsPerIP раз больше вызовов метода (не больше 1 — столько же).
+type RateLimitConfig struct {
+	Default   RateLimit            `yaml:"default"`
+	Methods   map[string]RateLimit `yaml:"methods"`
+	AppsPerIP int                  `yaml:"apps_per_ip" env-default:"1"`
+}
+
+// RateLimit не больше Requests вызовов за Per.
+// Нулевой Requests снимает ограничение.
+type RateLimit struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per" env-default:"1m"`
+}
+
+type RegistrationConfig struct {
+	// EnumerationSafe не сообщает, что email уже занят: ответ всегда
+	// одинаковый и без user_id, а владельцу адреса приходит письмо.
+	EnumerationSafe bool `yaml:"enumeration_safe"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// APIKey персональный API-ключ пользователя.
+//
+// Ключ выдается для приложения, в котором пользователь его создал.
+// Prefix виден пользователю и служит для поиска ключа,
+// секретная часть хранится только в виде хэша.
+type APIKey struct {
+	ID         int64
+	UserID     int64
+	AppID      int
+	Name       string
+	Prefix     string
+	SecretHash []byte
+	Scopes     []string
+	CreatedAt  time.Time
+	// ExpiresAt нулевое у бессрочного ключа.
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	RevokedAt  time.Time
+}
+
+// Active сообщает, можно ли пользоваться ключом на момент now.
+func (k APIKey) Active(now time.Time) bool {
+	if !k.RevokedAt.IsZero() {
+		return false
+	}
+
+	return k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt)
+}
@@ -1,7 +1,27 @@
 package models
 
 type App struct {
-	ID     int
-	Name   string
-	Secret string
+	ID       int
+	TenantID int64
+	Name     string
+	Secret   string
+	// RedirectURIs зарегистрированные адреса возврата OAuth.
+	RedirectURIs []string
+	// RequireVerifiedEmail запрещает вход пользователям
+	// с неподтвержденным email.
+	RequireVerifiedEmail bool
+}
+
+// AllowsRedirect сообщает, зарегистрирован ли адрес возврата uri.
+//
+// Сравнение точное, без нормализации: так исключаются подмены
+// через пути, параметры и регистр.
+func (a App) AllowsRedirect(uri string) bool {
+	for _, u := range a.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// EmailVerificationToken одноразовый токен подтверждения email.
+//
+// Токен подтверждает конкретный адрес Email: если пользователь
+// успел его сменить, старый токен новый адрес не подтвердит.
+// Хранится только хэш токена.
+type EmailVerificationToken struct {
+	ID        int64
+	Hash      []byte
+	UserID    int64
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
+}
+
+// Usable сообщает, можно ли подтвердить email по токену на момент now.
+func (t EmailVerificationToken) Usable(now time.Time) bool {
+	return t.UsedAt.IsZero() && now.Before(t.ExpiresAt)
+}
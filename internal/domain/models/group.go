@@ -0,0 +1,10 @@
+package models
+
+// Group группа пользователей организации TenantID. В группу входят
+// пользователи и другие группы; роли группы действуют для всех,
+// кто входит в нее напрямую или через вложенные группы.
+type Group struct {
+	ID       int64
+	TenantID int64
+	Name     string
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Invitation приглашение пользователя с адресом Email в приложение
+// AppID с ролью RoleID (0 — без роли).
+//
+// Ссылка с токеном приходит на Email, хранится только хэш токена.
+// Приглашение одноразовое и действует до ExpiresAt.
+type Invitation struct {
+	ID         int64
+	Hash       []byte
+	AppID      int
+	RoleID     int64
+	Email      string
+	InvitedBy  int64
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	AcceptedAt time.Time
+	AcceptedBy int64
+	RevokedAt  time.Time
+}
+
+// Pending сообщает, можно ли еще принять приглашение на момент now.
+func (i Invitation) Pending(now time.Time) bool {
+	return i.AcceptedAt.IsZero() && i.RevokedAt.IsZero() && now.Before(i.ExpiresAt)
+}
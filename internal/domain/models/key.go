@@ -0,0 +1,17 @@
+package models
+
+// JWK открытый ключ подписи в формате JSON Web Key (RFC 7517).
+//
+// Заполнены только поля, относящиеся к типу ключа (Kty):
+// N и E для RSA, Crv, X и Y для EC, Crv и X для OKP.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Области счетчиков неудачных входов.
+const (
+	LoginThrottleUser = "user"
+	LoginThrottleIP   = "ip"
+	// LoginThrottleEmail считает входы с email, которого нет в организации:
+	// ответ для него ограничивается так же, как для пользователя.
+	LoginThrottleEmail = "email"
+)
+
+// LoginThrottle счетчик неудачных входов по пользователю, IP-адресу
+// или незарегистрированному email.
+//
+// BlockedUntil — конец окна задержки после очередной неудачи,
+// LockedUntil — конец временной блокировки.
+type LoginThrottle struct {
+	Scope         string
+	Subject       string
+	Failures      int
+	LastFailureAt time.Time
+	BlockedUntil  time.Time
+	LockedUntil   time.Time
+}
+
+// Locked сообщает, заблокирован ли вход на момент now.
+func (t LoginThrottle) Locked(now time.Time) bool {
+	return now.Before(t.LockedUntil)
+}
+
+// Blocked сообщает, действует ли на момент now задержка.
+func (t LoginThrottle) Blocked(now time.Time) bool {
+	return now.Before(t.BlockedUntil)
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// AuthCode код авторизации OAuth 2.0.
+//
+// Хранится только хэш кода. FamilyID заполняется при обмене кода
+// и указывает на семейство выданных по нему refresh-токенов.
+type AuthCode struct {
+	ID            int64
+	Hash          []byte
+	AppID         int
+	UserID        int64
+	RedirectURI   string
+	CodeChallenge string
+	Scope         string
+	Nonce         string
+	AuthTime      time.Time
+	ExpiresAt     time.Time
+	UsedAt        time.Time
+	FamilyID      string
+}
+
+// Used сообщает, был ли код уже обменян.
+func (c AuthCode) Used() bool {
+	return !c.UsedAt.IsZero()
+}
+
+// AuthorizeRequest параметры запроса авторизации OAuth 2.0 с PKCE.
+type AuthorizeRequest struct {
+	AppID         int32
+	RedirectURI   string
+	CodeChallenge string
+	Scope         string
+	Nonce         string
+}
+
+// HasScope сообщает, запрошена ли область scope.
+func (c AuthCode) HasScope(scope string) bool {
+	for _, s := range strings.Fields(c.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PasswordResetToken одноразовый токен сброса пароля.
+//
+// Хранится только хэш токена.
+type PasswordResetToken struct {
+	ID        int64
+	Hash      []byte
+	UserID    int64
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
+}
+
+// Usable сообщает, можно ли сбросить пароль по токену на момент now.
+func (t PasswordResetToken) Usable(now time.Time) bool {
+	return t.UsedAt.IsZero() && now.Before(t.ExpiresAt)
+}
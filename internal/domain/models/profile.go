@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Profile профиль пользователя. Attributes дополнительные атрибуты,
+// их имена и типы задает схема из конфига. Нулевой UpdatedAt —
+// профиль еще не заполнялся.
+type Profile struct {
+	UserID      int64
+	DisplayName string
+	Locale      string
+	Timezone    string
+	AvatarURL   string
+	Attributes  map[string]string
+	UpdatedAt   time.Time
+}
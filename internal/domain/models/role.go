@@ -0,0 +1,58 @@
+package models
+
+import "slices"
+
+// Глобальная роль администратора SSO и ее разрешение.
+const (
+	RoleAdmin       = "admin"
+	PermissionAdmin = "sso.admin"
+)
+
+// Role роль с набором разрешений в приложении AppID.
+// Роль с AppID = 0 глобальная: действует во всех приложениях.
+type Role struct {
+	ID          int64
+	AppID       int
+	Name        string
+	Permissions []string
+}
+
+// Global сообщает, действует ли роль во всех приложениях.
+func (r Role) Global() bool {
+	return r.AppID == 0
+}
+
+// Access роли пользователя в приложении и выданные ими разрешения.
+// Groups группы, в которые пользователь входит напрямую
+// или через вложенные группы.
+type Access struct {
+	Roles       []string
+	Permissions []string
+	Groups      []string
+}
+
+// NewAccess собирает имена ролей и их разрешения без повторов.
+func NewAccess(roles []Role) Access {
+	access := Access{Roles: []string{}, Permissions: []string{}}
+
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		access.Roles = append(access.Roles, role.Name)
+
+		for _, p := range role.Permissions {
+			if !seen[p] {
+				seen[p] = true
+				access.Permissions = append(access.Permissions, p)
+			}
+		}
+	}
+
+	slices.Sort(access.Permissions)
+
+	return access
+}
+
+// Has сообщает, выдано ли разрешение permission.
+func (a Access) Has(permission string) bool {
+	return slices.Contains(a.Permissions, permission)
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// TOTP настройка второго фактора пользователя.
+//
+// До подтверждения (ConfirmedAt нулевое) второй фактор не включен:
+// пользователь еще не доказал, что добавил секрет в аутентификатор.
+type TOTP struct {
+	UserID      int64
+	Secret      string
+	ConfirmedAt time.Time
+	// LastStep последний принятый шаг: коды этого и более ранних
+	// шагов повторно не принимаются.
+	LastStep int64
+}
+
+// Enabled сообщает, включен ли второй фактор.
+func (t TOTP) Enabled() bool {
+	return !t.ConfirmedAt.IsZero()
+}
+
+// LoginChallenge вызов второго фактора, который выдается при входе
+// пользователю с включенной 2FA вместо токенов.
+//
+// Хранится только хэш вызова.
+type LoginChallenge struct {
+	ID        int64
+	Hash      []byte
+	UserID    int64
+	AppID     int
+	ExpiresAt time.Time
+	Attempts  int
+	UsedAt    time.Time
+}
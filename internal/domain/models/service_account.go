@@ -0,0 +1,58 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ServiceAccountSubjectPrefix префикс sub в токенах сервисных аккаунтов,
+// отличающий их от токенов пользователей.
+const ServiceAccountSubjectPrefix = "service:"
+
+// ServiceAccount сервисный аккаунт приложения для межсервисной
+// аутентификации (OAuth 2.0 client credentials).
+//
+// Секрет не хранится, только его хэш.
+type ServiceAccount struct {
+	ID         int64
+	AppID      int
+	Name       string
+	ClientID   string
+	SecretHash []byte
+	// Scopes области, которые аккаунт может запросить.
+	Scopes    []string
+	CreatedAt time.Time
+}
+
+// Subject значение sub в токенах аккаунта.
+func (s ServiceAccount) Subject() string {
+	return ServiceAccountSubjectPrefix + s.ClientID
+}
+
+// GrantScopes возвращает области, которые выдаются по запросу requested
+// (через пробел). Пустой запрос получает все разрешенные области.
+// Если запрошена неразрешенная область, ok = false.
+func (s ServiceAccount) GrantScopes(requested string) (granted string, ok bool) {
+	if strings.TrimSpace(requested) == "" {
+		return strings.Join(s.Scopes, " "), true
+	}
+
+	fields := strings.Fields(requested)
+	for _, scope := range fields {
+		if !s.allows(scope) {
+			return "", false
+		}
+	}
+
+	return strings.Join(fields, " "), true
+}
+
+func (s ServiceAccount) allows(scope string) bool {
+	for _, allowed := range s.Scopes {
+		if allowed == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ClientInfo сведения о клиенте, с которого выполняется вход.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	// Device название устройства, которое сообщил клиент.
+	Device string
+}
+
+// Session сеанс пользователя: вход в приложение с одного устройства.
+//
+// ID сеанса совпадает с FamilyID его refresh-токенов и передается
+// в access-токенах утверждением sid.
+type Session struct {
+	ID         string
+	UserID     int64
+	AppID      int
+	Client     ClientInfo
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	// Current сеанс, которому выдан токен запроса.
+	Current bool
+}
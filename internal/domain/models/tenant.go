@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// DefaultTenantID организация, к которой относятся приложения
+// и пользователи, созданные до появления организаций, а также
+// пользователи, зарегистрированные без указания приложения.
+const DefaultTenantID int64 = 1
+
+// Tenant организация. Ей принадлежат приложения и пользователи,
+// email пользователя уникален в пределах организации.
+type Tenant struct {
+	ID        int64
+	Name      string
+	CreatedAt time.Time
+}
+
+// TenantResources организация и то, что ей принадлежит.
+type TenantResources struct {
+	Tenant    Tenant
+	Apps      []App
+	UserCount int
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// TokenPair пара токенов, выдаваемая при входе и обновлении.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	// ExpiresIn время жизни access-токена.
+	ExpiresIn time.Duration
+	// IDToken ID-токен OpenID Connect, выдается только
+	// при авторизации с областью openid.
+	IDToken string
+	// Scope выданные области через пробел.
+	Scope string
+	// Challenge вызов второго фактора. Если он заполнен, токенов
+	// в паре нет: вход завершается ответом на вызов.
+	Challenge string
+}
+
+// RefreshToken запись о выданном refresh-токене.
+//
+// Сам токен не хранится, только его хэш.
+// Все токены, полученные ротацией из одного входа,
+// принадлежат одному семейству (FamilyID).
+type RefreshToken struct {
+	ID        int64
+	Hash      []byte
+	FamilyID  string
+	UserID    int64
+	AppID     int
+	ExpiresAt time.Time
+	RotatedAt time.Time
+	RevokedAt time.Time
+}
+
+// Rotated сообщает, был ли токен уже обменян на новый.
+func (t RefreshToken) Rotated() bool {
+	return !t.RotatedAt.IsZero()
+}
+
+// Revoked сообщает, был ли токен отозван.
+func (t RefreshToken) Revoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+// Expired сообщает, истек ли срок действия токена на момент now.
+func (t RefreshToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// TokenInfo результат интроспекции access-токена (RFC 7662).
+//
+// Для недействительного токена заполнено только Active = false.
+// У токена сервисного аккаунта UserID и Email пусты.
+type TokenInfo struct {
+	Active        bool
+	ID            string
+	Subject       string
+	UserID        int64
+	Email         string
+	EmailVerified bool
+	AppID         int
+	TenantID      int64
+	ClientID      string
+	Scope         string
+	Roles         []string
+	Permissions   []string
+	Groups        []string
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+}
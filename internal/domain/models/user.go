@@ -1,7 +1,52 @@
 package models
 
+import "time"
+
+// User пользователь. Нулевой CreatedAt — пользователь создан до того,
+// как время регистрации стало сохраняться. Disabled — учетная запись
+// отключена администратором.
 type User struct {
-	ID       int64
-	Email    string
-	PassHash []byte
+	ID            int64
+	TenantID      int64
+	Email         string
+	PassHash      []byte
+	EmailVerified bool
+	Disabled      bool
+	CreatedAt     time.Time
+}
+
+// Порядок списка пользователей, при равных значениях — по ID.
+// Email сравниваются без учета регистра, как в индексе idx_users_email.
+const (
+	UserSortID        = "id"
+	UserSortEmail     = "email"
+	UserSortCreatedAt = "created_at"
+)
+
+// UserFilter фильтры списка пользователей. Нулевые значения не фильтруют.
+type UserFilter struct {
+	TenantID int64
+	// EmailPrefix начало email без учета регистра.
+	EmailPrefix string
+	// CreatedFrom и CreatedTo полуинтервал [from, to) времени регистрации.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// RoleID роль, назначенная напрямую или через группы.
+	RoleID int64
+	// AppID приложение, в которое пользователь входил.
+	AppID int
+	// Verified подтвержден ли email.
+	Verified *bool
+	// Disabled отключена ли учетная запись администратором.
+	Disabled *bool
+}
+
+// UserQuery страница списка пользователей: не больше Limit
+// пользователей, следующих в порядке Sort за After (nil — с начала).
+type UserQuery struct {
+	Filter UserFilter
+	Sort   string
+	Desc   bool
+	After  *User
+	Limit  int
 }
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/grpc/clientip"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+const (
+	authorizationHeader = "authorization"
+	bearerPrefix        = "Bearer "
+	userAgentHeader     = "user-agent"
+	deviceNameHeader    = "x-device-name"
+)
+
+// accessToken извлекает access-токен из метаданных запроса
+// "authorization: Bearer <token>".
+func accessToken(c context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(c)
+
+	for _, v := range md.Get(authorizationHeader) {
+		if len(v) > len(bearerPrefix) &&
+			strings.EqualFold(v[:len(bearerPrefix)], bearerPrefix) {
+			return v[len(bearerPrefix):], nil
+		}
+	}
+
+	return "", status.Error(codes.Unauthenticated, "access-токен не указан")
+}
+
+// clientInfo возвращает сведения о клиенте: IP-адрес из соединения,
+// user-agent и название устройства из метаданных запроса.
+func clientInfo(c context.Context) models.ClientInfo {
+	md, _ := metadata.FromIncomingContext(c)
+
+	return models.ClientInfo{
+		IP:        clientip.FromContext(c),
+		UserAgent: firstValue(md, userAgentHeader),
+		Device:    firstValue(md, deviceNameHeader),
+	}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+
+	return ""
+}
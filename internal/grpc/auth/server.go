@@ -3,14 +3,30 @@ package auth
 import (
 	"context"
 	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/passwordpolicy"
+	"github.com/h1lton/sso-grpc-ntc/internal/profileschema"
 	"github.com/h1lton/sso-grpc-ntc/internal/services/auth"
 	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"slices"
+	"strings"
+	"time"
 )
 
-const emptyValue = 0
+const (
+	emptyValue      = 0
+	tokenTypeBearer = "Bearer"
+
+	// errorDomain и passwordPolicyReason заполняют ErrorInfo
+	// в подробностях ошибки о нарушении политики паролей.
+	errorDomain          = "sso"
+	passwordPolicyReason = "PASSWORD_POLICY_VIOLATION"
+)
 
 type Auth interface {
 	Login(
@@ -18,13 +34,210 @@ type Auth interface {
 		email string,
 		password string,
 		appID int32,
-	) (token string, err error)
+		client models.ClientInfo,
+	) (tokens models.TokenPair, err error)
 	Register(
 		c context.Context,
 		email string,
 		password string,
+		appID int32,
 	) (userID int64, err error)
 	IsAdmin(c context.Context, userID int64) (bool, error)
+	Refresh(
+		c context.Context,
+		refreshToken string,
+	) (tokens models.TokenPair, err error)
+	Logout(c context.Context, accessToken string, refreshToken string) error
+	LogoutAll(c context.Context, accessToken string) error
+	Introspect(c context.Context, token string) (models.TokenInfo, error)
+	JWKS() []models.JWK
+	ClientCredentials(
+		c context.Context,
+		clientID string,
+		clientSecret string,
+		scope string,
+	) (tokens models.TokenPair, err error)
+	CreateAPIKey(
+		c context.Context,
+		accessToken string,
+		name string,
+		scopes []string,
+		expiresAt time.Time,
+	) (key string, info models.APIKey, err error)
+	ListAPIKeys(c context.Context, accessToken string) ([]models.APIKey, error)
+	RevokeAPIKey(c context.Context, accessToken string, keyID int64) error
+	ExchangeAPIKey(
+		c context.Context,
+		apiKey string,
+	) (tokens models.TokenPair, err error)
+	EnrollTOTP(
+		c context.Context,
+		accessToken string,
+	) (secret string, uri string, err error)
+	ConfirmTOTP(
+		c context.Context,
+		accessToken string,
+		code string,
+	) (recoveryCodes []string, err error)
+	VerifySecondFactor(
+		c context.Context,
+		challenge string,
+		code string,
+		client models.ClientInfo,
+	) (tokens models.TokenPair, err error)
+	RequestPasswordReset(c context.Context, email string, appID int32) error
+	ConfirmPasswordReset(
+		c context.Context,
+		token string,
+		newPassword string,
+	) error
+	VerifyEmail(c context.Context, token string) error
+	ResendVerification(c context.Context, email string, appID int32) error
+	ChangePassword(
+		c context.Context,
+		accessToken string,
+		oldPassword string,
+		newPassword string,
+		revokeOtherSessions bool,
+		client models.ClientInfo,
+	) (tokens models.TokenPair, err error)
+	UnlockAccount(
+		c context.Context,
+		accessToken string,
+		userID int64,
+		ip string,
+	) error
+	ListSessions(c context.Context, accessToken string) ([]models.Session, error)
+	RevokeSession(c context.Context, accessToken string, sessionID string) error
+	CreateRole(
+		c context.Context,
+		accessToken string,
+		appID int32,
+		name string,
+		permissions []string,
+	) (models.Role, error)
+	ListRoles(
+		c context.Context,
+		accessToken string,
+		appID int32,
+	) ([]models.Role, error)
+	DeleteRole(c context.Context, accessToken string, roleID int64) error
+	AssignRole(
+		c context.Context,
+		accessToken string,
+		userID int64,
+		roleID int64,
+	) error
+	UnassignRole(
+		c context.Context,
+		accessToken string,
+		userID int64,
+		roleID int64,
+	) error
+	CheckPermission(
+		c context.Context,
+		userID int64,
+		appID int32,
+		permission string,
+	) (bool, error)
+	CreateTenant(
+		c context.Context,
+		accessToken string,
+		name string,
+	) (models.Tenant, error)
+	ListTenants(c context.Context, accessToken string) ([]models.Tenant, error)
+	TenantResources(
+		c context.Context,
+		accessToken string,
+		tenantID int64,
+	) (models.TenantResources, error)
+	CreateGroup(
+		c context.Context,
+		accessToken string,
+		tenantID int64,
+		name string,
+	) (models.Group, error)
+	ListGroups(
+		c context.Context,
+		accessToken string,
+		tenantID int64,
+	) ([]models.Group, error)
+	DeleteGroup(c context.Context, accessToken string, groupID int64) error
+	AddGroupMember(
+		c context.Context,
+		accessToken string,
+		groupID int64,
+		userID int64,
+		childID int64,
+	) error
+	RemoveGroupMember(
+		c context.Context,
+		accessToken string,
+		groupID int64,
+		userID int64,
+		childID int64,
+	) error
+	AssignGroupRole(
+		c context.Context,
+		accessToken string,
+		groupID int64,
+		roleID int64,
+	) error
+	UnassignGroupRole(
+		c context.Context,
+		accessToken string,
+		groupID int64,
+		roleID int64,
+	) error
+	CreateInvitation(
+		c context.Context,
+		accessToken string,
+		email string,
+		roleID int64,
+		appID int32,
+	) (models.Invitation, error)
+	ListInvitations(
+		c context.Context,
+		accessToken string,
+		appID int32,
+	) ([]models.Invitation, error)
+	RevokeInvitation(
+		c context.Context,
+		accessToken string,
+		invitationID int64,
+	) error
+	AcceptInvitation(
+		c context.Context,
+		token string,
+		password string,
+	) (userID int64, err error)
+	GetUser(
+		c context.Context,
+		accessToken string,
+		userID int64,
+		email string,
+		tenantID int64,
+	) (models.User, models.Profile, error)
+	UpdateProfile(
+		c context.Context,
+		accessToken string,
+		profile models.Profile,
+	) (models.Profile, error)
+	ListUsers(
+		c context.Context,
+		accessToken string,
+		filter models.UserFilter,
+		sort string,
+		desc bool,
+		pageSize int,
+		pageToken string,
+	) (users []models.User, nextPageToken string, err error)
+	SetUserDisabled(
+		c context.Context,
+		accessToken string,
+		userID int64,
+		disabled bool,
+	) error
 }
 
 type ServerAPI struct {
@@ -46,8 +259,18 @@ func (s *ServerAPI) Login(
 		return nil, err
 	}
 
-	token, err := s.auth.Login(c, r.GetEmail(), r.GetPassword(), r.GetAppId())
+	tokens, err := s.auth.Login(
+		c,
+		r.GetEmail(),
+		r.GetPassword(),
+		r.GetAppId(),
+		clientInfo(c),
+	)
 	if err != nil {
+		var retryErr *auth.RetryError
+		if errors.As(err, &retryErr) {
+			return nil, retryStatus(retryErr)
+		}
 		if errors.Is(err, auth.ErrInvalidCredentials) {
 			return nil, status.Error(
 				codes.InvalidArgument,
@@ -60,11 +283,27 @@ func (s *ServerAPI) Login(
 				"неверный id приложения",
 			)
 		}
+		if errors.Is(err, auth.ErrEmailNotVerified) {
+			return nil, status.Error(
+				codes.FailedPrecondition,
+				"email не подтвержден",
+			)
+		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Учетная запись отключена",
+			)
+		}
 
 		return nil, status.Error(codes.Internal, "Internal error")
 	}
 
-	return &ssov1.LoginResponse{Token: token}, nil
+	return &ssov1.LoginResponse{
+		Token:                 tokens.AccessToken,
+		RefreshToken:          tokens.RefreshToken,
+		SecondFactorChallenge: tokens.Challenge,
+	}, nil
 }
 
 func (s *ServerAPI) Register(
@@ -75,7 +314,12 @@ func (s *ServerAPI) Register(
 		return nil, err
 	}
 
-	userID, err := s.auth.Register(c, r.GetEmail(), r.GetPassword())
+	userID, err := s.auth.Register(
+		c,
+		r.GetEmail(),
+		r.GetPassword(),
+		r.GetAppId(),
+	)
 	if err != nil {
 		if errors.Is(err, auth.ErrUserExists) {
 			return nil, status.Error(
@@ -83,6 +327,17 @@ func (s *ServerAPI) Register(
 				"Пользователь уже существует",
 			)
 		}
+		if errors.Is(err, auth.ErrInvalidAppID) {
+			return nil, status.Error(
+				codes.InvalidArgument,
+				"неверный id приложения",
+			)
+		}
+
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyStatus(policyErr, "password")
+		}
 
 		return nil, status.Error(codes.Internal, "Internal error")
 	}
@@ -113,37 +368,2256 @@ func (s *ServerAPI) IsAdmin(
 	return &ssov1.IsAdminResponse{IsAdmin: isAdmin}, nil
 }
 
-// Валидаторы...
+func (s *ServerAPI) Refresh(
+	c context.Context,
+	r *ssov1.RefreshRequest,
+) (*ssov1.RefreshResponse, error) {
+	if err := validateRefresh(r); err != nil {
+		return nil, err
+	}
 
-func validateLogin(r *ssov1.LoginRequest) error {
-	if r.GetEmail() == "" {
-		return status.Error(codes.InvalidArgument, "email не указан")
+	tokens, err := s.auth.Refresh(c, r.GetRefreshToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный refresh-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Учетная запись отключена",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
 	}
 
-	if r.GetPassword() == "" {
-		return status.Error(codes.InvalidArgument, "пароль не указан")
+	return &ssov1.RefreshResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *ServerAPI) Logout(
+	c context.Context,
+	r *ssov1.LogoutRequest,
+) (*ssov1.LogoutResponse, error) {
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
 	}
 
-	if r.GetAppId() == emptyValue {
-		return status.Error(codes.InvalidArgument, "app id не указан")
+	err = s.auth.Logout(c, token, r.GetRefreshToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
 	}
 
-	return nil
+	return &ssov1.LogoutResponse{}, nil
 }
 
-func validateRegister(r *ssov1.RegisterRequest) error {
-	if r.GetEmail() == "" {
-		return status.Error(codes.InvalidArgument, "email не указан")
+func (s *ServerAPI) LogoutAll(
+	c context.Context,
+	_ *ssov1.LogoutAllRequest,
+) (*ssov1.LogoutAllResponse, error) {
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
 	}
 
-	if r.GetPassword() == "" {
-		return status.Error(codes.InvalidArgument, "пароль не указан")
+	err = s.auth.LogoutAll(c, token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrAPIKeyTokenNotAllowed) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Действие недоступно по токену API-ключа",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
 	}
 
-	return nil
+	return &ssov1.LogoutAllResponse{}, nil
 }
 
-func validateIsAdmin(r *ssov1.IsAdminRequest) error {
+func (s *ServerAPI) Introspect(
+	c context.Context,
+	r *ssov1.IntrospectRequest,
+) (*ssov1.IntrospectResponse, error) {
+	if err := validateIntrospect(r); err != nil {
+		return nil, err
+	}
+
+	info, err := s.auth.Introspect(c, r.GetToken())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	if !info.Active {
+		return &ssov1.IntrospectResponse{Active: false}, nil
+	}
+
+	return &ssov1.IntrospectResponse{
+		Active:        true,
+		TokenType:     tokenTypeBearer,
+		Sub:           info.Subject,
+		Exp:           info.ExpiresAt.Unix(),
+		Iat:           info.IssuedAt.Unix(),
+		Jti:           info.ID,
+		ClientId:      info.ClientID,
+		Username:      info.Email,
+		Uid:           info.UserID,
+		Email:         info.Email,
+		AppId:         int32(info.AppID),
+		TenantId:      info.TenantID,
+		Roles:         info.Roles,
+		Permissions:   info.Permissions,
+		Groups:        info.Groups,
+		Scope:         info.Scope,
+		EmailVerified: info.EmailVerified,
+	}, nil
+}
+
+func (s *ServerAPI) JWKS(
+	_ context.Context,
+	_ *ssov1.JWKSRequest,
+) (*ssov1.JWKSResponse, error) {
+	jwks := s.auth.JWKS()
+
+	keys := make([]*ssov1.JWK, 0, len(jwks))
+	for _, k := range jwks {
+		keys = append(keys, &ssov1.JWK{
+			Kty: k.Kty,
+			Kid: k.Kid,
+			Use: k.Use,
+			Alg: k.Alg,
+			N:   k.N,
+			E:   k.E,
+			Crv: k.Crv,
+			X:   k.X,
+			Y:   k.Y,
+		})
+	}
+
+	return &ssov1.JWKSResponse{Keys: keys}, nil
+}
+
+func (s *ServerAPI) ClientCredentials(
+	c context.Context,
+	r *ssov1.ClientCredentialsRequest,
+) (*ssov1.ClientCredentialsResponse, error) {
+	if err := validateClientCredentials(r); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.ClientCredentials(
+		c,
+		r.GetClientId(),
+		r.GetClientSecret(),
+		r.GetScope(),
+	)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidClient) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Неверный client id или секрет",
+			)
+		}
+		if errors.Is(err, auth.ErrInvalidScope) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Запрошена неразрешенная область",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.ClientCredentialsResponse{
+		Token:     tokens.AccessToken,
+		ExpiresIn: int64(tokens.ExpiresIn.Seconds()),
+		Scope:     tokens.Scope,
+	}, nil
+}
+
+func (s *ServerAPI) CreateAPIKey(
+	c context.Context,
+	r *ssov1.CreateAPIKeyRequest,
+) (*ssov1.CreateAPIKeyResponse, error) {
+	if err := validateCreateAPIKey(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt time.Time
+	if r.GetExpiresAt() != emptyValue {
+		expiresAt = time.Unix(r.GetExpiresAt(), 0)
+	}
+
+	key, info, err := s.auth.CreateAPIKey(
+		c,
+		token,
+		r.GetName(),
+		r.GetScopes(),
+		expiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrAPIKeyNotAllowed) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"API-ключ нельзя создать по токену API-ключа",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.CreateAPIKeyResponse{
+		Key:    key,
+		ApiKey: apiKeyToProto(info),
+	}, nil
+}
+
+func (s *ServerAPI) ListAPIKeys(
+	c context.Context,
+	_ *ssov1.ListAPIKeysRequest,
+) (*ssov1.ListAPIKeysResponse, error) {
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := s.auth.ListAPIKeys(c, token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrAPIKeyTokenNotAllowed) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Действие недоступно по токену API-ключа",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	resp := &ssov1.ListAPIKeysResponse{
+		ApiKeys: make([]*ssov1.APIKey, 0, len(keys)),
+	}
+	for _, k := range keys {
+		resp.ApiKeys = append(resp.ApiKeys, apiKeyToProto(k))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) RevokeAPIKey(
+	c context.Context,
+	r *ssov1.RevokeAPIKeyRequest,
+) (*ssov1.RevokeAPIKeyResponse, error) {
+	if err := validateRevokeAPIKey(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.RevokeAPIKey(c, token, r.GetId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrAPIKeyTokenNotAllowed) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Действие недоступно по токену API-ключа",
+			)
+		}
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			return nil, status.Error(codes.NotFound, "API-ключ не найден")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.RevokeAPIKeyResponse{}, nil
+}
+
+func (s *ServerAPI) ExchangeAPIKey(
+	c context.Context,
+	r *ssov1.ExchangeAPIKeyRequest,
+) (*ssov1.ExchangeAPIKeyResponse, error) {
+	if err := validateExchangeAPIKey(r); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.ExchangeAPIKey(c, r.GetApiKey())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный API-ключ",
+			)
+		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Учетная запись отключена",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.ExchangeAPIKeyResponse{
+		Token:     tokens.AccessToken,
+		ExpiresIn: int64(tokens.ExpiresIn.Seconds()),
+		Scope:     tokens.Scope,
+	}, nil
+}
+
+func (s *ServerAPI) EnrollTOTP(
+	c context.Context,
+	_ *ssov1.EnrollTOTPRequest,
+) (*ssov1.EnrollTOTPResponse, error) {
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, uri, err := s.auth.EnrollTOTP(c, token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrTOTPNotAllowed) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Второй фактор нельзя настроить по токену API-ключа",
+			)
+		}
+		if errors.Is(err, auth.ErrTOTPEnabled) {
+			return nil, status.Error(
+				codes.FailedPrecondition,
+				"Второй фактор уже включен",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.EnrollTOTPResponse{Secret: secret, OtpauthUri: uri}, nil
+}
+
+func (s *ServerAPI) ConfirmTOTP(
+	c context.Context,
+	r *ssov1.ConfirmTOTPRequest,
+) (*ssov1.ConfirmTOTPResponse, error) {
+	if err := validateConfirmTOTP(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.auth.ConfirmTOTP(c, token, r.GetCode())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		case errors.Is(err, auth.ErrTOTPNotAllowed):
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Второй фактор нельзя настроить по токену API-ключа",
+			)
+		case errors.Is(err, auth.ErrInvalidOTP):
+			return nil, status.Error(codes.InvalidArgument, "Неверный код")
+		case errors.Is(err, auth.ErrTOTPNotEnrolled):
+			return nil, status.Error(
+				codes.FailedPrecondition,
+				"Сначала вызовите EnrollTOTP",
+			)
+		case errors.Is(err, auth.ErrTOTPEnabled):
+			return nil, status.Error(
+				codes.FailedPrecondition,
+				"Второй фактор уже включен",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.ConfirmTOTPResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+func (s *ServerAPI) VerifySecondFactor(
+	c context.Context,
+	r *ssov1.VerifySecondFactorRequest,
+) (*ssov1.VerifySecondFactorResponse, error) {
+	if err := validateVerifySecondFactor(r); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.VerifySecondFactor(
+		c,
+		r.GetChallenge(),
+		r.GetCode(),
+		clientInfo(c),
+	)
+	if err != nil {
+		var retryErr *auth.RetryError
+		if errors.As(err, &retryErr) {
+			return nil, retryStatus(retryErr)
+		}
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный или истекший вызов, войдите заново",
+			)
+		}
+		if errors.Is(err, auth.ErrInvalidOTP) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Неверный код второго фактора",
+			)
+		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Учетная запись отключена",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.VerifySecondFactorResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *ServerAPI) RequestPasswordReset(
+	c context.Context,
+	r *ssov1.RequestPasswordResetRequest,
+) (*ssov1.RequestPasswordResetResponse, error) {
+	if err := validateRequestPasswordReset(r); err != nil {
+		return nil, err
+	}
+
+	err := s.auth.RequestPasswordReset(c, r.GetEmail(), r.GetAppId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidAppID) {
+			return nil, status.Error(
+				codes.InvalidArgument,
+				"неверный id приложения",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.RequestPasswordResetResponse{}, nil
+}
+
+func (s *ServerAPI) ConfirmPasswordReset(
+	c context.Context,
+	r *ssov1.ConfirmPasswordResetRequest,
+) (*ssov1.ConfirmPasswordResetResponse, error) {
+	if err := validateConfirmPasswordReset(r); err != nil {
+		return nil, err
+	}
+
+	err := s.auth.ConfirmPasswordReset(c, r.GetToken(), r.GetNewPassword())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidResetToken) {
+			return nil, status.Error(
+				codes.InvalidArgument,
+				"Ссылка для сброса пароля недействительна или устарела",
+			)
+		}
+
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyStatus(policyErr, "new_password")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.ConfirmPasswordResetResponse{}, nil
+}
+
+func (s *ServerAPI) VerifyEmail(
+	c context.Context,
+	r *ssov1.VerifyEmailRequest,
+) (*ssov1.VerifyEmailResponse, error) {
+	if err := validateVerifyEmail(r); err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.VerifyEmail(c, r.GetToken()); err != nil {
+		if errors.Is(err, auth.ErrInvalidVerificationToken) {
+			return nil, status.Error(
+				codes.InvalidArgument,
+				"Ссылка подтверждения недействительна или устарела",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.VerifyEmailResponse{}, nil
+}
+
+func (s *ServerAPI) ResendVerification(
+	c context.Context,
+	r *ssov1.ResendVerificationRequest,
+) (*ssov1.ResendVerificationResponse, error) {
+	if err := validateResendVerification(r); err != nil {
+		return nil, err
+	}
+
+	err := s.auth.ResendVerification(c, r.GetEmail(), r.GetAppId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidAppID) {
+			return nil, status.Error(
+				codes.InvalidArgument,
+				"неверный id приложения",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.ResendVerificationResponse{}, nil
+}
+
+func (s *ServerAPI) ChangePassword(
+	c context.Context,
+	r *ssov1.ChangePasswordRequest,
+) (*ssov1.ChangePasswordResponse, error) {
+	if err := validateChangePassword(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.ChangePassword(
+		c,
+		token,
+		r.GetOldPassword(),
+		r.GetNewPassword(),
+		r.GetRevokeOtherSessions(),
+		clientInfo(c),
+	)
+	if err != nil {
+		var retryErr *auth.RetryError
+		if errors.As(err, &retryErr) {
+			return nil, retryStatus(retryErr)
+		}
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPasswordChangeNotAllowed) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Пароль нельзя сменить по токену API-ключа",
+			)
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(
+				codes.InvalidArgument,
+				"Неправильный текущий пароль",
+			)
+		}
+
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyStatus(policyErr, "new_password")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.ChangePasswordResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *ServerAPI) UnlockAccount(
+	c context.Context,
+	r *ssov1.UnlockAccountRequest,
+) (*ssov1.UnlockAccountResponse, error) {
+	if err := validateUnlockAccount(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.UnlockAccount(c, token, r.GetUserId(), r.GetIp())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.UnlockAccountResponse{}, nil
+}
+
+func (s *ServerAPI) ListSessions(
+	c context.Context,
+	_ *ssov1.ListSessionsRequest,
+) (*ssov1.ListSessionsResponse, error) {
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.auth.ListSessions(c, token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrAPIKeyTokenNotAllowed) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Действие недоступно по токену API-ключа",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	resp := &ssov1.ListSessionsResponse{
+		Sessions: make([]*ssov1.Session, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, sessionToProto(session))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) RevokeSession(
+	c context.Context,
+	r *ssov1.RevokeSessionRequest,
+) (*ssov1.RevokeSessionResponse, error) {
+	if err := validateRevokeSession(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.RevokeSession(c, token, r.GetId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrAPIKeyTokenNotAllowed) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Действие недоступно по токену API-ключа",
+			)
+		}
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			return nil, status.Error(codes.NotFound, "Сеанс не найден")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.RevokeSessionResponse{}, nil
+}
+
+func (s *ServerAPI) CreateRole(
+	c context.Context,
+	r *ssov1.CreateRoleRequest,
+) (*ssov1.CreateRoleResponse, error) {
+	if err := validateCreateRole(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.auth.CreateRole(
+		c,
+		token,
+		r.GetAppId(),
+		r.GetName(),
+		r.GetPermissions(),
+	)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrInvalidAppID) {
+			return nil, status.Error(codes.InvalidArgument, "Неверный id приложения")
+		}
+		if errors.Is(err, auth.ErrRoleExists) {
+			return nil, status.Error(codes.AlreadyExists, "Роль уже существует")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.CreateRoleResponse{Role: roleToProto(role)}, nil
+}
+
+func (s *ServerAPI) ListRoles(
+	c context.Context,
+	r *ssov1.ListRolesRequest,
+) (*ssov1.ListRolesResponse, error) {
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.auth.ListRoles(c, token, r.GetAppId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	resp := &ssov1.ListRolesResponse{
+		Roles: make([]*ssov1.Role, 0, len(roles)),
+	}
+	for _, role := range roles {
+		resp.Roles = append(resp.Roles, roleToProto(role))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) DeleteRole(
+	c context.Context,
+	r *ssov1.DeleteRoleRequest,
+) (*ssov1.DeleteRoleResponse, error) {
+	if err := validateDeleteRole(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.DeleteRole(c, token, r.GetId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrRoleNotFound) {
+			return nil, status.Error(codes.NotFound, "Роль не найдена")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.DeleteRoleResponse{}, nil
+}
+
+func (s *ServerAPI) AssignRole(
+	c context.Context,
+	r *ssov1.AssignRoleRequest,
+) (*ssov1.AssignRoleResponse, error) {
+	if err := validateRoleAssignment(r.GetUserId(), r.GetRoleId()); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.AssignRole(c, token, r.GetUserId(), r.GetRoleId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "Пользователь не найден")
+		}
+		if errors.Is(err, auth.ErrRoleNotFound) {
+			return nil, status.Error(codes.NotFound, "Роль не найдена")
+		}
+		if errors.Is(err, auth.ErrTenantMismatch) {
+			return nil, status.Error(
+				codes.FailedPrecondition,
+				"Роль приложения другой организации",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.AssignRoleResponse{}, nil
+}
+
+func (s *ServerAPI) UnassignRole(
+	c context.Context,
+	r *ssov1.UnassignRoleRequest,
+) (*ssov1.UnassignRoleResponse, error) {
+	if err := validateRoleAssignment(r.GetUserId(), r.GetRoleId()); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.UnassignRole(c, token, r.GetUserId(), r.GetRoleId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrRoleNotAssigned) {
+			return nil, status.Error(codes.NotFound, "Роль не назначена")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.UnassignRoleResponse{}, nil
+}
+
+func (s *ServerAPI) CheckPermission(
+	c context.Context,
+	r *ssov1.CheckPermissionRequest,
+) (*ssov1.CheckPermissionResponse, error) {
+	if err := validateCheckPermission(r); err != nil {
+		return nil, err
+	}
+
+	allowed, err := s.auth.CheckPermission(
+		c,
+		r.GetUserId(),
+		r.GetAppId(),
+		r.GetPermission(),
+	)
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "Пользователь не найден")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.CheckPermissionResponse{Allowed: allowed}, nil
+}
+
+func (s *ServerAPI) CreateTenant(
+	c context.Context,
+	r *ssov1.CreateTenantRequest,
+) (*ssov1.CreateTenantResponse, error) {
+	if err := validateCreateTenant(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant, err := s.auth.CreateTenant(c, token, r.GetName())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrTenantExists) {
+			return nil, status.Error(
+				codes.AlreadyExists,
+				"Организация уже существует",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.CreateTenantResponse{Tenant: tenantToProto(tenant)}, nil
+}
+
+func (s *ServerAPI) ListTenants(
+	c context.Context,
+	_ *ssov1.ListTenantsRequest,
+) (*ssov1.ListTenantsResponse, error) {
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	tenants, err := s.auth.ListTenants(c, token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	resp := &ssov1.ListTenantsResponse{
+		Tenants: make([]*ssov1.Tenant, 0, len(tenants)),
+	}
+	for _, tenant := range tenants {
+		resp.Tenants = append(resp.Tenants, tenantToProto(tenant))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) GetTenant(
+	c context.Context,
+	r *ssov1.GetTenantRequest,
+) (*ssov1.GetTenantResponse, error) {
+	if err := validateGetTenant(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.auth.TenantResources(c, token, r.GetId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrTenantNotFound) {
+			return nil, status.Error(codes.NotFound, "Организация не найдена")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	resp := &ssov1.GetTenantResponse{
+		Tenant:    tenantToProto(res.Tenant),
+		Apps:      make([]*ssov1.App, 0, len(res.Apps)),
+		UserCount: int64(res.UserCount),
+	}
+	for _, app := range res.Apps {
+		resp.Apps = append(resp.Apps, &ssov1.App{
+			Id:   int32(app.ID),
+			Name: app.Name,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) CreateGroup(
+	c context.Context,
+	r *ssov1.CreateGroupRequest,
+) (*ssov1.CreateGroupResponse, error) {
+	if err := validateCreateGroup(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.auth.CreateGroup(c, token, r.GetTenantId(), r.GetName())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrTenantNotFound) {
+			return nil, status.Error(codes.NotFound, "Организация не найдена")
+		}
+		if errors.Is(err, auth.ErrGroupExists) {
+			return nil, status.Error(codes.AlreadyExists, "Группа уже существует")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.CreateGroupResponse{Group: groupToProto(group)}, nil
+}
+
+func (s *ServerAPI) ListGroups(
+	c context.Context,
+	r *ssov1.ListGroupsRequest,
+) (*ssov1.ListGroupsResponse, error) {
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.auth.ListGroups(c, token, r.GetTenantId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	resp := &ssov1.ListGroupsResponse{
+		Groups: make([]*ssov1.Group, 0, len(groups)),
+	}
+	for _, group := range groups {
+		resp.Groups = append(resp.Groups, groupToProto(group))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) DeleteGroup(
+	c context.Context,
+	r *ssov1.DeleteGroupRequest,
+) (*ssov1.DeleteGroupResponse, error) {
+	if err := validateDeleteGroup(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.DeleteGroup(c, token, r.GetId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrGroupNotFound) {
+			return nil, status.Error(codes.NotFound, "Группа не найдена")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.DeleteGroupResponse{}, nil
+}
+
+func (s *ServerAPI) AddGroupMember(
+	c context.Context,
+	r *ssov1.AddGroupMemberRequest,
+) (*ssov1.AddGroupMemberResponse, error) {
+	err := validateGroupMember(r.GetGroupId(), r.GetUserId(), r.GetChildGroupId())
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.AddGroupMember(
+		c,
+		token,
+		r.GetGroupId(),
+		r.GetUserId(),
+		r.GetChildGroupId(),
+	)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrGroupNotFound) {
+			return nil, status.Error(codes.NotFound, "Группа не найдена")
+		}
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "Пользователь не найден")
+		}
+		if errors.Is(err, auth.ErrTenantMismatch) {
+			return nil, status.Error(
+				codes.FailedPrecondition,
+				"Участник из другой организации",
+			)
+		}
+		if errors.Is(err, auth.ErrGroupCycle) {
+			return nil, status.Error(
+				codes.FailedPrecondition,
+				"Группа уже входит в добавляемую группу",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.AddGroupMemberResponse{}, nil
+}
+
+func (s *ServerAPI) RemoveGroupMember(
+	c context.Context,
+	r *ssov1.RemoveGroupMemberRequest,
+) (*ssov1.RemoveGroupMemberResponse, error) {
+	err := validateGroupMember(r.GetGroupId(), r.GetUserId(), r.GetChildGroupId())
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.RemoveGroupMember(
+		c,
+		token,
+		r.GetGroupId(),
+		r.GetUserId(),
+		r.GetChildGroupId(),
+	)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrGroupMemberNotFound) {
+			return nil, status.Error(codes.NotFound, "Участник не состоит в группе")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.RemoveGroupMemberResponse{}, nil
+}
+
+func (s *ServerAPI) AssignGroupRole(
+	c context.Context,
+	r *ssov1.AssignGroupRoleRequest,
+) (*ssov1.AssignGroupRoleResponse, error) {
+	if err := validateGroupRole(r.GetGroupId(), r.GetRoleId()); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.AssignGroupRole(c, token, r.GetGroupId(), r.GetRoleId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrGroupNotFound) {
+			return nil, status.Error(codes.NotFound, "Группа не найдена")
+		}
+		if errors.Is(err, auth.ErrRoleNotFound) {
+			return nil, status.Error(codes.NotFound, "Роль не найдена")
+		}
+		if errors.Is(err, auth.ErrTenantMismatch) {
+			return nil, status.Error(
+				codes.FailedPrecondition,
+				"Роль приложения другой организации",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.AssignGroupRoleResponse{}, nil
+}
+
+func (s *ServerAPI) UnassignGroupRole(
+	c context.Context,
+	r *ssov1.UnassignGroupRoleRequest,
+) (*ssov1.UnassignGroupRoleResponse, error) {
+	if err := validateGroupRole(r.GetGroupId(), r.GetRoleId()); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.UnassignGroupRole(c, token, r.GetGroupId(), r.GetRoleId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrRoleNotAssigned) {
+			return nil, status.Error(codes.NotFound, "Роль не назначена группе")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.UnassignGroupRoleResponse{}, nil
+}
+
+func (s *ServerAPI) CreateInvitation(
+	c context.Context,
+	r *ssov1.CreateInvitationRequest,
+) (*ssov1.CreateInvitationResponse, error) {
+	if err := validateCreateInvitation(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	inv, err := s.auth.CreateInvitation(
+		c,
+		token,
+		r.GetEmail(),
+		r.GetRoleId(),
+		r.GetAppId(),
+	)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrInvalidAppID) {
+			return nil, status.Error(codes.InvalidArgument, "Неверный id приложения")
+		}
+		if errors.Is(err, auth.ErrRoleNotFound) {
+			return nil, status.Error(codes.NotFound, "Роль не найдена")
+		}
+		if errors.Is(err, auth.ErrInvitationRole) {
+			return nil, status.Error(
+				codes.FailedPrecondition,
+				"Роль не действует в приложении приглашения",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.CreateInvitationResponse{
+		Invitation: invitationToProto(inv),
+	}, nil
+}
+
+func (s *ServerAPI) ListInvitations(
+	c context.Context,
+	r *ssov1.ListInvitationsRequest,
+) (*ssov1.ListInvitationsResponse, error) {
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.auth.ListInvitations(c, token, r.GetAppId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	resp := &ssov1.ListInvitationsResponse{
+		Invitations: make([]*ssov1.Invitation, 0, len(invitations)),
+	}
+	for _, inv := range invitations {
+		resp.Invitations = append(resp.Invitations, invitationToProto(inv))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) RevokeInvitation(
+	c context.Context,
+	r *ssov1.RevokeInvitationRequest,
+) (*ssov1.RevokeInvitationResponse, error) {
+	if err := validateRevokeInvitation(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.RevokeInvitation(c, token, r.GetId())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrInvitationNotFound) {
+			return nil, status.Error(codes.NotFound, "Приглашение не найдено")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.RevokeInvitationResponse{}, nil
+}
+
+func (s *ServerAPI) AcceptInvitation(
+	c context.Context,
+	r *ssov1.AcceptInvitationRequest,
+) (*ssov1.AcceptInvitationResponse, error) {
+	if err := validateAcceptInvitation(r); err != nil {
+		return nil, err
+	}
+
+	userID, err := s.auth.AcceptInvitation(c, r.GetToken(), r.GetPassword())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidInvitation) {
+			return nil, status.Error(
+				codes.InvalidArgument,
+				"Приглашение недействительно или устарело",
+			)
+		}
+		if errors.Is(err, auth.ErrUserExists) {
+			return nil, status.Error(
+				codes.AlreadyExists,
+				"Пользователь уже существует",
+			)
+		}
+		var retryErr *auth.RetryError
+		if errors.As(err, &retryErr) {
+			return nil, retryStatus(retryErr)
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(codes.InvalidArgument, "Неправильный пароль")
+		}
+
+		var policyErr *passwordpolicy.Error
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyStatus(policyErr, "password")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.AcceptInvitationResponse{UserId: userID}, nil
+}
+
+func (s *ServerAPI) GetUser(
+	c context.Context,
+	r *ssov1.GetUserRequest,
+) (*ssov1.GetUserResponse, error) {
+	if err := validateGetUser(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	user, profile, err := s.auth.GetUser(
+		c,
+		token,
+		r.GetUserId(),
+		r.GetEmail(),
+		r.GetTenantId(),
+	)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "Пользователь не найден")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	resp := &ssov1.GetUserResponse{User: userToProto(user)}
+	resp.User.Profile = profileToProto(profile)
+
+	return resp, nil
+}
+
+func (s *ServerAPI) UpdateProfile(
+	c context.Context,
+	r *ssov1.UpdateProfileRequest,
+) (*ssov1.UpdateProfileResponse, error) {
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.auth.UpdateProfile(c, token, models.Profile{
+		UserID:      r.GetUserId(),
+		DisplayName: r.GetDisplayName(),
+		Locale:      r.GetLocale(),
+		Timezone:    r.GetTimezone(),
+		AvatarURL:   r.GetAvatarUrl(),
+		Attributes:  r.GetAttributes(),
+	})
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrAPIKeyTokenNotAllowed) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"Действие недоступно по токену API-ключа",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "Пользователь не найден")
+		}
+
+		var schemaErr *profileschema.Error
+		if errors.As(err, &schemaErr) {
+			return nil, profileSchemaStatus(schemaErr)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.UpdateProfileResponse{Profile: profileToProto(profile)}, nil
+}
+
+func (s *ServerAPI) ListUsers(
+	c context.Context,
+	r *ssov1.ListUsersRequest,
+) (*ssov1.ListUsersResponse, error) {
+	if err := validateListUsers(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := models.UserFilter{
+		TenantID:    r.GetTenantId(),
+		EmailPrefix: r.GetEmailPrefix(),
+		RoleID:      r.GetRoleId(),
+		AppID:       int(r.GetAppId()),
+		Verified:    r.Verified,
+		Disabled:    r.Disabled,
+	}
+	if r.GetCreatedFrom() != emptyValue {
+		filter.CreatedFrom = time.Unix(r.GetCreatedFrom(), 0)
+	}
+	if r.GetCreatedTo() != emptyValue {
+		filter.CreatedTo = time.Unix(r.GetCreatedTo(), 0)
+	}
+
+	users, next, err := s.auth.ListUsers(
+		c,
+		token,
+		filter,
+		r.GetSort(),
+		r.GetDesc(),
+		int(r.GetPageSize()),
+		r.GetPageToken(),
+	)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrInvalidPageToken) {
+			return nil, status.Error(
+				codes.InvalidArgument,
+				"Недействительный токен страницы",
+			)
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	resp := &ssov1.ListUsersResponse{
+		Users:         make([]*ssov1.User, 0, len(users)),
+		NextPageToken: next,
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, userToProto(user))
+	}
+
+	return resp, nil
+}
+
+func (s *ServerAPI) SetUserDisabled(
+	c context.Context,
+	r *ssov1.SetUserDisabledRequest,
+) (*ssov1.SetUserDisabledResponse, error) {
+	if err := validateSetUserDisabled(r); err != nil {
+		return nil, err
+	}
+
+	token, err := accessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.SetUserDisabled(c, token, r.GetUserId(), r.GetDisabled())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(
+				codes.Unauthenticated,
+				"Недействительный access-токен",
+			)
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "Недостаточно прав")
+		}
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "Пользователь не найден")
+		}
+
+		return nil, status.Error(codes.Internal, "Internal error")
+	}
+
+	return &ssov1.SetUserDisabledResponse{}, nil
+}
+
+func apiKeyToProto(k models.APIKey) *ssov1.APIKey {
+	return &ssov1.APIKey{
+		Id:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedAt:  unixOrZero(k.CreatedAt),
+		ExpiresAt:  unixOrZero(k.ExpiresAt),
+		LastUsedAt: unixOrZero(k.LastUsedAt),
+	}
+}
+
+func roleToProto(role models.Role) *ssov1.Role {
+	return &ssov1.Role{
+		Id:          role.ID,
+		AppId:       int32(role.AppID),
+		Name:        role.Name,
+		Permissions: role.Permissions,
+	}
+}
+
+func tenantToProto(tenant models.Tenant) *ssov1.Tenant {
+	return &ssov1.Tenant{
+		Id:        tenant.ID,
+		Name:      tenant.Name,
+		CreatedAt: tenant.CreatedAt.Unix(),
+	}
+}
+
+func groupToProto(group models.Group) *ssov1.Group {
+	return &ssov1.Group{
+		Id:       group.ID,
+		TenantId: group.TenantID,
+		Name:     group.Name,
+	}
+}
+
+func invitationToProto(inv models.Invitation) *ssov1.Invitation {
+	return &ssov1.Invitation{
+		Id:         inv.ID,
+		Email:      inv.Email,
+		AppId:      int32(inv.AppID),
+		RoleId:     inv.RoleID,
+		InvitedBy:  inv.InvitedBy,
+		CreatedAt:  inv.CreatedAt.Unix(),
+		ExpiresAt:  inv.ExpiresAt.Unix(),
+		AcceptedAt: unixOrZero(inv.AcceptedAt),
+		AcceptedBy: inv.AcceptedBy,
+		RevokedAt:  unixOrZero(inv.RevokedAt),
+	}
+}
+
+func userToProto(user models.User) *ssov1.User {
+	return &ssov1.User{
+		Id:            user.ID,
+		TenantId:      user.TenantID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Disabled:      user.Disabled,
+		CreatedAt:     unixOrZero(user.CreatedAt),
+	}
+}
+
+func profileToProto(profile models.Profile) *ssov1.Profile {
+	return &ssov1.Profile{
+		DisplayName: profile.DisplayName,
+		Locale:      profile.Locale,
+		Timezone:    profile.Timezone,
+		AvatarUrl:   profile.AvatarURL,
+		Attributes:  profile.Attributes,
+		UpdatedAt:   unixOrZero(profile.UpdatedAt),
+	}
+}
+
+func sessionToProto(session models.Session) *ssov1.Session {
+	return &ssov1.Session{
+		Id:         session.ID,
+		AppId:      int32(session.AppID),
+		Ip:         session.Client.IP,
+		UserAgent:  session.Client.UserAgent,
+		Device:     session.Client.Device,
+		CreatedAt:  session.CreatedAt.Unix(),
+		LastSeenAt: session.LastSeenAt.Unix(),
+		ExpiresAt:  session.ExpiresAt.Unix(),
+		Current:    session.Current,
+	}
+}
+
+// passwordPolicyStatus возвращает статус InvalidArgument
+// с нарушенными правилами политики паролей в подробностях:
+// BadRequest с описаниями для поля field и ErrorInfo
+// с кодами правил через запятую в metadata["rules"].
+func passwordPolicyStatus(policyErr *passwordpolicy.Error, field string) error {
+	st := status.New(
+		codes.InvalidArgument,
+		"Пароль не соответствует требованиям",
+	)
+
+	violations := make(
+		[]*errdetails.BadRequest_FieldViolation,
+		0,
+		len(policyErr.Violations),
+	)
+	rules := make([]string, 0, len(policyErr.Violations))
+	for _, v := range policyErr.Violations {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: v.Description,
+		})
+		rules = append(rules, v.Rule)
+	}
+
+	detailed, err := st.WithDetails(
+		&errdetails.BadRequest{FieldViolations: violations},
+		&errdetails.ErrorInfo{
+			Reason:   passwordPolicyReason,
+			Domain:   errorDomain,
+			Metadata: map[string]string{"rules": strings.Join(rules, ",")},
+		},
+	)
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// profileSchemaStatus возвращает статус InvalidArgument
+// с нарушениями схемы профиля в подробностях (BadRequest).
+func profileSchemaStatus(schemaErr *profileschema.Error) error {
+	st := status.New(
+		codes.InvalidArgument,
+		"Профиль не соответствует схеме",
+	)
+
+	violations := make(
+		[]*errdetails.BadRequest_FieldViolation,
+		0,
+		len(schemaErr.Violations),
+	)
+	for _, v := range schemaErr.Violations {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+
+	detailed, err := st.WithDetails(
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// retryStatus возвращает статус отказа во входе после неудачных
+// попыток с RetryInfo в подробностях: RESOURCE_EXHAUSTED, пока
+// действует задержка, и PERMISSION_DENIED при блокировке.
+func retryStatus(retryErr *auth.RetryError) error {
+	st := status.New(
+		codes.ResourceExhausted,
+		"Слишком много неудачных попыток входа, повторите позже",
+	)
+	if errors.Is(retryErr, auth.ErrAccountLocked) {
+		st = status.New(
+			codes.PermissionDenied,
+			"Вход временно заблокирован после неудачных попыток",
+		)
+	}
+
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(retryErr.RetryAfter),
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// unixOrZero переводит время в unix-секунды, нулевое время — в 0.
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+// Валидаторы...
+
+func validateLogin(r *ssov1.LoginRequest) error {
+	if r.GetEmail() == "" {
+		return status.Error(codes.InvalidArgument, "email не указан")
+	}
+
+	if r.GetPassword() == "" {
+		return status.Error(codes.InvalidArgument, "пароль не указан")
+	}
+
+	if r.GetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "app id не указан")
+	}
+
+	return nil
+}
+
+func validateRegister(r *ssov1.RegisterRequest) error {
+	if r.GetEmail() == "" {
+		return status.Error(codes.InvalidArgument, "email не указан")
+	}
+
+	if r.GetPassword() == "" {
+		return status.Error(codes.InvalidArgument, "пароль не указан")
+	}
+
+	return nil
+}
+
+func validateIsAdmin(r *ssov1.IsAdminRequest) error {
+	if r.GetUserId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "user id не указан")
+	}
+
+	return nil
+}
+
+func validateRefresh(r *ssov1.RefreshRequest) error {
+	if r.GetRefreshToken() == "" {
+		return status.Error(codes.InvalidArgument, "refresh-токен не указан")
+	}
+
+	return nil
+}
+
+func validateIntrospect(r *ssov1.IntrospectRequest) error {
+	if r.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "токен не указан")
+	}
+
+	return nil
+}
+
+func validateClientCredentials(r *ssov1.ClientCredentialsRequest) error {
+	if r.GetClientId() == "" {
+		return status.Error(codes.InvalidArgument, "client id не указан")
+	}
+
+	if r.GetClientSecret() == "" {
+		return status.Error(codes.InvalidArgument, "секрет не указан")
+	}
+
+	return nil
+}
+
+func validateCreateAPIKey(r *ssov1.CreateAPIKeyRequest) error {
+	if r.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "название ключа не указано")
+	}
+
+	for _, scope := range r.GetScopes() {
+		if scope == "" || strings.ContainsAny(scope, " \t\n") {
+			return status.Errorf(
+				codes.InvalidArgument,
+				"неверная область %q",
+				scope,
+			)
+		}
+	}
+
+	if r.GetExpiresAt() != emptyValue &&
+		!time.Unix(r.GetExpiresAt(), 0).After(time.Now()) {
+		return status.Error(codes.InvalidArgument, "срок действия уже истек")
+	}
+
+	return nil
+}
+
+func validateRevokeAPIKey(r *ssov1.RevokeAPIKeyRequest) error {
+	if r.GetId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "id ключа не указан")
+	}
+
+	return nil
+}
+
+func validateExchangeAPIKey(r *ssov1.ExchangeAPIKeyRequest) error {
+	if r.GetApiKey() == "" {
+		return status.Error(codes.InvalidArgument, "API-ключ не указан")
+	}
+
+	return nil
+}
+
+func validateConfirmTOTP(r *ssov1.ConfirmTOTPRequest) error {
+	if r.GetCode() == "" {
+		return status.Error(codes.InvalidArgument, "код не указан")
+	}
+
+	return nil
+}
+
+func validateVerifySecondFactor(r *ssov1.VerifySecondFactorRequest) error {
+	if r.GetChallenge() == "" {
+		return status.Error(codes.InvalidArgument, "вызов не указан")
+	}
+
+	if r.GetCode() == "" {
+		return status.Error(codes.InvalidArgument, "код не указан")
+	}
+
+	return nil
+}
+
+func validateRequestPasswordReset(r *ssov1.RequestPasswordResetRequest) error {
+	if r.GetEmail() == "" {
+		return status.Error(codes.InvalidArgument, "email не указан")
+	}
+
+	return nil
+}
+
+func validateConfirmPasswordReset(r *ssov1.ConfirmPasswordResetRequest) error {
+	if r.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "токен не указан")
+	}
+
+	if r.GetNewPassword() == "" {
+		return status.Error(codes.InvalidArgument, "пароль не указан")
+	}
+
+	return nil
+}
+
+func validateVerifyEmail(r *ssov1.VerifyEmailRequest) error {
+	if r.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "токен не указан")
+	}
+
+	return nil
+}
+
+func validateResendVerification(r *ssov1.ResendVerificationRequest) error {
+	if r.GetEmail() == "" {
+		return status.Error(codes.InvalidArgument, "email не указан")
+	}
+
+	return nil
+}
+
+func validateChangePassword(r *ssov1.ChangePasswordRequest) error {
+	if r.GetOldPassword() == "" {
+		return status.Error(codes.InvalidArgument, "текущий пароль не указан")
+	}
+
+	if r.GetNewPassword() == "" {
+		return status.Error(codes.InvalidArgument, "новый пароль не указан")
+	}
+
+	return nil
+}
+
+func validateUnlockAccount(r *ssov1.UnlockAccountRequest) error {
+	if r.GetUserId() == emptyValue && r.GetIp() == "" {
+		return status.Error(codes.InvalidArgument, "не указан user id или ip")
+	}
+
+	return nil
+}
+
+func validateRevokeSession(r *ssov1.RevokeSessionRequest) error {
+	if r.GetId() == "" {
+		return status.Error(codes.InvalidArgument, "id сеанса не указан")
+	}
+
+	return nil
+}
+
+func validateCreateRole(r *ssov1.CreateRoleRequest) error {
+	if r.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "имя роли не указано")
+	}
+
+	if slices.Contains(r.GetPermissions(), "") {
+		return status.Error(codes.InvalidArgument, "пустое разрешение")
+	}
+
+	return nil
+}
+
+func validateDeleteRole(r *ssov1.DeleteRoleRequest) error {
+	if r.GetId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "id роли не указан")
+	}
+
+	return nil
+}
+
+func validateRoleAssignment(userID, roleID int64) error {
+	if userID == emptyValue {
+		return status.Error(codes.InvalidArgument, "user id не указан")
+	}
+
+	if roleID == emptyValue {
+		return status.Error(codes.InvalidArgument, "id роли не указан")
+	}
+
+	return nil
+}
+
+func validateCheckPermission(r *ssov1.CheckPermissionRequest) error {
+	if r.GetUserId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "user id не указан")
+	}
+
+	if r.GetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "app id не указан")
+	}
+
+	if r.GetPermission() == "" {
+		return status.Error(codes.InvalidArgument, "разрешение не указано")
+	}
+
+	return nil
+}
+
+func validateCreateTenant(r *ssov1.CreateTenantRequest) error {
+	if r.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "имя организации не указано")
+	}
+
+	return nil
+}
+
+func validateGetTenant(r *ssov1.GetTenantRequest) error {
+	if r.GetId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "id организации не указан")
+	}
+
+	return nil
+}
+
+func validateCreateGroup(r *ssov1.CreateGroupRequest) error {
+	if r.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "имя группы не указано")
+	}
+
+	return nil
+}
+
+func validateDeleteGroup(r *ssov1.DeleteGroupRequest) error {
+	if r.GetId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "id группы не указан")
+	}
+
+	return nil
+}
+
+func validateGroupMember(groupID, userID, childGroupID int64) error {
+	if groupID == emptyValue {
+		return status.Error(codes.InvalidArgument, "id группы не указан")
+	}
+
+	if (userID == emptyValue) == (childGroupID == emptyValue) {
+		return status.Error(
+			codes.InvalidArgument,
+			"нужно указать либо user id, либо id вложенной группы",
+		)
+	}
+
+	return nil
+}
+
+func validateGroupRole(groupID, roleID int64) error {
+	if groupID == emptyValue {
+		return status.Error(codes.InvalidArgument, "id группы не указан")
+	}
+
+	if roleID == emptyValue {
+		return status.Error(codes.InvalidArgument, "id роли не указан")
+	}
+
+	return nil
+}
+
+func validateCreateInvitation(r *ssov1.CreateInvitationRequest) error {
+	if r.GetEmail() == "" {
+		return status.Error(codes.InvalidArgument, "email не указан")
+	}
+
+	if r.GetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "app id не указан")
+	}
+
+	return nil
+}
+
+func validateRevokeInvitation(r *ssov1.RevokeInvitationRequest) error {
+	if r.GetId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "id приглашения не указан")
+	}
+
+	return nil
+}
+
+func validateAcceptInvitation(r *ssov1.AcceptInvitationRequest) error {
+	if r.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "токен не указан")
+	}
+
+	if r.GetPassword() == "" {
+		return status.Error(codes.InvalidArgument, "пароль не указан")
+	}
+
+	return nil
+}
+
+func validateGetUser(r *ssov1.GetUserRequest) error {
+	if r.GetUserId() != emptyValue && r.GetEmail() != "" {
+		return status.Error(
+			codes.InvalidArgument,
+			"user id и email нельзя указывать одновременно",
+		)
+	}
+
+	return nil
+}
+
+func validateListUsers(r *ssov1.ListUsersRequest) error {
+	switch r.GetSort() {
+	case "", models.UserSortID, models.UserSortEmail, models.UserSortCreatedAt:
+	default:
+		return status.Error(
+			codes.InvalidArgument,
+			"sort может быть id, email или created_at",
+		)
+	}
+
+	if r.GetPageSize() < 0 {
+		return status.Error(
+			codes.InvalidArgument,
+			"page size не может быть отрицательным",
+		)
+	}
+
+	if r.GetCreatedTo() != emptyValue && r.GetCreatedTo() <= r.GetCreatedFrom() {
+		return status.Error(
+			codes.InvalidArgument,
+			"created to должен быть больше created from",
+		)
+	}
+
+	return nil
+}
+
+func validateSetUserDisabled(r *ssov1.SetUserDisabledRequest) error {
 	if r.GetUserId() == emptyValue {
 		return status.Error(codes.InvalidArgument, "user id не указан")
 	}
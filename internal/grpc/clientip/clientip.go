@@ -0,0 +1,26 @@
+// Package clientip определяет IP-адрес клиента gRPC-вызова.
+// Им пользуются и обработчики, и перехватчики, поэтому
+// он не зависит ни от тех, ни от других.
+package clientip
+
+import (
+	"context"
+	"google.golang.org/grpc/peer"
+	"net"
+)
+
+// FromContext возвращает IP-адрес клиента из соединения
+// или пустую строку, если он неизвестен.
+func FromContext(c context.Context) string {
+	p, ok := peer.FromContext(c)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
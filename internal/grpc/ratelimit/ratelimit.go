@@ -0,0 +1,276 @@
+// Package ratelimit ограничивает частоту вызовов gRPC-методов
+// алгоритмом token bucket.
+//
+// Бюджет метода ведется для каждого IP-адреса клиента, а если
+// в запросе есть app_id существующего приложения — еще и для каждого
+// сочетания адреса и приложения. Выдуманный app_id не дает нового
+// бюджета: такие вызовы расходуют только бюджет адреса.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/grpc/clientip"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"log/slog"
+	"path"
+	"sync"
+	"time"
+)
+
+// sweepInterval как часто из памяти удаляются бюджеты,
+// которые успели восстановиться полностью.
+const sweepInterval = time.Minute
+
+// Limit бюджет вызовов: Requests за Per, причем подряд можно
+// сделать не больше Requests. Нулевой Requests снимает ограничение.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+type Config struct {
+	// Default применяется к методам, которых нет в Methods.
+	Default Limit
+	// Methods лимиты по короткому имени метода, например "Login".
+	Methods map[string]Limit
+	// AppsPerIP во сколько раз бюджет метода для IP-адреса больше
+	// бюджета для адреса и одного приложения: столько приложений
+	// за одним адресом могут расходовать свой бюджет полностью.
+	// Не больше 1 — бюджеты равны.
+	AppsPerIP int
+}
+
+// AppProvider возвращает приложение по id.
+type AppProvider interface {
+	App(c context.Context, appID int32) (models.App, error)
+}
+
+type Limiter struct {
+	log       *slog.Logger
+	cfg       Config
+	apps      AppProvider
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+}
+
+type bucketKey struct {
+	ip     string
+	appID  int32
+	method string
+}
+
+type bucket struct {
+	limit   Limit
+	tokens  float64
+	updated time.Time
+}
+
+// appIDGetter реализуют запросы с полем app_id.
+type appIDGetter interface {
+	GetAppId() int32
+}
+
+func New(log *slog.Logger, cfg Config, apps AppProvider) *Limiter {
+	return &Limiter{
+		log:       log,
+		cfg:       cfg,
+		apps:      apps,
+		buckets:   make(map[bucketKey]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// UnaryInterceptor отклоняет вызовы сверх бюджета
+// с codes.ResourceExhausted и RetryInfo в деталях.
+func (l *Limiter) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		c context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		method := path.Base(info.FullMethod)
+
+		limit, ok := l.cfg.Methods[method]
+		if !ok {
+			limit = l.cfg.Default
+		}
+		if limit.Requests <= 0 || limit.Per <= 0 {
+			return handler(c, req)
+		}
+
+		ip := clientip.FromContext(c)
+
+		keys := []bucketKey{{ip: ip, method: method}}
+		limits := []Limit{l.ipLimit(limit)}
+
+		// Адрес без бюджета отклоняется до обращения к хранилищу,
+		// иначе каждый лишний вызов все равно стоил бы запроса.
+		if wait, ok := l.peek(keys[0], limits[0], time.Now()); !ok {
+			l.log.Warn(
+				"превышен лимит вызовов",
+				slog.String("method", method),
+				slog.String("ip", ip),
+			)
+
+			return nil, exhaustedStatus(wait)
+		}
+
+		if r, ok := req.(appIDGetter); ok && l.appExists(c, r.GetAppId()) {
+			keys = append(keys, bucketKey{ip: ip, appID: r.GetAppId(), method: method})
+			limits = append(limits, limit)
+		}
+
+		if wait, ok := l.allow(keys, limits, time.Now()); !ok {
+			l.log.Warn(
+				"превышен лимит вызовов",
+				slog.String("method", method),
+				slog.String("ip", ip),
+				slog.Int("app_id", int(keys[len(keys)-1].appID)),
+			)
+
+			return nil, exhaustedStatus(wait)
+		}
+
+		return handler(c, req)
+	}
+}
+
+// ipLimit возвращает бюджет IP-адреса для метода с лимитом limit.
+func (l *Limiter) ipLimit(limit Limit) Limit {
+	if l.cfg.AppsPerIP > 1 {
+		limit.Requests *= l.cfg.AppsPerIP
+	}
+
+	return limit
+}
+
+// appExists сообщает, есть ли приложение appID. Бюджет по приложению
+// ведется только для существующих, иначе каждый выдуманный app_id
+// получал бы свой бюджет и занимал память.
+func (l *Limiter) appExists(c context.Context, appID int32) bool {
+	if appID == 0 {
+		return false
+	}
+
+	_, err := l.apps.App(c, appID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrAppNotFound) {
+			l.log.Error("не удалось получить приложение", sl.Err(err))
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// allow списывает по одному вызову из бюджетов keys с лимитами limits.
+// Если хотя бы один бюджет исчерпан, ничего не списывает и возвращает
+// false и время до появления следующего вызова.
+func (l *Limiter) allow(
+	keys []bucketKey,
+	limits []Limit,
+	now time.Time,
+) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	buckets := make([]*bucket, len(keys))
+	var wait time.Duration
+	for i, key := range keys {
+		buckets[i] = l.refill(key, limits[i], now)
+		wait = max(wait, buckets[i].wait())
+	}
+	if wait > 0 {
+		return wait, false
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+
+	return 0, true
+}
+
+// peek как allow для одного бюджета, но ничего не списывает.
+func (l *Limiter) peek(key bucketKey, limit Limit, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	wait := l.refill(key, limit, now).wait()
+
+	return wait, wait == 0
+}
+
+// refill возвращает бюджет key, пополненный к моменту now.
+func (l *Limiter) refill(key bucketKey, limit Limit, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{limit: limit, tokens: float64(limit.Requests), updated: now}
+		l.buckets[key] = b
+	}
+
+	// Пополнение в вызовах за наносекунду.
+	rate := float64(limit.Requests) / float64(limit.Per)
+
+	b.tokens = min(
+		float64(limit.Requests),
+		b.tokens+float64(now.Sub(b.updated))*rate,
+	)
+	b.updated = now
+
+	return b
+}
+
+// wait возвращает время до появления в бюджете вызова, 0 — если он есть.
+func (b *bucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	rate := float64(b.limit.Requests) / float64(b.limit.Per)
+
+	return time.Duration((1 - b.tokens) / rate)
+}
+
+// sweep удаляет полностью восстановившиеся бюджеты:
+// новый бюджет для того же ключа будет таким же.
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= b.limit.Per {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastSweep = now
+}
+
+func exhaustedStatus(wait time.Duration) error {
+	st := status.New(
+		codes.ResourceExhausted,
+		"Слишком много запросов, повторите позже",
+	)
+
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(wait),
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
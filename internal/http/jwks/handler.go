@@ -0,0 +1,31 @@
+package jwks
+
+import (
+	"encoding/json"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"net/http"
+)
+
+const Path = "/.well-known/jwks.json"
+
+type KeyProvider interface {
+	JWKS() []models.JWK
+}
+
+// Register регистрирует обработчик, публикующий JWKS-документ.
+func Register(mux *http.ServeMux, keys KeyProvider) {
+	mux.HandleFunc("GET "+Path, func(w http.ResponseWriter, _ *http.Request) {
+		doc := struct {
+			Keys []models.JWK `json:"keys"`
+		}{Keys: keys.JWKS()}
+
+		if doc.Keys == nil {
+			doc.Keys = []models.JWK{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+
+		_ = json.NewEncoder(w).Encode(doc)
+	})
+}
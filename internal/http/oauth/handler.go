@@ -0,0 +1,494 @@
+package oauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/services/auth"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	AuthorizePath = "/authorize"
+	TokenPath     = "/token"
+
+	codeChallengeMethodS256 = "S256"
+
+	// csrfCookie и csrfField cookie и поле формы входа с одним
+	// и тем же токеном: чужой сайт может отправить форму, но не может
+	// прочитать cookie, чтобы подставить токен в поле.
+	csrfCookie = "sso_csrf"
+	csrfField  = "csrf_token"
+)
+
+// Коды ошибок OAuth 2.0 (RFC 6749, разделы 4.1.2.1 и 5.2).
+const (
+	errInvalidRequest          = "invalid_request"
+	errInvalidClient           = "invalid_client"
+	errInvalidGrant            = "invalid_grant"
+	errInvalidScope            = "invalid_scope"
+	errUnsupportedGrantType    = "unsupported_grant_type"
+	errUnsupportedResponseType = "unsupported_response_type"
+	errServerError             = "server_error"
+)
+
+type Auth interface {
+	ValidateAuthorize(c context.Context, r models.AuthorizeRequest) error
+	Authorize(
+		c context.Context,
+		r models.AuthorizeRequest,
+		email string,
+		password string,
+		otp string,
+		clientIP string,
+	) (code string, err error)
+	ExchangeCode(
+		c context.Context,
+		code string,
+		appID int32,
+		clientSecret string,
+		redirectURI string,
+		codeVerifier string,
+		client models.ClientInfo,
+	) (models.TokenPair, error)
+	ExchangeRefreshToken(
+		c context.Context,
+		refreshToken string,
+		appID int32,
+		clientSecret string,
+	) (models.TokenPair, error)
+	ClientCredentials(
+		c context.Context,
+		clientID string,
+		clientSecret string,
+		scope string,
+	) (models.TokenPair, error)
+}
+
+type handler struct {
+	log  *slog.Logger
+	auth Auth
+}
+
+// Register регистрирует эндпоинты OAuth 2.0:
+// авторизацию по коду с PKCE и выдачу токенов,
+// в том числе сервисным аккаунтам (client credentials).
+func Register(mux *http.ServeMux, log *slog.Logger, auth Auth) {
+	h := &handler{
+		log:  log.With(slog.String("op", "http.oauth")),
+		auth: auth,
+	}
+
+	mux.HandleFunc("GET "+AuthorizePath, h.authorizeForm)
+	mux.HandleFunc("POST "+AuthorizePath, h.authorize)
+	mux.HandleFunc("POST "+TokenPath, h.token)
+}
+
+// Обработчики...
+
+func (h *handler) authorizeForm(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if _, ok := h.authorizeRequest(w, r, q); !ok {
+		return
+	}
+
+	csrfToken, err := opaque.New()
+	if err != nil {
+		h.log.Error("не удалось создать CSRF-токен", sl.Err(err))
+		http.Error(w, "внутренняя ошибка", http.StatusInternalServerError)
+
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    csrfToken,
+		Path:     AuthorizePath,
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	renderLogin(w, http.StatusOK, loginPage{Params: q, CSRFToken: csrfToken})
+}
+
+func (h *handler) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "некорректная форма", http.StatusBadRequest)
+
+		return
+	}
+
+	if !validCSRF(r) {
+		h.log.Warn("форма входа без действительного CSRF-токена")
+		http.Error(w, "форма входа устарела, откройте ее заново", http.StatusForbidden)
+
+		return
+	}
+
+	req, ok := h.authorizeRequest(w, r, r.PostForm)
+	if !ok {
+		return
+	}
+
+	code, err := h.auth.Authorize(
+		r.Context(),
+		req,
+		r.PostForm.Get("email"),
+		r.PostForm.Get("password"),
+		r.PostForm.Get("otp"),
+		clientIP(r),
+	)
+	if err != nil {
+		var retryErr *auth.RetryError
+		if errors.As(err, &retryErr) {
+			w.Header().Set(
+				"Retry-After",
+				strconv.Itoa(int(retryErr.RetryAfter.Seconds())),
+			)
+			renderLogin(w, http.StatusTooManyRequests, loginPage{
+				Params:    authorizeParams(r.PostForm),
+				CSRFToken: r.PostForm.Get(csrfField),
+				Error:     "Слишком много неудачных попыток, повторите позже",
+			})
+
+			return
+		}
+
+		var message string
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			message = "Неправильный email или пароль"
+		case errors.Is(err, auth.ErrSecondFactorRequired):
+			message = "Введите код из приложения-аутентификатора"
+		case errors.Is(err, auth.ErrInvalidOTP):
+			message = "Неверный код второго фактора"
+		case errors.Is(err, auth.ErrEmailNotVerified):
+			message = "Подтвердите email по ссылке из письма"
+		case errors.Is(err, auth.ErrUserDisabled):
+			message = "Учетная запись отключена"
+		}
+
+		if message != "" {
+			renderLogin(w, http.StatusUnauthorized, loginPage{
+				Params:    authorizeParams(r.PostForm),
+				CSRFToken: r.PostForm.Get(csrfField),
+				Error:     message,
+			})
+
+			return
+		}
+
+		h.log.Error("не удалось выдать код авторизации", sl.Err(err))
+
+		redirectError(w, r, req.RedirectURI, r.PostForm.Get("state"), errServerError)
+
+		return
+	}
+
+	redirect, _ := url.Parse(req.RedirectURI)
+	q := redirect.Query()
+	q.Set("code", code)
+	if state := r.PostForm.Get("state"); state != "" {
+		q.Set("state", state)
+	}
+	redirect.RawQuery = q.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (h *handler) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, errInvalidRequest)
+
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+
+	var (
+		tokens models.TokenPair
+		err    error
+	)
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		appID, convErr := strconv.ParseInt(clientID, 10, 32)
+		if convErr != nil {
+			tokenError(w, http.StatusUnauthorized, errInvalidClient)
+
+			return
+		}
+
+		tokens, err = h.auth.ExchangeCode(
+			r.Context(),
+			r.PostForm.Get("code"),
+			int32(appID),
+			clientSecret,
+			r.PostForm.Get("redirect_uri"),
+			r.PostForm.Get("code_verifier"),
+			models.ClientInfo{IP: clientIP(r), UserAgent: r.UserAgent()},
+		)
+	case "refresh_token":
+		appID, convErr := strconv.ParseInt(clientID, 10, 32)
+		if convErr != nil {
+			tokenError(w, http.StatusUnauthorized, errInvalidClient)
+
+			return
+		}
+
+		tokens, err = h.auth.ExchangeRefreshToken(
+			r.Context(),
+			r.PostForm.Get("refresh_token"),
+			int32(appID),
+			clientSecret,
+		)
+	case "client_credentials":
+		tokens, err = h.auth.ClientCredentials(
+			r.Context(),
+			clientID,
+			clientSecret,
+			r.PostForm.Get("scope"),
+		)
+	default:
+		tokenError(w, http.StatusBadRequest, errUnsupportedGrantType)
+
+		return
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidClient):
+			tokenError(w, http.StatusUnauthorized, errInvalidClient)
+		case errors.Is(err, auth.ErrInvalidGrant),
+			errors.Is(err, auth.ErrInvalidToken),
+			errors.Is(err, auth.ErrUserDisabled):
+			tokenError(w, http.StatusBadRequest, errInvalidGrant)
+		case errors.Is(err, auth.ErrInvalidScope):
+			tokenError(w, http.StatusBadRequest, errInvalidScope)
+		default:
+			h.log.Error("не удалось выдать токены", sl.Err(err))
+
+			tokenError(w, http.StatusInternalServerError, errServerError)
+		}
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      tokens.IDToken,
+		Scope:        tokens.Scope,
+	})
+}
+
+// authorizeRequest разбирает и проверяет параметры запроса авторизации.
+//
+// Если клиент или адрес возврата неверны, показывает ошибку
+// пользователю, не перенаправляя его. Остальные ошибки
+// передаются клиенту через redirect_uri. В обоих случаях возвращает false.
+func (h *handler) authorizeRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	params url.Values,
+) (models.AuthorizeRequest, bool) {
+	appID, err := strconv.ParseInt(params.Get("client_id"), 10, 32)
+	if err != nil {
+		http.Error(w, "неверный client_id", http.StatusBadRequest)
+
+		return models.AuthorizeRequest{}, false
+	}
+
+	req := models.AuthorizeRequest{
+		AppID:         int32(appID),
+		RedirectURI:   params.Get("redirect_uri"),
+		CodeChallenge: params.Get("code_challenge"),
+		Scope:         params.Get("scope"),
+		Nonce:         params.Get("nonce"),
+	}
+	state := params.Get("state")
+
+	err = h.auth.ValidateAuthorize(r.Context(), req)
+	switch {
+	case errors.Is(err, auth.ErrInvalidAppID):
+		http.Error(w, "неверный client_id", http.StatusBadRequest)
+
+		return models.AuthorizeRequest{}, false
+	case errors.Is(err, auth.ErrInvalidRedirectURI):
+		http.Error(w, "неверный redirect_uri", http.StatusBadRequest)
+
+		return models.AuthorizeRequest{}, false
+	case errors.Is(err, auth.ErrInvalidCodeChallenge):
+		redirectError(w, r, req.RedirectURI, state, errInvalidRequest)
+
+		return models.AuthorizeRequest{}, false
+	case err != nil:
+		h.log.Error("не удалось проверить запрос авторизации", sl.Err(err))
+
+		redirectError(w, r, req.RedirectURI, state, errServerError)
+
+		return models.AuthorizeRequest{}, false
+	}
+
+	if params.Get("response_type") != "code" {
+		redirectError(w, r, req.RedirectURI, state, errUnsupportedResponseType)
+
+		return models.AuthorizeRequest{}, false
+	}
+
+	if params.Get("code_challenge_method") != codeChallengeMethodS256 {
+		redirectError(w, r, req.RedirectURI, state, errInvalidRequest)
+
+		return models.AuthorizeRequest{}, false
+	}
+
+	return req, true
+}
+
+// Ответы...
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+func tokenError(w http.ResponseWriter, code int, oauthErr string) {
+	writeJSON(w, code, map[string]string{"error": oauthErr})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// redirectError возвращает клиенту ошибку авторизации через redirect_uri.
+// Вызывается только для уже проверенного адреса возврата.
+func redirectError(
+	w http.ResponseWriter,
+	r *http.Request,
+	redirectURI string,
+	state string,
+	oauthErr string,
+) {
+	redirect, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "неверный redirect_uri", http.StatusBadRequest)
+
+		return
+	}
+
+	q := redirect.Query()
+	q.Set("error", oauthErr)
+	if state != "" {
+		q.Set("state", state)
+	}
+	redirect.RawQuery = q.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// authorizeParams параметры запроса авторизации,
+// которые форма входа передает обратно скрытыми полями.
+func authorizeParams(form url.Values) url.Values {
+	params := url.Values{}
+
+	for _, name := range []string{
+		"response_type",
+		"client_id",
+		"redirect_uri",
+		"state",
+		"scope",
+		"code_challenge",
+		"code_challenge_method",
+		"nonce",
+	} {
+		if v := form.Get(name); v != "" {
+			params.Set(name, v)
+		}
+	}
+
+	return params
+}
+
+type loginPage struct {
+	Params    url.Values
+	CSRFToken string
+	Error     string
+}
+
+var loginTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head><meta charset="utf-8"><title>Вход</title></head>
+<body>
+<h1>Вход</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="post" action="/authorize">
+{{range $name, $values := .Params}}{{range $values}}<input type="hidden" name="{{$name}}" value="{{.}}">
+{{end}}{{end}}<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<label>Email <input type="email" name="email" required autofocus></label>
+<label>Пароль <input type="password" name="password" required></label>
+<label>Код 2FA, если включена <input type="text" name="otp" autocomplete="one-time-code"></label>
+<button type="submit">Войти</button>
+</form>
+</body>
+</html>
+`))
+
+func renderLogin(w http.ResponseWriter, code int, page loginPage) {
+	page.Params = authorizeParams(page.Params)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(code)
+
+	_ = loginTemplate.Execute(w, page)
+}
+
+// validCSRF проверяет, что токен из формы входа совпадает
+// с токеном из cookie, выданной вместе с формой.
+func validCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(
+		[]byte(cookie.Value),
+		[]byte(r.PostForm.Get(csrfField)),
+	) == 1
+}
+
+// clientIP возвращает IP-адрес клиента из соединения.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,157 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/http/jwks"
+	"github.com/h1lton/sso-grpc-ntc/internal/http/oauth"
+	"github.com/h1lton/sso-grpc-ntc/internal/services/auth"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	DiscoveryPath = "/.well-known/openid-configuration"
+	UserInfoPath  = "/userinfo"
+)
+
+type Auth interface {
+	UserInfo(
+		c context.Context,
+		accessToken string,
+	) (models.User, models.Profile, error)
+	SigningAlgs() []string
+}
+
+type handler struct {
+	log    *slog.Logger
+	auth   Auth
+	issuer string
+}
+
+// Register регистрирует эндпоинты провайдера OpenID Connect:
+// документ обнаружения и userinfo.
+//
+// issuer — внешний адрес сервиса, от него строятся адреса эндпоинтов.
+func Register(mux *http.ServeMux, log *slog.Logger, auth Auth, issuer string) {
+	h := &handler{
+		log:    log.With(slog.String("op", "http.oidc")),
+		auth:   auth,
+		issuer: strings.TrimSuffix(issuer, "/"),
+	}
+
+	mux.HandleFunc("GET "+DiscoveryPath, h.discovery)
+	mux.HandleFunc("GET "+UserInfoPath, h.userInfo)
+	mux.HandleFunc("POST "+UserInfoPath, h.userInfo)
+}
+
+// Обработчики...
+
+func (h *handler) discovery(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                   h.issuer,
+		"authorization_endpoint":   h.issuer + oauth.AuthorizePath,
+		"token_endpoint":           h.issuer + oauth.TokenPath,
+		"userinfo_endpoint":        h.issuer + UserInfoPath,
+		"jwks_uri":                 h.issuer + jwks.Path,
+		"scopes_supported":         []string{auth.ScopeOpenID, "email", "profile"},
+		"response_types_supported": []string{"code"},
+		"grant_types_supported": []string{
+			"authorization_code",
+			"refresh_token",
+			"client_credentials",
+		},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": h.auth.SigningAlgs(),
+		"token_endpoint_auth_methods_supported": []string{
+			"client_secret_basic",
+			"client_secret_post",
+			"none",
+		},
+		"code_challenge_methods_supported": []string{"S256"},
+		"claims_supported": []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce",
+			"email", "email_verified",
+		},
+	})
+}
+
+func (h *handler) userInfo(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(r)
+	if !ok {
+		unauthorized(w, "invalid_request")
+
+		return
+	}
+
+	user, profile, err := h.auth.UserInfo(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			unauthorized(w, "invalid_token")
+
+			return
+		}
+
+		h.log.Error("не удалось получить пользователя", sl.Err(err))
+
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+
+		return
+	}
+
+	info := map[string]any{
+		"sub":            strconv.FormatInt(user.ID, 10),
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+	}
+
+	// Стандартные claims профиля отдаются, только если заполнены.
+	profileClaims := map[string]string{
+		"name":     profile.DisplayName,
+		"locale":   profile.Locale,
+		"zoneinfo": profile.Timezone,
+		"picture":  profile.AvatarURL,
+	}
+	for claim, value := range profileClaims {
+		if value != "" {
+			info[claim] = value
+		}
+	}
+	if !profile.UpdatedAt.IsZero() {
+		info["updated_at"] = profile.UpdatedAt.Unix()
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+// bearerToken извлекает access-токен из заголовка Authorization.
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+
+	h := r.Header.Get("Authorization")
+	if len(h) <= len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return h[len(prefix):], true
+}
+
+// unauthorized отвечает 401 с ошибкой в WWW-Authenticate (RFC 6750).
+func unauthorized(w http.ResponseWriter, bearerErr string) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="`+bearerErr+`"`)
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -1,28 +1,314 @@
 package jwt
 
 import (
+	"errors"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"strconv"
+	"strings"
 	"time"
 )
 
+var ErrInvalidToken = errors.New("недействительный токен")
+
+// Claims утверждения access-токена.
+//
+// В токене сервисного аккаунта UID и Email пусты,
+// а sub начинается с models.ServiceAccountSubjectPrefix.
+type Claims struct {
+	UID           int64  `json:"uid"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	AppID         int    `json:"app_id"`
+	TenantID      int64  `json:"tenant_id"`
+	ClientID      string `json:"client_id,omitempty"`
+	Scope         string `json:"scope,omitempty"`
+	// APIKey префикс API-ключа, в обмен на который выдан токен.
+	APIKey string `json:"api_key,omitempty"`
+	// SessionID сеанс, в котором выдан токен. Пуст у токенов,
+	// выданных не при входе (API-ключи, сервисные аккаунты).
+	SessionID string `json:"sid,omitempty"`
+	// Generation поколение токенов пользователя на момент выпуска.
+	// Токен отозван, если с тех пор токены пользователя отзывались.
+	Generation int64 `json:"gen,omitempty"`
+	// Roles и Permissions роли пользователя в приложении токена
+	// и выданные ими разрешения на момент выпуска.
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	// Groups группы пользователя, включая группы вложенных групп.
+	Groups []string `json:"groups,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Service сообщает, выдан ли токен сервисному аккаунту.
+func (c Claims) Service() bool {
+	return strings.HasPrefix(c.Subject, models.ServiceAccountSubjectPrefix)
+}
+
+// IDClaims утверждения ID-токена OpenID Connect.
+type IDClaims struct {
+	Email         string           `json:"email,omitempty"`
+	EmailVerified bool             `json:"email_verified"`
+	Nonce         string           `json:"nonce,omitempty"`
+	AuthTime      *jwt.NumericDate `json:"auth_time,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// SecretFunc возвращает секрет приложения, которым подписан токен.
+type SecretFunc func(appID int) (string, error)
+
+// NewToken выпускает access-токен пользователя для приложения
+// в сеансе sessionID и поколении токенов generation
+// с ролями, разрешениями и группами access.
+//
+// Если в keys есть ключи для приложения, токен подписывается активным
+// из них и получает заголовок kid. Иначе используется секрет приложения (HS256).
 func NewToken(
+	user models.User,
+	app models.App,
+	sessionID string,
+	generation int64,
+	access models.Access,
+	duration time.Duration,
+	keys *KeySet,
+	issuer string,
+) (string, error) {
+	now := time.Now()
+
+	claims, err := userClaims(user, app, generation, duration, issuer, now)
+	if err != nil {
+		return "", err
+	}
+
+	claims.SessionID = sessionID
+	claims.Roles = access.Roles
+	claims.Permissions = access.Permissions
+	claims.Groups = access.Groups
+
+	return sign(claims, app, keys, now)
+}
+
+// NewAPIKeyToken выпускает access-токен пользователя в обмен на API-ключ.
+// Токен ограничен областями ключа и помечен его префиксом.
+func NewAPIKeyToken(
+	user models.User,
+	app models.App,
+	key models.APIKey,
+	generation int64,
+	duration time.Duration,
+	keys *KeySet,
+	issuer string,
+) (string, error) {
+	now := time.Now()
+
+	claims, err := userClaims(user, app, generation, duration, issuer, now)
+	if err != nil {
+		return "", err
+	}
+
+	claims.Scope = strings.Join(key.Scopes, " ")
+	claims.APIKey = key.Prefix
+
+	return sign(claims, app, keys, now)
+}
+
+func userClaims(
+	user models.User,
+	app models.App,
+	generation int64,
+	duration time.Duration,
+	issuer string,
+	now time.Time,
+) (Claims, error) {
+	jti, err := opaque.New()
+	if err != nil {
+		return Claims{}, err
+	}
+
+	return Claims{
+		UID:           user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		AppID:         app.ID,
+		TenantID:      app.TenantID,
+		Generation:    generation,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    issuer,
+			Subject:   strconv.FormatInt(user.ID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+	}, nil
+}
+
+// NewServiceToken выпускает access-токен сервисного аккаунта
+// с выданными ему областями scope.
+func NewServiceToken(
+	account models.ServiceAccount,
+	app models.App,
+	scope string,
+	duration time.Duration,
+	keys *KeySet,
+	issuer string,
+) (string, error) {
+	jti, err := opaque.New()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	claims := Claims{
+		AppID:    app.ID,
+		TenantID: app.TenantID,
+		ClientID: account.ClientID,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    issuer,
+			Subject:   account.Subject(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+	}
+
+	return sign(claims, app, keys, now)
+}
+
+// NewIDToken выпускает ID-токен OpenID Connect.
+//
+// aud — id приложения, auth_time — момент, когда пользователь
+// ввел учетные данные, nonce переносится из запроса авторизации.
+func NewIDToken(
 	user models.User,
 	app models.App,
 	duration time.Duration,
+	keys *KeySet,
+	issuer string,
+	authTime time.Time,
+	nonce string,
 ) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+	now := time.Now()
+
+	claims := IDClaims{
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Nonce:         nonce,
+		AuthTime:      jwt.NewNumericDate(authTime),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   strconv.FormatInt(user.ID, 10),
+			Audience:  jwt.ClaimStrings{strconv.Itoa(app.ID)},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	claims["uid"] = user.ID
-	claims["email"] = user.Email
-	claims["exp"] = time.Now().Add(duration).Unix()
-	claims["app_id"] = app.ID
+	return sign(claims, app, keys, now)
+}
+
+// sign подписывает утверждения активным ключом приложения,
+// а если ключей для него нет — секретом приложения (HS256).
+func sign(
+	claims jwt.Claims,
+	app models.App,
+	keys *KeySet,
+	now time.Time,
+) (string, error) {
+	if !keys.HasKeys(app.ID) {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).
+			SignedString([]byte(app.Secret))
+	}
 
-	tokenString, err := token.SignedString([]byte(app.Secret))
+	key, err := keys.ForApp(app.ID, now)
 	if err != nil {
 		return "", err
 	}
 
-	return tokenString, nil
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.private)
+}
+
+// Parse проверяет подпись и срок действия токена и возвращает его утверждения.
+//
+// Токен с заголовком kid проверяется открытым ключом из keys,
+// если ключ еще принимается.
+// Токен без kid принимается только от приложения, у которого
+// нет асимметричного ключа, и проверяется его секретом.
+// Любая проблема с токеном возвращается как ErrInvalidToken,
+// ошибки secret пробрасываются как есть.
+func Parse(
+	tokenString string,
+	keys *KeySet,
+	secret SecretFunc,
+) (Claims, error) {
+	var secretErr error
+
+	var claims Claims
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		&claims,
+		func(token *jwt.Token) (interface{}, error) {
+			appID := token.Claims.(*Claims).AppID
+
+			if kid, ok := token.Header["kid"].(string); ok {
+				return verificationKey(token, keys, kid, appID)
+			}
+
+			if keys.HasKeys(appID) {
+				return nil, errors.New("токен должен быть подписан ключом")
+			}
+			if token.Method != jwt.SigningMethodHS256 {
+				return nil, errors.New("неожиданный алгоритм подписи")
+			}
+
+			s, err := secret(appID)
+			if err != nil {
+				secretErr = err
+
+				return nil, err
+			}
+
+			return []byte(s), nil
+		},
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodHS256.Alg(), AlgRS256, AlgES256, AlgEdDSA,
+		}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if secretErr != nil {
+		return Claims{}, secretErr
+	}
+	if err != nil {
+		return Claims{}, errors.Join(ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+// verificationKey возвращает открытый ключ kid,
+// убедившись, что им действительно подписываются токены приложения.
+func verificationKey(
+	token *jwt.Token,
+	keys *KeySet,
+	kid string,
+	appID int,
+) (interface{}, error) {
+	key, ok := keys.ByID(kid, time.Now())
+	if !ok {
+		return nil, errors.New("неизвестный ключ подписи")
+	}
+	if key.AppID != 0 && key.AppID != appID {
+		return nil, errors.New("ключ принадлежит другому приложению")
+	}
+	if token.Method.Alg() != key.Alg {
+		return nil, errors.New("неожиданный алгоритм подписи")
+	}
+
+	return key.private.Public(), nil
 }
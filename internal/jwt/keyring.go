@@ -0,0 +1,305 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// rsaBits размер генерируемых RSA-ключей.
+const rsaBits = 2048
+
+// Manifest описание связки ключей, хранится в JSON-файле.
+//
+// Пути к PEM-файлам указываются относительно каталога манифеста.
+type Manifest struct {
+	Keys []ManifestKey `json:"keys"`
+}
+
+type ManifestKey struct {
+	ID        string     `json:"kid"`
+	Alg       string     `json:"alg"`
+	AppID     int        `json:"app_id,omitempty"`
+	Path      string     `json:"path"`
+	State     KeyState   `json:"state"`
+	NotBefore time.Time  `json:"not_before"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+}
+
+// ReadManifest читает манифест связки ключей.
+func ReadManifest(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var m Manifest
+	if err = json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, fmt.Errorf("манифест %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// WriteManifest атомарно записывает манифест:
+// сначала во временный файл, затем переименовывает его.
+func WriteManifest(path string, m Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Generate создает новый закрытый ключ алгоритма alg для приложения appID,
+// сохраняет его рядом с манифестом path и добавляет в связку
+// в состоянии StateNext.
+//
+// Если токены приложения сейчас не подписывает ни один активный ключ,
+// ни собственный, ни по умолчанию, ключ сразу становится активным:
+// иначе до promote выпускать токены было бы нечем.
+//
+// У приложения может быть только один следующий ключ.
+func (m *Manifest) Generate(
+	path string,
+	alg string,
+	appID int,
+	now time.Time,
+) (ManifestKey, error) {
+	state := StateActive
+	for _, k := range m.Keys {
+		if k.AppID == appID && k.State == StateNext {
+			return ManifestKey{}, fmt.Errorf(
+				"у приложения %d уже есть следующий ключ %q",
+				appID,
+				k.ID,
+			)
+		}
+		if (k.AppID == appID || k.AppID == 0) && k.key().Signs(now) {
+			state = StateNext
+		}
+	}
+
+	private, err := generatePrivateKey(alg)
+	if err != nil {
+		return ManifestKey{}, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return ManifestKey{}, err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err = rand.Read(suffix); err != nil {
+		return ManifestKey{}, err
+	}
+
+	key := ManifestKey{
+		ID:        now.UTC().Format("20060102") + "-" + hex.EncodeToString(suffix),
+		Alg:       alg,
+		AppID:     appID,
+		State:     state,
+		NotBefore: now.UTC().Truncate(time.Second),
+	}
+	key.Path = key.ID + ".pem"
+
+	err = os.WriteFile(
+		filepath.Join(filepath.Dir(path), key.Path),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		0o600,
+	)
+	if err != nil {
+		return ManifestKey{}, err
+	}
+
+	m.Keys = append(m.Keys, key)
+
+	return key, nil
+}
+
+func generatePrivateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case AlgRS256:
+		return rsa.GenerateKey(rand.Reader, rsaBits)
+	case AlgES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+
+		return key, err
+	default:
+		return nil, fmt.Errorf("неподдерживаемый алгоритм %q", alg)
+	}
+}
+
+// Promote делает следующий ключ приложения appID активным.
+//
+// Прежний активный ключ переводится в StateRetired и принимается
+// при проверке еще overlap, чтобы выданные им токены успели истечь.
+// overlap должен быть не меньше времени жизни access-токена.
+func (m *Manifest) Promote(appID int, now time.Time, overlap time.Duration) error {
+	next := -1
+	for i, k := range m.Keys {
+		if k.AppID == appID && k.State == StateNext {
+			next = i
+		}
+	}
+	if next == -1 {
+		return fmt.Errorf("у приложения %d нет следующего ключа", appID)
+	}
+
+	retireAt := now.Add(overlap).UTC()
+
+	for i, k := range m.Keys {
+		if k.AppID == appID && k.State == StateActive {
+			m.Keys[i].State = StateRetired
+			if k.NotAfter == nil || k.NotAfter.After(retireAt) {
+				m.Keys[i].NotAfter = &retireAt
+			}
+		}
+	}
+
+	m.Keys[next].State = StateActive
+	if m.Keys[next].NotBefore.After(now) {
+		m.Keys[next].NotBefore = now.UTC()
+	}
+
+	return nil
+}
+
+// Prune удаляет из связки ключи, которые в момент now уже не принимаются,
+// и возвращает их.
+func (m *Manifest) Prune(now time.Time) []ManifestKey {
+	var kept, pruned []ManifestKey
+
+	for _, k := range m.Keys {
+		if k.key().Verifies(now) {
+			kept = append(kept, k)
+		} else {
+			pruned = append(pruned, k)
+		}
+	}
+
+	m.Keys = kept
+
+	return pruned
+}
+
+func (k ManifestKey) key() Key {
+	key := Key{
+		ID:        k.ID,
+		Alg:       k.Alg,
+		AppID:     k.AppID,
+		State:     k.State,
+		NotBefore: k.NotBefore,
+	}
+	if k.NotAfter != nil {
+		key.NotAfter = *k.NotAfter
+	}
+
+	return key
+}
+
+// KeySet загружает закрытые ключи манифеста path.
+//
+// Ключи, которые уже не принимаются, пропускаются.
+func (m Manifest) KeySet(path string, now time.Time) (*KeySet, error) {
+	dir := filepath.Dir(path)
+
+	keys := make([]Key, 0, len(m.Keys))
+	for _, k := range m.Keys {
+		key := k.key()
+		if !key.Verifies(now) {
+			continue
+		}
+
+		loaded, err := LoadKey(k.ID, k.Alg, filepath.Join(dir, k.Path), k.AppID)
+		if err != nil {
+			return nil, err
+		}
+
+		key.private = loaded.private
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(keys...)
+}
+
+// KeyRing связка ключей подписи, которую можно перечитать с диска
+// без остановки сервиса.
+type KeyRing struct {
+	path string
+	set  atomic.Pointer[KeySet]
+}
+
+// OpenKeyRing загружает связку ключей из манифеста path.
+//
+// Пустой path дает пустую связку: токены подписываются
+// секретами приложений (HS256).
+func OpenKeyRing(path string) (*KeyRing, error) {
+	r := &KeyRing{path: path}
+
+	if path == "" {
+		set, _ := NewKeySet()
+		r.set.Store(set)
+
+		return r, nil
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload перечитывает манифест и ключи.
+//
+// При ошибке продолжает действовать ранее загруженный набор.
+func (r *KeyRing) Reload() error {
+	if r.path == "" {
+		return nil
+	}
+
+	m, err := ReadManifest(r.path)
+	if err != nil {
+		return err
+	}
+
+	set, err := m.KeySet(r.path, time.Now())
+	if err != nil {
+		return err
+	}
+
+	r.set.Store(set)
+
+	return nil
+}
+
+// Keys возвращает текущий набор ключей.
+func (r *KeyRing) Keys() *KeySet {
+	if r == nil {
+		return nil
+	}
+
+	return r.set.Load()
+}
@@ -0,0 +1,323 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"math/big"
+	"os"
+	"time"
+)
+
+// Поддерживаемые алгоритмы асимметричной подписи.
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// KeyState состояние ключа в связке.
+type KeyState string
+
+const (
+	// StateNext ключ опубликован в JWKS и принимается при проверке,
+	// но еще не подписывает токены. Так потребители успевают
+	// получить его заранее.
+	StateNext KeyState = "next"
+	// StateActive единственный ключ приложения, которым подписываются токены.
+	StateActive KeyState = "active"
+	// StateRetired ключ больше не подписывает токены.
+	// Он остается в JWKS и принимается при проверке до NotAfter,
+	// чтобы уже выданные токены дожили до конца срока.
+	StateRetired KeyState = "retired"
+)
+
+var ErrNoKey = errors.New("нет действующего ключа подписи")
+
+// Key закрытый ключ подписи токенов.
+type Key struct {
+	// ID идентификатор ключа, попадает в заголовок kid.
+	ID string
+	// Alg алгоритм подписи: RS256, ES256 или EdDSA.
+	Alg string
+	// AppID приложение, для которого предназначен ключ.
+	// 0 означает ключ по умолчанию для всех приложений.
+	AppID int
+	State KeyState
+	// NotBefore момент, раньше которого ключ не подписывает токены.
+	NotBefore time.Time
+	// NotAfter момент, начиная с которого ключ не принимается.
+	// Нулевое значение — без ограничения.
+	NotAfter time.Time
+
+	private crypto.Signer
+}
+
+// LoadKey читает закрытый ключ из PEM-файла
+// и проверяет, что он подходит для алгоритма alg.
+//
+// Поддерживаются PKCS#8, а также PKCS#1 для RSA и SEC 1 для EC.
+func LoadKey(id, alg, path string, appID int) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+
+	private, err := parsePrivateKey(data)
+	if err != nil {
+		return Key{}, fmt.Errorf("ключ %q: %w", id, err)
+	}
+
+	key := Key{
+		ID:      id,
+		Alg:     alg,
+		AppID:   appID,
+		State:   StateActive,
+		private: private,
+	}
+
+	if err = key.checkAlg(); err != nil {
+		return Key{}, fmt.Errorf("ключ %q: %w", id, err)
+	}
+
+	return key, nil
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("файл не содержит PEM-блок")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("неподдерживаемый тип ключа")
+		}
+
+		return signer, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("не удалось разобрать закрытый ключ")
+}
+
+func (k Key) checkAlg() error {
+	switch k.Alg {
+	case AlgRS256:
+		if _, ok := k.private.(*rsa.PrivateKey); ok {
+			return nil
+		}
+	case AlgES256:
+		if key, ok := k.private.(*ecdsa.PrivateKey); ok &&
+			key.Curve == elliptic.P256() {
+			return nil
+		}
+	case AlgEdDSA:
+		if _, ok := k.private.(ed25519.PrivateKey); ok {
+			return nil
+		}
+	default:
+		return fmt.Errorf("неподдерживаемый алгоритм %q", k.Alg)
+	}
+
+	return fmt.Errorf("ключ не подходит для алгоритма %q", k.Alg)
+}
+
+func (k Key) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Alg)
+}
+
+// Signs сообщает, подписывает ли ключ токены в момент now.
+func (k Key) Signs(now time.Time) bool {
+	return k.State == StateActive &&
+		!now.Before(k.NotBefore) &&
+		k.Verifies(now)
+}
+
+// Verifies сообщает, принимается ли ключ при проверке в момент now.
+func (k Key) Verifies(now time.Time) bool {
+	if k.NotAfter.IsZero() {
+		return k.State != StateRetired
+	}
+
+	return now.Before(k.NotAfter)
+}
+
+// JWK возвращает открытую часть ключа в формате JWK.
+func (k Key) JWK() models.JWK {
+	jwk := models.JWK{Kid: k.ID, Use: "sig", Alg: k.Alg}
+
+	switch pub := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(pub.N.Bytes())
+		jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = b64(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = b64(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(pub)
+	}
+
+	return jwk
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// KeySet набор ключей подписи.
+//
+// Пустой набор допустим: тогда токены подписываются
+// секретом приложения (HS256), как раньше.
+type KeySet struct {
+	keys  []Key
+	byID  map[string]Key
+	byApp map[int][]Key
+}
+
+// NewKeySet собирает набор ключей.
+//
+// Идентификаторы ключей должны быть уникальны,
+// а на каждое приложение (и на значение по умолчанию)
+// может быть не больше одного активного ключа.
+func NewKeySet(keys ...Key) (*KeySet, error) {
+	set := &KeySet{
+		byID:  make(map[string]Key, len(keys)),
+		byApp: make(map[int][]Key, len(keys)),
+	}
+
+	active := make(map[int]bool, len(keys))
+
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("у ключа не указан kid")
+		}
+		if _, ok := set.byID[key.ID]; ok {
+			return nil, fmt.Errorf("ключ %q указан дважды", key.ID)
+		}
+		if key.State == StateActive {
+			if active[key.AppID] {
+				return nil, fmt.Errorf(
+					"для приложения %d указано несколько активных ключей",
+					key.AppID,
+				)
+			}
+
+			active[key.AppID] = true
+		}
+
+		set.keys = append(set.keys, key)
+		set.byID[key.ID] = key
+		set.byApp[key.AppID] = append(set.byApp[key.AppID], key)
+	}
+
+	return set, nil
+}
+
+// HasKeys сообщает, подписываются ли токены приложения ключами набора:
+// собственными ключами приложения или ключами по умолчанию.
+func (s *KeySet) HasKeys(appID int) bool {
+	if s == nil {
+		return false
+	}
+
+	return len(s.byApp[appID]) > 0 || len(s.byApp[0]) > 0
+}
+
+// ForApp возвращает ключ, которым в момент now подписываются
+// токены приложения: собственный ключ приложения, а если ни один
+// из них еще не действует (например, есть только следующий) —
+// ключ по умолчанию.
+//
+// Если не действует ни один подходящий ключ, возвращает ErrNoKey.
+func (s *KeySet) ForApp(appID int, now time.Time) (Key, error) {
+	if s == nil {
+		return Key{}, ErrNoKey
+	}
+
+	for _, key := range s.byApp[appID] {
+		if key.Signs(now) {
+			return key, nil
+		}
+	}
+
+	for _, key := range s.byApp[0] {
+		if key.Signs(now) {
+			return key, nil
+		}
+	}
+
+	return Key{}, ErrNoKey
+}
+
+// ByID возвращает ключ по его идентификатору,
+// если в момент now он принимается при проверке.
+func (s *KeySet) ByID(id string, now time.Time) (Key, bool) {
+	if s == nil {
+		return Key{}, false
+	}
+
+	key, ok := s.byID[id]
+	if !ok || !key.Verifies(now) {
+		return Key{}, false
+	}
+
+	return key, true
+}
+
+// Algs возвращает алгоритмы подписи ключей набора без повторов.
+func (s *KeySet) Algs() []string {
+	if s == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(s.keys))
+	algs := make([]string, 0, len(s.keys))
+	for _, key := range s.keys {
+		if !seen[key.Alg] {
+			seen[key.Alg] = true
+			algs = append(algs, key.Alg)
+		}
+	}
+
+	return algs
+}
+
+// JWKS возвращает открытые ключи набора, принимаемые в момент now.
+func (s *KeySet) JWKS(now time.Time) []models.JWK {
+	if s == nil {
+		return nil
+	}
+
+	jwks := make([]models.JWK, 0, len(s.keys))
+	for _, key := range s.keys {
+		if key.Verifies(now) {
+			jwks = append(jwks, key.JWK())
+		}
+	}
+
+	return jwks
+}
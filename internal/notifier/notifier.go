@@ -0,0 +1,11 @@
+package notifier
+
+import "time"
+
+// Message письмо пользователю.
+type Message struct {
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
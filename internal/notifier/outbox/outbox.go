@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/h1lton/sso-grpc-ntc/internal/notifier"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outbox вместо отправки дописывает письма в файл, по одному
+// JSON-объекту в строке. Для локальной разработки и тестов.
+type Outbox struct {
+	mu   sync.Mutex
+	path string
+}
+
+// New создает Outbox, пишущий в файл path.
+// Каталог файла создается при необходимости.
+func New(path string) (*Outbox, error) {
+	const op = "notifier.outbox.New"
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return &Outbox{path: path}, nil
+}
+
+func (o *Outbox) Send(_ context.Context, msg notifier.Message) error {
+	const op = "notifier.outbox.Send"
+
+	msg.SentAt = time.Now()
+
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	f, err := os.OpenFile(o.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer f.Close()
+
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package smtp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/internal/notifier"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config параметры SMTP-сервера.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Sender отправляет письма через SMTP-сервер.
+//
+// Если сервер поддерживает STARTTLS, соединение шифруется,
+// а логин и пароль передаются только по зашифрованному соединению.
+type Sender struct {
+	cfg Config
+}
+
+func New(cfg Config) *Sender {
+	return &Sender{cfg: cfg}
+}
+
+func (s *Sender) Send(c context.Context, msg notifier.Message) error {
+	const op = "notifier.smtp.Send"
+
+	// Адрес попадает в заголовки письма как есть.
+	if strings.ContainsAny(msg.To, "\r\n") {
+		return operr.Error(op, errors.New("недопустимый адрес получателя"))
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+
+	var d net.Dialer
+	conn, err := d.DialContext(c, "tcp", addr)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	// net/smtp не принимает контекст, поэтому его срок
+	// переносится на соединение.
+	if deadline, ok := c.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+
+		return operr.Error(op, err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: s.cfg.Host})
+		if err != nil {
+			return operr.Error(op, err)
+		}
+	}
+
+	if s.cfg.Username != "" {
+		auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+		if err = client.Auth(auth); err != nil {
+			return operr.Error(op, err)
+		}
+	}
+
+	if err = client.Mail(s.cfg.From); err != nil {
+		return operr.Error(op, err)
+	}
+	if err = client.Rcpt(msg.To); err != nil {
+		return operr.Error(op, err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	if _, err = w.Write(s.render(msg)); err != nil {
+		return operr.Error(op, err)
+	}
+	if err = w.Close(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	if err = client.Quit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// render собирает письмо в формате RFC 5322.
+func (s *Sender) render(msg notifier.Message) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
@@ -0,0 +1,390 @@
+package passwordhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Алгоритмы хэширования.
+const (
+	Bcrypt   = "bcrypt"
+	Argon2id = "argon2id"
+	Scrypt   = "scrypt"
+)
+
+var (
+	ErrMismatch      = errors.New("пароль не совпадает с хэшем")
+	ErrUnknownFormat = errors.New("неизвестный формат хэша")
+)
+
+// Config алгоритм, которым хэшируются новые пароли, и его параметры.
+// Параметры других алгоритмов нужны, только чтобы понять,
+// не слабее ли их хэши требуемого.
+type Config struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Config
+	Scrypt     ScryptConfig
+}
+
+type Argon2Config struct {
+	// Memory объем памяти в КиБ.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type ScryptConfig struct {
+	// N параметр стоимости, степень двойки.
+	N          int
+	R          int
+	P          int
+	SaltLength int
+	KeyLength  int
+}
+
+// Hasher хэширует и проверяет пароли.
+//
+// argon2id и scrypt записываются в формате PHC
+// ($argon2id$v=19$m=...,t=...,p=...$соль$хэш), bcrypt — в своем
+// обычном формате ($2a$...), в котором уже хранятся старые пароли.
+type Hasher struct {
+	cfg Config
+}
+
+func New(cfg Config) (*Hasher, error) {
+	const op = "passwordhash.New"
+
+	switch cfg.Algorithm {
+	case Bcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, operr.Error(
+				op,
+				fmt.Errorf("недопустимая стоимость bcrypt %d", cfg.BcryptCost),
+			)
+		}
+	case Argon2id:
+		a := cfg.Argon2
+		if a.Memory == 0 || a.Iterations == 0 || a.Parallelism == 0 ||
+			a.SaltLength == 0 || a.KeyLength == 0 {
+			return nil, operr.Error(op, errors.New("не заданы параметры argon2id"))
+		}
+	case Scrypt:
+		s := cfg.Scrypt
+		if s.N < 2 || bits.OnesCount(uint(s.N)) != 1 {
+			return nil, operr.Error(
+				op,
+				fmt.Errorf("N для scrypt должен быть степенью двойки: %d", s.N),
+			)
+		}
+		if s.R <= 0 || s.P <= 0 || s.SaltLength <= 0 || s.KeyLength <= 0 {
+			return nil, operr.Error(op, errors.New("не заданы параметры scrypt"))
+		}
+	default:
+		return nil, operr.Error(
+			op,
+			fmt.Errorf("неизвестный алгоритм хэширования %q", cfg.Algorithm),
+		)
+	}
+
+	return &Hasher{cfg: cfg}, nil
+}
+
+// Hash хэширует пароль выбранным в конфиге алгоритмом.
+func (h *Hasher) Hash(password string) ([]byte, error) {
+	const op = "passwordhash.Hash"
+
+	var (
+		hash []byte
+		err  error
+	)
+	switch h.cfg.Algorithm {
+	case Bcrypt:
+		hash, err = bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+	case Argon2id:
+		hash, err = hashArgon2id(password, h.cfg.Argon2)
+	case Scrypt:
+		hash, err = hashScrypt(password, h.cfg.Scrypt)
+	}
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return hash, nil
+}
+
+// Verify проверяет пароль по хэшу любого поддерживаемого алгоритма.
+//
+// Если пароль не подходит, возвращает ErrMismatch.
+func (h *Hasher) Verify(hash []byte, password string) error {
+	const op = "passwordhash.Verify"
+
+	p, err := parse(hash)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	if p.algorithm == Bcrypt {
+		err = bcrypt.CompareHashAndPassword(hash, []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return operr.Error(op, ErrMismatch)
+		}
+		if err != nil {
+			return operr.Error(op, err)
+		}
+
+		return nil
+	}
+
+	key, err := p.derive(password)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	if subtle.ConstantTimeCompare(key, p.key) != 1 {
+		return operr.Error(op, ErrMismatch)
+	}
+
+	return nil
+}
+
+// NeedsRehash сообщает, что хэш получен другим алгоритмом
+// или с более слабыми параметрами, чем задано в конфиге,
+// и его стоит пересчитать при следующем входе.
+func (h *Hasher) NeedsRehash(hash []byte) bool {
+	p, err := parse(hash)
+	if err != nil || p.algorithm != h.cfg.Algorithm {
+		return true
+	}
+
+	switch p.algorithm {
+	case Bcrypt:
+		return p.bcryptCost < h.cfg.BcryptCost
+	case Argon2id:
+		want := h.cfg.Argon2
+
+		return p.argon2.Memory < want.Memory ||
+			p.argon2.Iterations < want.Iterations ||
+			p.argon2.Parallelism < want.Parallelism ||
+			len(p.salt) < int(want.SaltLength) ||
+			len(p.key) < int(want.KeyLength)
+	case Scrypt:
+		want := h.cfg.Scrypt
+
+		return p.scrypt.N < want.N ||
+			p.scrypt.R < want.R ||
+			p.scrypt.P < want.P ||
+			len(p.salt) < want.SaltLength ||
+			len(p.key) < want.KeyLength
+	}
+
+	return true
+}
+
+// parsed разобранный хэш.
+type parsed struct {
+	algorithm  string
+	bcryptCost int
+	argon2     Argon2Config
+	scrypt     ScryptConfig
+	salt       []byte
+	key        []byte
+}
+
+// derive вычисляет ключ из пароля с параметрами и солью хэша p.
+func (p parsed) derive(password string) ([]byte, error) {
+	switch p.algorithm {
+	case Argon2id:
+		return argon2.IDKey(
+			[]byte(password),
+			p.salt,
+			p.argon2.Iterations,
+			p.argon2.Memory,
+			p.argon2.Parallelism,
+			uint32(len(p.key)),
+		), nil
+	case Scrypt:
+		return scrypt.Key(
+			[]byte(password),
+			p.salt,
+			p.scrypt.N,
+			p.scrypt.R,
+			p.scrypt.P,
+			len(p.key),
+		)
+	}
+
+	return nil, ErrUnknownFormat
+}
+
+func hashArgon2id(password string, cfg Argon2Config) ([]byte, error) {
+	salt, err := newSalt(int(cfg.SaltLength))
+	if err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey(
+		[]byte(password),
+		salt,
+		cfg.Iterations,
+		cfg.Memory,
+		cfg.Parallelism,
+		cfg.KeyLength,
+	)
+
+	return []byte(fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		Argon2id,
+		argon2.Version,
+		cfg.Memory,
+		cfg.Iterations,
+		cfg.Parallelism,
+		b64.EncodeToString(salt),
+		b64.EncodeToString(key),
+	)), nil
+}
+
+func hashScrypt(password string, cfg ScryptConfig) ([]byte, error) {
+	salt, err := newSalt(cfg.SaltLength)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := scrypt.Key([]byte(password), salt, cfg.N, cfg.R, cfg.P, cfg.KeyLength)
+	if err != nil {
+		return nil, err
+	}
+
+	// В PHC-формате scrypt стоимость записывается как ln = log2(N).
+	return []byte(fmt.Sprintf(
+		"$%s$ln=%d,r=%d,p=%d$%s$%s",
+		Scrypt,
+		bits.TrailingZeros(uint(cfg.N)),
+		cfg.R,
+		cfg.P,
+		b64.EncodeToString(salt),
+		b64.EncodeToString(key),
+	)), nil
+}
+
+// b64 кодировка соли и хэша в формате PHC.
+var b64 = base64.RawStdEncoding
+
+func newSalt(n int) ([]byte, error) {
+	salt := make([]byte, n)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+// parse разбирает хэш в формате PHC или bcrypt.
+func parse(hash []byte) (parsed, error) {
+	s := string(hash)
+
+	if strings.HasPrefix(s, "$2a$") ||
+		strings.HasPrefix(s, "$2b$") ||
+		strings.HasPrefix(s, "$2y$") {
+		cost, err := bcrypt.Cost(hash)
+		if err != nil {
+			return parsed{}, err
+		}
+
+		return parsed{algorithm: Bcrypt, bcryptCost: cost}, nil
+	}
+
+	// "", алгоритм, [версия,] параметры, соль, хэш.
+	parts := strings.Split(s, "$")
+	if len(parts) < 5 || parts[0] != "" {
+		return parsed{}, ErrUnknownFormat
+	}
+
+	p := parsed{algorithm: parts[1]}
+
+	var params string
+	switch {
+	case p.algorithm == Argon2id && len(parts) == 6:
+		if parts[2] != "v="+strconv.Itoa(argon2.Version) {
+			return parsed{}, ErrUnknownFormat
+		}
+		params = parts[3]
+	case p.algorithm == Scrypt && len(parts) == 5:
+		params = parts[2]
+	default:
+		return parsed{}, ErrUnknownFormat
+	}
+
+	values, err := parseParams(params)
+	if err != nil {
+		return parsed{}, err
+	}
+
+	switch p.algorithm {
+	case Argon2id:
+		// argon2.IDKey паникует на нулевых t и p.
+		if values["m"] == 0 || values["t"] == 0 ||
+			values["p"] == 0 || values["p"] > 255 {
+			return parsed{}, ErrUnknownFormat
+		}
+		p.argon2 = Argon2Config{
+			Memory:      uint32(values["m"]),
+			Iterations:  uint32(values["t"]),
+			Parallelism: uint8(values["p"]),
+		}
+	case Scrypt:
+		if values["ln"] >= 63 {
+			return parsed{}, ErrUnknownFormat
+		}
+		p.scrypt = ScryptConfig{
+			N: 1 << values["ln"],
+			R: int(values["r"]),
+			P: int(values["p"]),
+		}
+	}
+
+	if p.salt, err = b64.DecodeString(parts[len(parts)-2]); err != nil {
+		return parsed{}, ErrUnknownFormat
+	}
+	if p.key, err = b64.DecodeString(parts[len(parts)-1]); err != nil {
+		return parsed{}, ErrUnknownFormat
+	}
+	if len(p.key) == 0 {
+		return parsed{}, ErrUnknownFormat
+	}
+
+	return p, nil
+}
+
+// parseParams разбирает параметры вида "m=65536,t=3,p=2".
+func parseParams(s string) (map[string]uint64, error) {
+	values := make(map[string]uint64)
+
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, ErrUnknownFormat
+		}
+
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, ErrUnknownFormat
+		}
+
+		values[k] = n
+	}
+
+	return values, nil
+}
@@ -0,0 +1,198 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Правила, которые может нарушить пароль.
+const (
+	RuleMinLength = "min_length"
+	RuleMaxBytes  = "max_bytes"
+	RuleLower     = "lower"
+	RuleUpper     = "upper"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleEmail     = "email"
+	RuleCommon    = "common"
+)
+
+// minEmailNameLen минимальная длина имени из email,
+// которое проверяет DisallowEmail.
+const minEmailNameLen = 3
+
+// Config требования к паролю. Нулевое значение ничего не требует.
+type Config struct {
+	// MinLength минимальная длина в символах.
+	MinLength int
+	// MaxBytes максимальная длина в байтах, 0 — без ограничения.
+	// bcrypt молча отбрасывает все после 72 байт.
+	MaxBytes      int
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// DisallowEmail запрещает пароль, содержащий имя из email
+	// (часть до @).
+	DisallowEmail bool
+	// CommonPasswordsPath файл со списком утекших и распространенных
+	// паролей, по одному в строке. Пустой — список не проверяется.
+	CommonPasswordsPath string
+}
+
+// Violation нарушенное правило.
+type Violation struct {
+	Rule        string
+	Description string
+}
+
+// Error возвращается, если пароль нарушает политику.
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		descriptions = append(descriptions, v.Description)
+	}
+
+	return "пароль не соответствует политике: " +
+		strings.Join(descriptions, "; ")
+}
+
+// Policy проверяет пароли на соответствие требованиям.
+type Policy struct {
+	cfg    Config
+	common map[string]struct{}
+}
+
+// New создает политику и загружает список распространенных паролей.
+func New(cfg Config) (*Policy, error) {
+	const op = "passwordpolicy.New"
+
+	p := &Policy{cfg: cfg}
+
+	if cfg.CommonPasswordsPath != "" {
+		common, err := loadCommon(cfg.CommonPasswordsPath)
+		if err != nil {
+			return nil, operr.Error(op, err)
+		}
+
+		p.common = common
+	}
+
+	return p, nil
+}
+
+// Check проверяет пароль пользователя с адресом email.
+//
+// Возвращает *Error со всеми нарушенными правилами или nil.
+// Nil-политика ничего не проверяет.
+func (p *Policy) Check(password string, email string) error {
+	if p == nil {
+		return nil
+	}
+
+	var violations []Violation
+	add := func(rule string, format string, args ...any) {
+		violations = append(violations, Violation{
+			Rule:        rule,
+			Description: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		add(RuleMinLength, "пароль короче %d символов", p.cfg.MinLength)
+	}
+
+	if p.cfg.MaxBytes > 0 && len(password) > p.cfg.MaxBytes {
+		add(RuleMaxBytes, "пароль длиннее %d байт", p.cfg.MaxBytes)
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	if p.cfg.RequireLower && !lower {
+		add(RuleLower, "в пароле нет строчной буквы")
+	}
+	if p.cfg.RequireUpper && !upper {
+		add(RuleUpper, "в пароле нет заглавной буквы")
+	}
+	if p.cfg.RequireDigit && !digit {
+		add(RuleDigit, "в пароле нет цифры")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		add(RuleSymbol, "в пароле нет спецсимвола")
+	}
+
+	if p.cfg.DisallowEmail && containsEmailName(password, email) {
+		add(RuleEmail, "пароль содержит имя из email")
+	}
+
+	if _, ok := p.common[strings.ToLower(password)]; ok {
+		add(RuleCommon, "пароль слишком распространен или уже утекал")
+	}
+
+	if len(violations) > 0 {
+		return &Error{Violations: violations}
+	}
+
+	return nil
+}
+
+// containsEmailName сообщает, содержит ли пароль часть email до @.
+//
+// Слишком короткие имена не проверяются: под запрет
+// попало бы слишком много паролей.
+func containsEmailName(password string, email string) bool {
+	name, _, _ := strings.Cut(email, "@")
+	if utf8.RuneCountInString(name) < minEmailNameLen {
+		return false
+	}
+
+	return strings.Contains(strings.ToLower(password), strings.ToLower(name))
+}
+
+// loadCommon читает список паролей. Пустые строки
+// и строки, начинающиеся с #, пропускаются.
+func loadCommon(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	common := make(map[string]struct{})
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		common[strings.ToLower(line)] = struct{}{}
+	}
+	if err = sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return common, nil
+}
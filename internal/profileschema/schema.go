@@ -0,0 +1,216 @@
+package profileschema
+
+import (
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
+)
+
+// Типы атрибутов профиля.
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeBool   = "bool"
+)
+
+// Поля профиля, которые может нарушить проверка.
+// Атрибуты обозначаются как attributes.<имя>.
+const (
+	FieldDisplayName = "display_name"
+	FieldLocale      = "locale"
+	FieldTimezone    = "timezone"
+	FieldAvatarURL   = "avatar_url"
+	FieldAttributes  = "attributes"
+)
+
+// Ограничения встроенных полей профиля.
+const (
+	maxDisplayNameLen = 100
+	maxAvatarURLLen   = 2048
+)
+
+// locale языковой тег BCP 47: ru, en-US, zh-Hant-TW.
+var locale = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// Attribute описание атрибута профиля.
+type Attribute struct {
+	// Type string, int или bool. Пустой — string.
+	Type string
+	// MaxLength максимальная длина строки в символах, 0 — без ограничения.
+	MaxLength int
+	// Values допустимые значения строки, пустой — любые.
+	Values []string
+}
+
+// Config схема атрибутов профиля по их именам.
+// Пустая схема не допускает атрибутов.
+type Config struct {
+	Attributes map[string]Attribute
+}
+
+// Violation нарушение в поле профиля.
+type Violation struct {
+	Field       string
+	Description string
+}
+
+// Error возвращается, если профиль не соответствует схеме.
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		descriptions = append(descriptions, v.Field+": "+v.Description)
+	}
+
+	return "профиль не соответствует схеме: " +
+		strings.Join(descriptions, "; ")
+}
+
+// Schema проверяет профили пользователей.
+type Schema struct {
+	attributes map[string]Attribute
+}
+
+// New проверяет описание атрибутов и создает схему.
+func New(cfg Config) (*Schema, error) {
+	const op = "profileschema.New"
+
+	attributes := make(map[string]Attribute, len(cfg.Attributes))
+	for name, attr := range cfg.Attributes {
+		if attr.Type == "" {
+			attr.Type = TypeString
+		}
+
+		switch attr.Type {
+		case TypeString:
+		case TypeInt, TypeBool:
+			if attr.MaxLength != 0 || len(attr.Values) != 0 {
+				return nil, operr.Error(op, fmt.Errorf(
+					"атрибут %q: max_length и values только для строк", name,
+				))
+			}
+		default:
+			return nil, operr.Error(op, fmt.Errorf(
+				"атрибут %q: неизвестный тип %q", name, attr.Type,
+			))
+		}
+
+		attributes[name] = attr
+	}
+
+	return &Schema{attributes: attributes}, nil
+}
+
+// Check проверяет профиль.
+//
+// Возвращает *Error со всеми нарушениями или nil.
+// Nil-схема не допускает атрибутов.
+func (s *Schema) Check(p models.Profile) error {
+	var attributes map[string]Attribute
+	if s != nil {
+		attributes = s.attributes
+	}
+
+	var violations []Violation
+	add := func(field string, format string, args ...any) {
+		violations = append(violations, Violation{
+			Field:       field,
+			Description: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if utf8.RuneCountInString(p.DisplayName) > maxDisplayNameLen {
+		add(FieldDisplayName, "длиннее %d символов", maxDisplayNameLen)
+	}
+
+	if p.Locale != "" && !locale.MatchString(p.Locale) {
+		add(FieldLocale, "не языковой тег BCP 47")
+	}
+
+	if p.Timezone != "" && !validTimezone(p.Timezone) {
+		add(FieldTimezone, "неизвестный часовой пояс")
+	}
+
+	if p.AvatarURL != "" {
+		if len(p.AvatarURL) > maxAvatarURLLen {
+			add(FieldAvatarURL, "длиннее %d байт", maxAvatarURLLen)
+		} else if !validURL(p.AvatarURL) {
+			add(FieldAvatarURL, "не абсолютный http(s)-адрес")
+		}
+	}
+
+	// Имена сортируются, чтобы порядок нарушений не зависел от map.
+	names := make([]string, 0, len(p.Attributes))
+	for name := range p.Attributes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		field := FieldAttributes + "." + name
+		value := p.Attributes[name]
+
+		attr, ok := attributes[name]
+		if !ok {
+			add(field, "атрибута нет в схеме")
+
+			continue
+		}
+
+		switch attr.Type {
+		case TypeInt:
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				add(field, "не целое число")
+			}
+		case TypeBool:
+			if _, err := strconv.ParseBool(value); err != nil {
+				add(field, "не true или false")
+			}
+		default:
+			if attr.MaxLength > 0 && utf8.RuneCountInString(value) > attr.MaxLength {
+				add(field, "длиннее %d символов", attr.MaxLength)
+			}
+			if len(attr.Values) > 0 && !slices.Contains(attr.Values, value) {
+				add(field, "допустимые значения: %s", strings.Join(attr.Values, ", "))
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &Error{Violations: violations}
+	}
+
+	return nil
+}
+
+// validTimezone сообщает, известен ли часовой пояс
+// из базы IANA, например Europe/Moscow.
+func validTimezone(name string) bool {
+	if name == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(name)
+
+	return err == nil
+}
+
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
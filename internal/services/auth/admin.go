@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"log/slog"
+)
+
+var ErrPermissionDenied = errors.New("недостаточно прав")
+
+// requireAdmin проверяет, что accessToken выдан администратору,
+// и возвращает его ID.
+//
+// Токены сервисных аккаунтов и API-ключей прав администратора
+// не дают. Если в конфиге требуется второй фактор для администраторов,
+// без него права тоже не выдаются.
+func (a *Auth) requireAdmin(
+	c context.Context,
+	log *slog.Logger,
+	accessToken string,
+) (int64, error) {
+	claims, err := a.verifyUserToken(c, accessToken)
+	if err != nil {
+		logTokenError(log, err)
+
+		return 0, err
+	}
+
+	log = log.With(slog.Int64("caller_id", claims.UID))
+
+	if claims.APIKey != "" {
+		log.Warn("действие администратора по токену API-ключа")
+
+		return 0, ErrPermissionDenied
+	}
+
+	isAdmin, err := a.usrProvider.IsAdmin(c, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("пользователь не найден", sl.Err(err))
+
+			return 0, ErrInvalidToken
+		}
+
+		log.Error("не удалось проверить пользователя", sl.Err(err))
+
+		return 0, err
+	}
+
+	isAdmin, err = a.adminWithSecondFactor(c, log, claims.UID, isAdmin)
+	if err != nil {
+		return 0, err
+	}
+	if !isAdmin {
+		log.Warn("действие администратора без прав")
+
+		return 0, ErrPermissionDenied
+	}
+
+	return claims.UID, nil
+}
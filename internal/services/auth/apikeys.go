@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/jwt"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// apiKeyTag начало каждого API-ключа: по нему ключ легко узнать
+// в конфигах и найти сканерами утечек.
+const apiKeyTag = "sso_"
+
+var (
+	ErrAPIKeyNotFound   = errors.New("API-ключ не найден")
+	ErrAPIKeyNotAllowed = errors.New("API-ключ нельзя создать по токену API-ключа")
+)
+
+// CreateAPIKey создает API-ключ пользователю, которому выдан accessToken,
+// для того же приложения.
+//
+// Ключ возвращается один раз: хранится только хэш его секретной части.
+// Нулевой expiresAt означает бессрочный ключ.
+func (a *Auth) CreateAPIKey(
+	c context.Context,
+	accessToken string,
+	name string,
+	scopes []string,
+	expiresAt time.Time,
+) (string, models.APIKey, error) {
+	const op = "Auth.CreateAPIKey"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.verifyUserToken(c, accessToken)
+	if err != nil {
+		logTokenError(log, err)
+
+		return "", models.APIKey{}, operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", claims.UID))
+
+	// Иначе утекший ключ позволял бы выпускать новые, бессрочные.
+	if claims.APIKey != "" {
+		log.Warn("попытка создать API-ключ по токену API-ключа")
+
+		return "", models.APIKey{}, operr.Error(op, ErrAPIKeyNotAllowed)
+	}
+
+	prefix, err := newAPIKeyPrefix()
+	if err != nil {
+		return "", models.APIKey{}, operr.Error(op, err)
+	}
+
+	secret, err := opaque.New()
+	if err != nil {
+		return "", models.APIKey{}, operr.Error(op, err)
+	}
+
+	key := models.APIKey{
+		UserID:     claims.UID,
+		AppID:      claims.AppID,
+		Name:       name,
+		Prefix:     prefix,
+		SecretHash: opaque.Hash(secret),
+		Scopes:     scopes,
+		CreatedAt:  time.Now(),
+		ExpiresAt:  expiresAt,
+	}
+
+	key.ID, err = a.apiKeyStorage.SaveAPIKey(c, key)
+	if err != nil {
+		log.Error("не удалось сохранить API-ключ", sl.Err(err))
+
+		return "", models.APIKey{}, operr.Error(op, err)
+	}
+
+	log.Info("создан API-ключ", slog.String("prefix", prefix))
+
+	return apiKeyTag + prefix + "_" + secret, key, nil
+}
+
+// ListAPIKeys возвращает неотозванные API-ключи пользователя,
+// которому выдан accessToken.
+func (a *Auth) ListAPIKeys(
+	c context.Context,
+	accessToken string,
+) ([]models.APIKey, error) {
+	const op = "Auth.ListAPIKeys"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.verifyAccountToken(c, accessToken)
+	if err != nil {
+		logTokenError(log, err)
+
+		return nil, operr.Error(op, err)
+	}
+
+	keys, err := a.apiKeyStorage.APIKeys(c, claims.UID)
+	if err != nil {
+		log.Error("не удалось получить API-ключи", sl.Err(err))
+
+		return nil, operr.Error(op, err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает API-ключ keyID пользователя, которому выдан
+// accessToken. Выданные по ключу токены перестают приниматься.
+func (a *Auth) RevokeAPIKey(
+	c context.Context,
+	accessToken string,
+	keyID int64,
+) error {
+	const op = "Auth.RevokeAPIKey"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("key_id", keyID),
+	)
+
+	claims, err := a.verifyAccountToken(c, accessToken)
+	if err != nil {
+		logTokenError(log, err)
+
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", claims.UID))
+
+	err = a.apiKeyStorage.RevokeAPIKey(c, claims.UID, keyID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Warn("API-ключ не найден", sl.Err(err))
+
+			return operr.Error(op, ErrAPIKeyNotFound)
+		}
+
+		log.Error("не удалось отозвать API-ключ", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("API-ключ отозван")
+
+	return nil
+}
+
+// ExchangeAPIKey обменивает API-ключ на access-токен пользователя,
+// ограниченный областями ключа.
+//
+// Для неизвестного, отозванного или истекшего ключа
+// возвращает ErrInvalidToken, для ключа отключенного
+// пользователя — ErrUserDisabled.
+func (a *Auth) ExchangeAPIKey(
+	c context.Context,
+	apiKey string,
+) (models.TokenPair, error) {
+	const op = "Auth.ExchangeAPIKey"
+
+	log := a.log.With(slog.String("op", op))
+
+	prefix, secret, ok := parseAPIKey(apiKey)
+	if !ok {
+		log.Warn("неверный формат API-ключа")
+
+		return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+	}
+
+	log = log.With(slog.String("prefix", prefix))
+
+	key, err := a.apiKeyStorage.APIKey(c, prefix)
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Warn("API-ключ не найден", sl.Err(err))
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		log.Error("не удалось получить API-ключ", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	now := time.Now()
+
+	if subtle.ConstantTimeCompare(opaque.Hash(secret), key.SecretHash) != 1 {
+		log.Warn("неверный секрет API-ключа")
+
+		return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+	}
+	if !key.Active(now) {
+		log.Warn("API-ключ отозван или истек")
+
+		return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+	}
+
+	user, err := a.usrProvider.UserByID(c, key.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	if err = checkUserEnabled(log, user); err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	app, err := a.app(c, log, int32(key.AppID))
+	if err != nil {
+		if errors.Is(err, ErrInvalidAppID) {
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	generation, err := a.tokenStorage.TokenGeneration(c, user.ID)
+	if err != nil {
+		log.Error("не удалось получить поколение токенов", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	token, err := jwt.NewAPIKeyToken(
+		user,
+		app,
+		key,
+		generation,
+		a.cfg.TokenTTL,
+		a.keys.Keys(),
+		a.cfg.Issuer,
+	)
+	if err != nil {
+		log.Error("не удалось выпустить токен", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	// Время последнего использования справочное:
+	// из-за него не стоит отказывать в токене.
+	if err = a.apiKeyStorage.TouchAPIKey(c, key.ID, now); err != nil {
+		log.Warn("не удалось обновить время использования ключа", sl.Err(err))
+	}
+
+	log.Info("API-ключ обменян на токен")
+
+	return models.TokenPair{
+		AccessToken: token,
+		ExpiresIn:   a.cfg.TokenTTL,
+		Scope:       strings.Join(key.Scopes, " "),
+	}, nil
+}
+
+// newAPIKeyPrefix генерирует видимую часть ключа.
+func newAPIKeyPrefix() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// parseAPIKey разбирает ключ вида sso_<prefix>_<secret>.
+// Префикс шестнадцатеричный, поэтому первый "_" после тега
+// отделяет его от секрета, в котором "_" тоже может встречаться.
+func parseAPIKey(key string) (prefix, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(key, apiKeyTag)
+	if !ok {
+		return "", "", false
+	}
+
+	prefix, secret, ok = strings.Cut(rest, "_")
+	if !ok || prefix == "" || secret == "" {
+		return "", "", false
+	}
+
+	return prefix, secret, true
+}
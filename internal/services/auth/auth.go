@@ -5,59 +5,391 @@ import (
 	"errors"
 	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
 	"github.com/h1lton/sso-grpc-ntc/internal/jwt"
+	"github.com/h1lton/sso-grpc-ntc/internal/notifier"
+	"github.com/h1lton/sso-grpc-ntc/internal/passwordhash"
+	"github.com/h1lton/sso-grpc-ntc/internal/passwordpolicy"
+	"github.com/h1lton/sso-grpc-ntc/internal/profileschema"
 	"github.com/h1lton/sso-grpc-ntc/internal/storage"
 	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
 	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
-	"golang.org/x/crypto/bcrypt"
 	"log/slog"
+	"sync"
 	"time"
 )
 
 type Auth struct {
-	log         *slog.Logger
-	usrSaver    UserSaver
-	usrProvider UserProvider
-	appProvider AppProvider
-	tokenTTL    time.Duration
+	log             *slog.Logger
+	usrSaver        UserSaver
+	usrProvider     UserProvider
+	usrUpdater      UserUpdater
+	appProvider     AppProvider
+	tokenStorage    TokenStorage
+	oauthStorage    OAuthStorage
+	svcProvider     ServiceAccountProvider
+	apiKeyStorage   APIKeyStorage
+	mfaStorage      SecondFactorStorage
+	resetStorage    PasswordResetStorage
+	verifyStorage   EmailVerificationStorage
+	throttleStorage LoginThrottleStorage
+	sessionStorage  SessionStorage
+	roleStorage     RoleStorage
+	tenantStorage   TenantStorage
+	groupStorage    GroupStorage
+	inviteStorage   InvitationStorage
+	profileStorage  ProfileStorage
+	notifier        Notifier
+	hasher          PasswordHasher
+	keys            *jwt.KeyRing
+	cfg             Config
+
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+}
+
+// Config параметры сервиса.
+type Config struct {
+	// TokenTTL время жизни access-токена.
+	TokenTTL time.Duration
+	// RefreshTTL время жизни refresh-токена.
+	RefreshTTL time.Duration
+	// AuthCodeTTL время жизни кода авторизации OAuth.
+	AuthCodeTTL time.Duration
+	// Issuer идентификатор провайдера (iss) в выпускаемых токенах.
+	Issuer string
+	// TOTPIssuer название сервиса в приложении-аутентификаторе.
+	TOTPIssuer string
+	// ChallengeTTL время жизни вызова второго фактора.
+	ChallengeTTL time.Duration
+	// RequireAdminMFA признает администратором только пользователя
+	// с включенным вторым фактором.
+	RequireAdminMFA bool
+	// PasswordResetTTL время жизни токена сброса пароля.
+	PasswordResetTTL time.Duration
+	// PasswordResetURL адрес страницы сброса пароля,
+	// токен добавляется к нему параметром token.
+	PasswordResetURL string
+	// EmailVerificationTTL время жизни ссылки подтверждения email.
+	EmailVerificationTTL time.Duration
+	// EmailVerificationURL адрес страницы подтверждения email,
+	// токен добавляется к нему параметром token.
+	EmailVerificationURL string
+	// InvitationTTL время жизни приглашения.
+	InvitationTTL time.Duration
+	// InvitationURL адрес страницы принятия приглашения,
+	// токен добавляется к нему параметром token.
+	InvitationURL string
+	// PasswordPolicy требования к новым паролям, nil — без требований.
+	PasswordPolicy *passwordpolicy.Policy
+	// ProfileSchema схема профиля, nil — без дополнительных атрибутов.
+	ProfileSchema *profileschema.Schema
+	Lockout       Lockout
+	// EnumerationSafeRegistration отвечает на регистрацию одинаково,
+	// занят email или нет: user_id не возвращается, а владельцу
+	// занятого адреса приходит письмо о попытке регистрации.
+	EnumerationSafeRegistration bool
 }
 
 type UserSaver interface {
 	SaveUser(
 		c context.Context,
+		tenantID int64,
 		email string,
 		passHash []byte,
+		createdAt time.Time,
 	) (userID int64, err error)
 }
 
 type UserProvider interface {
-	User(c context.Context, email string) (models.User, error)
+	User(c context.Context, tenantID int64, email string) (models.User, error)
+	UserByID(c context.Context, userID int64) (models.User, error)
 	IsAdmin(c context.Context, userID int64) (bool, error)
+	Users(c context.Context, q models.UserQuery) ([]models.User, error)
+}
+
+type UserUpdater interface {
+	UpdatePassword(c context.Context, userID int64, passHash []byte) error
+	// RehashPassword заменяет хэш пароля, только если он все еще oldHash.
+	RehashPassword(
+		c context.Context,
+		userID int64,
+		oldHash []byte,
+		newHash []byte,
+	) error
+	SetUserDisabled(c context.Context, userID int64, disabled bool) error
 }
 
 type AppProvider interface {
 	App(c context.Context, appID int32) (models.App, error)
 }
 
+type TokenStorage interface {
+	SaveRefreshToken(c context.Context, token models.RefreshToken) error
+	RefreshToken(c context.Context, hash []byte) (models.RefreshToken, error)
+	RotateRefreshToken(
+		c context.Context,
+		oldID int64,
+		next models.RefreshToken,
+	) error
+	RevokeTokenFamily(c context.Context, familyID string) error
+	RevokeAccessToken(c context.Context, jti string, expiresAt time.Time) error
+	RevokeUserTokens(c context.Context, userID int64, at time.Time) error
+	TokenGeneration(c context.Context, userID int64) (int64, error)
+	AccessTokenRevoked(
+		c context.Context,
+		jti string,
+		sid string,
+		userID int64,
+		generation int64,
+	) (bool, error)
+}
+
+type OAuthStorage interface {
+	SaveAuthCode(c context.Context, code models.AuthCode) error
+	AuthCode(c context.Context, hash []byte) (models.AuthCode, error)
+	UseAuthCode(
+		c context.Context,
+		hash []byte,
+		familyID string,
+	) (models.AuthCode, error)
+}
+
+type ServiceAccountProvider interface {
+	ServiceAccount(
+		c context.Context,
+		clientID string,
+	) (models.ServiceAccount, error)
+}
+
+type APIKeyStorage interface {
+	SaveAPIKey(c context.Context, key models.APIKey) (int64, error)
+	APIKey(c context.Context, prefix string) (models.APIKey, error)
+	APIKeys(c context.Context, userID int64) ([]models.APIKey, error)
+	RevokeAPIKey(c context.Context, userID, keyID int64) error
+	TouchAPIKey(c context.Context, keyID int64, at time.Time) error
+}
+
+type SecondFactorStorage interface {
+	SaveTOTP(c context.Context, userID int64, secret string) error
+	TOTP(c context.Context, userID int64) (models.TOTP, error)
+	ConfirmTOTP(
+		c context.Context,
+		userID int64,
+		step int64,
+		recoveryHashes [][]byte,
+	) error
+	UseTOTPStep(c context.Context, userID int64, step int64) error
+	UseRecoveryCode(c context.Context, userID int64, hash []byte) error
+	SaveLoginChallenge(c context.Context, challenge models.LoginChallenge) error
+	LoginChallenge(c context.Context, hash []byte) (models.LoginChallenge, error)
+	AttemptLoginChallenge(c context.Context, id int64, maxAttempts int) error
+	UseLoginChallenge(c context.Context, id int64) error
+}
+
+type PasswordResetStorage interface {
+	SavePasswordResetToken(c context.Context, token models.PasswordResetToken) error
+	PasswordResetToken(
+		c context.Context,
+		hash []byte,
+	) (models.PasswordResetToken, error)
+	ResetPassword(
+		c context.Context,
+		tokenID int64,
+		userID int64,
+		passHash []byte,
+	) error
+}
+
+type EmailVerificationStorage interface {
+	SaveEmailVerificationToken(
+		c context.Context,
+		token models.EmailVerificationToken,
+	) error
+	EmailVerificationToken(
+		c context.Context,
+		hash []byte,
+	) (models.EmailVerificationToken, error)
+	VerifyEmail(c context.Context, token models.EmailVerificationToken) error
+}
+
+// LoginThrottleStorage хранит счетчики неудачных входов,
+// чтобы ограничения переживали перезапуск.
+type LoginThrottleStorage interface {
+	LoginThrottle(
+		c context.Context,
+		scope string,
+		subject string,
+	) (models.LoginThrottle, error)
+	RecordLoginFailure(
+		c context.Context,
+		scope string,
+		subject string,
+		at time.Time,
+		resetAfter time.Duration,
+	) (failures int, err error)
+	SetLoginBlock(
+		c context.Context,
+		scope string,
+		subject string,
+		blockedUntil time.Time,
+		lockedUntil time.Time,
+	) error
+	ResetLoginFailures(c context.Context, scope string, subject string) error
+}
+
+type SessionStorage interface {
+	SaveSession(c context.Context, session models.Session) error
+	TouchSession(
+		c context.Context,
+		id string,
+		lastSeenAt time.Time,
+		expiresAt time.Time,
+	) error
+	Sessions(
+		c context.Context,
+		userID int64,
+		now time.Time,
+	) ([]models.Session, error)
+	RevokeSession(c context.Context, userID int64, id string) error
+}
+
+type RoleStorage interface {
+	SaveRole(c context.Context, role models.Role) (int64, error)
+	Role(c context.Context, id int64) (models.Role, error)
+	Roles(c context.Context, appID int) ([]models.Role, error)
+	UserRoles(
+		c context.Context,
+		userID int64,
+		appID int,
+	) ([]models.Role, error)
+	DeleteRole(c context.Context, id int64) error
+	AssignRole(c context.Context, userID, roleID int64) error
+	UnassignRole(c context.Context, userID, roleID int64) error
+}
+
+type TenantStorage interface {
+	SaveTenant(c context.Context, name string, createdAt time.Time) (int64, error)
+	Tenant(c context.Context, id int64) (models.Tenant, error)
+	Tenants(c context.Context) ([]models.Tenant, error)
+	TenantApps(c context.Context, tenantID int64) ([]models.App, error)
+	TenantUserCount(c context.Context, tenantID int64) (int, error)
+}
+
+type GroupStorage interface {
+	SaveGroup(c context.Context, group models.Group) (int64, error)
+	Group(c context.Context, id int64) (models.Group, error)
+	Groups(c context.Context, tenantID int64) ([]models.Group, error)
+	UserGroups(c context.Context, userID int64) ([]models.Group, error)
+	DeleteGroup(c context.Context, id int64) error
+	AddGroupUser(c context.Context, groupID, userID int64) error
+	RemoveGroupUser(c context.Context, groupID, userID int64) error
+	AddSubgroup(c context.Context, groupID, childID int64) error
+	RemoveSubgroup(c context.Context, groupID, childID int64) error
+	AssignGroupRole(c context.Context, groupID, roleID int64) error
+	UnassignGroupRole(c context.Context, groupID, roleID int64) error
+}
+
+type InvitationStorage interface {
+	SaveInvitation(c context.Context, inv models.Invitation) (int64, error)
+	Invitation(c context.Context, hash []byte) (models.Invitation, error)
+	Invitations(c context.Context, appID int) ([]models.Invitation, error)
+	RevokeInvitation(c context.Context, id int64, at time.Time) error
+	AcceptInvitation(
+		c context.Context,
+		id int64,
+		userID int64,
+		at time.Time,
+	) error
+	AcceptInvitationNewUser(
+		c context.Context,
+		id int64,
+		tenantID int64,
+		email string,
+		passHash []byte,
+		at time.Time,
+	) (int64, error)
+}
+
+type ProfileStorage interface {
+	Profile(c context.Context, userID int64) (models.Profile, error)
+	SaveProfile(c context.Context, profile models.Profile) error
+}
+
+// PasswordHasher хэширует пароли для хранения и проверяет их.
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	// Verify возвращает passwordhash.ErrMismatch,
+	// если пароль не подходит к хэшу.
+	Verify(hash []byte, password string) error
+	// NeedsRehash сообщает, что хэш стоит пересчитать
+	// алгоритмом и параметрами из конфига.
+	NeedsRehash(hash []byte) bool
+}
+
+// Notifier доставляет письма пользователям.
+type Notifier interface {
+	Send(c context.Context, msg notifier.Message) error
+}
+
+// dummyPassword пароль хэша-заглушки, см. verifyDummyPassword.
+const dummyPassword = "dummy password"
+
 var (
 	ErrInvalidCredentials = errors.New("недействительные учетные данные")
 	ErrUserExists         = errors.New("пользователь уже существует")
 	ErrUserNotFound       = errors.New("пользователь не найден")
 	ErrInvalidAppID       = errors.New("неверный id приложения")
+	ErrInvalidToken       = errors.New("недействительный токен")
 )
 
 func New(
 	log *slog.Logger,
 	userSaver UserSaver,
 	userProvider UserProvider,
+	userUpdater UserUpdater,
 	appProvider AppProvider,
-	tokenTTL time.Duration,
+	tokenStorage TokenStorage,
+	oauthStorage OAuthStorage,
+	serviceAccountProvider ServiceAccountProvider,
+	apiKeyStorage APIKeyStorage,
+	mfaStorage SecondFactorStorage,
+	resetStorage PasswordResetStorage,
+	verifyStorage EmailVerificationStorage,
+	throttleStorage LoginThrottleStorage,
+	sessionStorage SessionStorage,
+	roleStorage RoleStorage,
+	tenantStorage TenantStorage,
+	groupStorage GroupStorage,
+	inviteStorage InvitationStorage,
+	profileStorage ProfileStorage,
+	notifier Notifier,
+	hasher PasswordHasher,
+	keys *jwt.KeyRing,
+	cfg Config,
 ) *Auth {
 	return &Auth{
-		log:         log,
-		usrSaver:    userSaver,
-		usrProvider: userProvider,
-		appProvider: appProvider,
-		tokenTTL:    tokenTTL,
+		log:             log,
+		usrSaver:        userSaver,
+		usrProvider:     userProvider,
+		usrUpdater:      userUpdater,
+		appProvider:     appProvider,
+		tokenStorage:    tokenStorage,
+		oauthStorage:    oauthStorage,
+		svcProvider:     serviceAccountProvider,
+		apiKeyStorage:   apiKeyStorage,
+		mfaStorage:      mfaStorage,
+		resetStorage:    resetStorage,
+		verifyStorage:   verifyStorage,
+		throttleStorage: throttleStorage,
+		sessionStorage:  sessionStorage,
+		roleStorage:     roleStorage,
+		tenantStorage:   tenantStorage,
+		groupStorage:    groupStorage,
+		inviteStorage:   inviteStorage,
+		profileStorage:  profileStorage,
+		notifier:        notifier,
+		hasher:          hasher,
+		keys:            keys,
+		cfg:             cfg,
 	}
 }
 
@@ -66,7 +398,8 @@ func (a *Auth) Login(
 	email string,
 	password string,
 	appID int32,
-) (string, error) {
+	client models.ClientInfo,
+) (models.TokenPair, error) {
 	const op = "Auth.Login"
 
 	log := a.log.With(
@@ -76,56 +409,242 @@ func (a *Auth) Login(
 
 	log.Info("попытка войти в систему пользователя")
 
-	user, err := a.usrProvider.User(c, email)
+	// Пользователь ищется в организации, которой принадлежит приложение.
+	app, err := a.app(c, log, appID)
+	if err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	user, err := a.authenticate(c, log, app.TenantID, email, password, client.IP)
+	if err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	if err = checkEmailVerified(log, user, app); err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	enabled, err := a.secondFactorEnabled(c, user.ID)
+	if err != nil {
+		log.Error("не удалось проверить второй фактор", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+	if enabled {
+		challenge, err := a.newLoginChallenge(c, user, app)
+		if err != nil {
+			log.Error("не удалось создать вызов второго фактора", sl.Err(err))
+
+			return models.TokenPair{}, operr.Error(op, err)
+		}
+
+		log.Info("пароль верен, требуется второй фактор")
+
+		return models.TokenPair{Challenge: challenge}, nil
+	}
+
+	a.resetLoginFailures(c, log, user.ID)
+
+	tokens, err := a.startSession(c, user, app, client)
+	if err != nil {
+		log.Error("не удалось выпустить токены", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	log.Info("пользователь успешно вошел в систему")
+
+	return tokens, nil
+}
+
+// authenticate проверяет email и пароль пользователя
+// организации tenantID, входящего с адреса clientIP.
+//
+// Для неизвестного email и неверного пароля возвращает
+// одну и ту же ошибку ErrInvalidCredentials, а для отключенной
+// учетной записи с верным паролем — ErrUserDisabled. Если после
+// неудачных попыток вход для пользователя или адреса
+// ограничен, возвращает *RetryError, не проверяя пароль.
+// Попытки с неизвестным email ограничиваются так же,
+// как с email пользователя.
+//
+// Счетчик неудач пользователя не обнуляется: это делает вызывающий,
+// когда вход завершен, в том числе вторым фактором.
+func (a *Auth) authenticate(
+	c context.Context,
+	log *slog.Logger,
+	tenantID int64,
+	email string,
+	password string,
+	clientIP string,
+) (models.User, error) {
+	var keys []throttleKey
+	if clientIP != "" {
+		keys = append(keys, a.ipThrottleKey(clientIP))
+
+		if err := a.checkThrottle(c, log, keys[0]); err != nil {
+			return models.User{}, err
+		}
+	}
+
+	user, err := a.usrProvider.User(c, tenantID, email)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Warn("пользователь не найден", sl.Err(err))
 
-			return "", operr.Error(op, ErrInvalidCredentials)
+			emailKey := a.emailThrottleKey(tenantID, email)
+			if err = a.checkThrottle(c, log, emailKey); err != nil {
+				return models.User{}, err
+			}
+
+			a.verifyDummyPassword(log, password)
+			a.recordLoginFailure(c, log, append(keys, emailKey)...)
+
+			return models.User{}, ErrInvalidCredentials
 		}
 
 		log.Error("не удалось получить пользователя", sl.Err(err))
 
-		return "", operr.Error(op, err)
+		return models.User{}, err
 	}
 
-	err = bcrypt.CompareHashAndPassword(user.PassHash, []byte(password))
-	if err != nil {
+	userKey := a.userThrottleKey(user.ID)
+	if err = a.checkThrottle(c, log, userKey); err != nil {
+		return models.User{}, err
+	}
+
+	if err = a.verifyPassword(log, user, password); err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			a.recordLoginFailure(c, log, append(keys, userKey)...)
+		}
+
+		return models.User{}, err
+	}
+
+	if err = checkUserEnabled(log, user); err != nil {
+		return models.User{}, err
+	}
+
+	a.rehashPassword(c, log, user, password)
+
+	return user, nil
+}
+
+// verifyPassword проверяет пароль пользователя.
+//
+// Если пароль не подходит, возвращает ErrInvalidCredentials.
+func (a *Auth) verifyPassword(
+	log *slog.Logger,
+	user models.User,
+	password string,
+) error {
+	err := a.hasher.Verify(user.PassHash, password)
+	if errors.Is(err, passwordhash.ErrMismatch) {
 		log.Info("неверный пароль", sl.Err(err))
 
-		return "", operr.Error(op, ErrInvalidCredentials)
+		return ErrInvalidCredentials
+	}
+	if err != nil {
+		log.Error("не удалось проверить пароль", sl.Err(err))
+
+		return err
 	}
 
-	app, err := a.appProvider.App(c, appID)
+	return nil
+}
+
+// verifyDummyPassword сверяет пароль с хэшем-заглушкой, чтобы вход
+// несуществующего пользователя занимал столько же времени, сколько
+// вход с неверным паролем, и по времени ответа нельзя было узнать,
+// зарегистрирован ли email.
+//
+// Заглушка считается при первом вызове текущим алгоритмом
+// с параметрами из конфига.
+func (a *Auth) verifyDummyPassword(log *slog.Logger, password string) {
+	a.dummyHashOnce.Do(func() {
+		var err error
+		a.dummyHash, err = a.hasher.Hash(dummyPassword)
+		if err != nil {
+			log.Error("не удалось сгенерировать хэш-заглушку", sl.Err(err))
+		}
+	})
+
+	if a.dummyHash == nil {
+		return
+	}
+
+	_ = a.hasher.Verify(a.dummyHash, password)
+}
+
+// rehashPassword пересчитывает хэш пароля, если он получен
+// устаревшим алгоритмом или с более слабыми параметрами,
+// чем задано в конфиге. Так пользователи переходят на новый
+// алгоритм при входе, без сброса паролей.
+//
+// Ошибки только пишутся в лог: вход от них не зависит.
+func (a *Auth) rehashPassword(
+	c context.Context,
+	log *slog.Logger,
+	user models.User,
+	password string,
+) {
+	if !a.hasher.NeedsRehash(user.PassHash) {
+		return
+	}
+
+	passHash, err := a.hasher.Hash(password)
 	if err != nil {
-		if errors.Is(err, storage.ErrAppNotFound) {
-			log.Warn("приложение не найдено", sl.Err(err))
+		log.Error("не удалось пересчитать хэш пароля", sl.Err(err))
+
+		return
+	}
+
+	err = a.usrUpdater.RehashPassword(c, user.ID, user.PassHash, passHash)
+	if err != nil {
+		// Пароль успели сменить: новый хэш уже актуален.
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("хэш пароля изменился до пересчета")
 
-			return "", operr.Error(op, ErrInvalidAppID)
+			return
 		}
 
-		log.Error("не удалось получить приложение", sl.Err(err))
+		log.Error("не удалось сохранить новый хэш пароля", sl.Err(err))
 
-		return "", operr.Error(op, err)
+		return
 	}
 
-	log.Info("пользователь успешно вошел в систему")
+	log.Info("хэш пароля пересчитан", slog.Int64("user_id", user.ID))
+}
 
-	token, err := jwt.NewToken(user, app, a.tokenTTL)
+// app возвращает приложение appID или ErrInvalidAppID, если его нет.
+func (a *Auth) app(
+	c context.Context,
+	log *slog.Logger,
+	appID int32,
+) (models.App, error) {
+	app, err := a.appProvider.App(c, appID)
 	if err != nil {
-		log.Error("не удалось сгенерировать JWT-токен", sl.Err(err))
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("приложение не найдено", sl.Err(err))
+
+			return models.App{}, ErrInvalidAppID
+		}
+
+		log.Error("не удалось получить приложение", sl.Err(err))
 
-		return "", operr.Error(op, err)
+		return models.App{}, err
 	}
 
-	return token, nil
+	return app, nil
 }
 
-// Register регистрирует пользователя и возвращает его ID.
+// Register регистрирует пользователя в организации приложения appID
+// и возвращает его ID. При appID = 0 — в организации по умолчанию.
 func (a *Auth) Register(
 	c context.Context,
 	email string,
 	password string,
+	appID int32,
 ) (int64, error) {
 	const op = "auth.Register"
 
@@ -136,31 +655,132 @@ func (a *Auth) Register(
 
 	log.Info("регистрация пользователя")
 
-	passwordHash, err := bcrypt.GenerateFromPassword(
-		[]byte(password),
-		bcrypt.DefaultCost,
-	)
+	tenantID, err := a.tenantOf(c, log, appID)
 	if err != nil {
-		log.Error("не удалось сгенерировать хэш пароля", sl.Err(err))
+		return 0, operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("tenant_id", tenantID))
+
+	id, err := a.saveNewUser(c, log, email, password, func(
+		passHash []byte,
+		createdAt time.Time,
+	) (int64, error) {
+		return a.usrSaver.SaveUser(c, tenantID, email, passHash, createdAt)
+	})
+	if err != nil {
+		if errors.Is(err, ErrUserExists) && a.cfg.EnumerationSafeRegistration {
+			a.notifyRegistrationAttempt(c, log, email)
+
+			return 0, nil
+		}
 
 		return 0, operr.Error(op, err)
 	}
 
-	id, err := a.usrSaver.SaveUser(c, email, passwordHash)
+	a.sendVerification(c, log, models.User{
+		ID:       id,
+		TenantID: tenantID,
+		Email:    email,
+	})
+
+	if a.cfg.EnumerationSafeRegistration {
+		return 0, nil
+	}
+
+	return id, nil
+}
+
+// saveNewUserFunc сохраняет нового пользователя с хэшем пароля
+// passHash и возвращает его ID или storage.ErrUserExists.
+type saveNewUserFunc func(passHash []byte, createdAt time.Time) (int64, error)
+
+// saveNewUser проверяет пароль по политике, хэширует его и сохраняет
+// пользователя с адресом email функцией save. Так регистрация
+// и принятие приглашения создают учетные записи одинаково.
+// Если email в организации уже занят, возвращает ErrUserExists.
+func (a *Auth) saveNewUser(
+	c context.Context,
+	log *slog.Logger,
+	email string,
+	password string,
+	save saveNewUserFunc,
+) (int64, error) {
+	if err := a.checkPassword(log, password, email); err != nil {
+		return 0, err
+	}
+
+	passwordHash, err := a.hasher.Hash(password)
+	if err != nil {
+		log.Error("не удалось сгенерировать хэш пароля", sl.Err(err))
+
+		return 0, err
+	}
+
+	id, err := save(passwordHash, time.Now())
 	if err != nil {
 		if errors.Is(err, storage.ErrUserExists) {
 			log.Warn("пользователь уже существует", sl.Err(err))
 
-			return 0, operr.Error(op, ErrUserExists)
+			return 0, ErrUserExists
 		}
 		log.Error("не удалось сохранить пользователя", sl.Err(err))
 
-		return 0, operr.Error(op, err)
+		return 0, err
+	}
+
+	log.Info("пользователь зарегистрирован", slog.Int64("user_id", id))
+
+	return id, nil
+}
+
+// notifyRegistrationAttempt сообщает владельцу email, что с его адресом
+// пытались зарегистрироваться. Вместо ответа "пользователь уже
+// существует" это узнает только тот, у кого есть доступ к почте.
+//
+// Ошибки только пишутся в лог: ответ на регистрацию от них не зависит.
+func (a *Auth) notifyRegistrationAttempt(
+	c context.Context,
+	log *slog.Logger,
+	email string,
+) {
+	err := a.notifier.Send(c, notifier.Message{
+		To:      email,
+		Subject: "Попытка регистрации",
+		Body: "Кто-то пытался зарегистрироваться с вашим адресом, " +
+			"но учетная запись с ним уже есть.\n\n" +
+			"Если это были вы, войдите или восстановите пароль. " +
+			"Иначе просто проигнорируйте это письмо.\n",
+	})
+	if err != nil {
+		log.Error("не удалось отправить письмо", sl.Err(err))
+
+		return
 	}
 
-	log.Info("пользователь зарегистрирован")
+	log.Info("владельцу email отправлено письмо о попытке регистрации")
+}
+
+// checkPassword проверяет новый пароль пользователя email
+// по политике паролей.
+func (a *Auth) checkPassword(
+	log *slog.Logger,
+	password string,
+	email string,
+) error {
+	err := a.cfg.PasswordPolicy.Check(password, email)
+
+	var policyErr *passwordpolicy.Error
+	if errors.As(err, &policyErr) {
+		rules := make([]string, 0, len(policyErr.Violations))
+		for _, v := range policyErr.Violations {
+			rules = append(rules, v.Rule)
+		}
 
-	return id, err
+		log.Info("пароль не соответствует политике", slog.Any("rules", rules))
+	}
+
+	return err
 }
 
 func (a *Auth) IsAdmin(
@@ -189,6 +809,11 @@ func (a *Auth) IsAdmin(
 		return false, operr.Error(op, err)
 	}
 
+	is, err = a.adminWithSecondFactor(c, log, userID, is)
+	if err != nil {
+		return false, operr.Error(op, err)
+	}
+
 	log.Info(
 		"проверенно",
 		slog.Bool("is_admin", is),
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"time"
+)
+
+var ErrPasswordChangeNotAllowed = errors.New(
+	"пароль нельзя сменить по токену API-ключа",
+)
+
+// ChangePassword меняет пароль пользователю, которому выдан accessToken,
+// если oldPassword совпадает с текущим. Неверный oldPassword
+// учитывается в ограничениях неудачных попыток входа.
+//
+// С revokeOtherSessions завершаются все сеансы пользователя,
+// а вместо текущего начинается новый сеанс с клиента client,
+// пара токенов которого возвращается. Иначе возвращаемая пара пуста.
+func (a *Auth) ChangePassword(
+	c context.Context,
+	accessToken string,
+	oldPassword string,
+	newPassword string,
+	revokeOtherSessions bool,
+	client models.ClientInfo,
+) (models.TokenPair, error) {
+	const op = "Auth.ChangePassword"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.verifyUserToken(c, accessToken)
+	if err != nil {
+		logTokenError(log, err)
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", claims.UID))
+
+	// Утекший API-ключ не должен позволять перехватить учетную запись.
+	if claims.APIKey != "" {
+		log.Warn("попытка сменить пароль по токену API-ключа")
+
+		return models.TokenPair{}, operr.Error(op, ErrPasswordChangeNotAllowed)
+	}
+
+	user, err := a.usrProvider.UserByID(c, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("пользователь не найден", sl.Err(err))
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	// Иначе по украденному access-токену можно было бы подбирать
+	// текущий пароль в обход ограничений входа.
+	userKey := a.userThrottleKey(user.ID)
+	if err = a.checkThrottle(c, log, userKey); err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	if err = a.verifyPassword(log, user, oldPassword); err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			a.recordLoginFailure(c, log, userKey)
+		}
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	a.resetLoginFailures(c, log, user.ID)
+
+	if err = a.checkPassword(log, newPassword, user.Email); err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	passHash, err := a.hasher.Hash(newPassword)
+	if err != nil {
+		log.Error("не удалось сгенерировать хэш пароля", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	if err = a.usrUpdater.UpdatePassword(c, user.ID, passHash); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("пользователь не найден", sl.Err(err))
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		log.Error("не удалось сменить пароль", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	log.Info("пароль изменен")
+
+	if !revokeOtherSessions {
+		return models.TokenPair{}, nil
+	}
+
+	app, err := a.app(c, log, int32(claims.AppID))
+	if err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	err = a.tokenStorage.RevokeUserTokens(c, user.ID, time.Now())
+	if err != nil {
+		log.Error("не удалось отозвать токены пользователя", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	user.PassHash = passHash
+
+	tokens, err := a.startSession(c, user, app, client)
+	if err != nil {
+		log.Error("не удалось выпустить токены", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	log.Info("остальные сеансы завершены")
+
+	return tokens, nil
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/jwt"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+)
+
+var ErrInvalidScope = errors.New("запрошена неразрешенная область")
+
+// ClientCredentials выдает access-токен сервисному аккаунту
+// по его client_id и секрету (OAuth 2.0 client credentials).
+//
+// scope — запрашиваемые области через пробел, пустой запрос
+// получает все разрешенные аккаунту области.
+// Refresh-токен не выдается: аккаунт может просто запросить новый токен.
+func (a *Auth) ClientCredentials(
+	c context.Context,
+	clientID string,
+	clientSecret string,
+	scope string,
+) (models.TokenPair, error) {
+	const op = "Auth.ClientCredentials"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("client_id", clientID),
+	)
+
+	account, err := a.svcProvider.ServiceAccount(c, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrServiceAccountNotFound) {
+			log.Warn("сервисный аккаунт не найден", sl.Err(err))
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidClient)
+		}
+
+		log.Error("не удалось получить сервисный аккаунт", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	secretHash := opaque.Hash(clientSecret)
+	if subtle.ConstantTimeCompare(secretHash, account.SecretHash) != 1 {
+		log.Warn("неверный секрет сервисного аккаунта")
+
+		return models.TokenPair{}, operr.Error(op, ErrInvalidClient)
+	}
+
+	granted, ok := account.GrantScopes(scope)
+	if !ok {
+		log.Warn("запрошена неразрешенная область", slog.String("scope", scope))
+
+		return models.TokenPair{}, operr.Error(op, ErrInvalidScope)
+	}
+
+	app, err := a.app(c, log, int32(account.AppID))
+	if err != nil {
+		if errors.Is(err, ErrInvalidAppID) {
+			return models.TokenPair{}, operr.Error(op, ErrInvalidClient)
+		}
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	token, err := jwt.NewServiceToken(
+		account,
+		app,
+		granted,
+		a.cfg.TokenTTL,
+		a.keys.Keys(),
+		a.cfg.Issuer,
+	)
+	if err != nil {
+		log.Error("не удалось выпустить токен", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	log.Info("сервисному аккаунту выдан токен")
+
+	return models.TokenPair{
+		AccessToken: token,
+		ExpiresIn:   a.cfg.TokenTTL,
+		Scope:       granted,
+	}, nil
+}
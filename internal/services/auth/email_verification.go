@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/notifier"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New(
+		"недействительный токен подтверждения email",
+	)
+	ErrEmailNotVerified = errors.New("email не подтвержден")
+)
+
+// VerifyEmail подтверждает email пользователя по токену из письма.
+func (a *Auth) VerifyEmail(c context.Context, token string) error {
+	const op = "Auth.VerifyEmail"
+
+	log := a.log.With(slog.String("op", op))
+
+	verification, err := a.verifyStorage.EmailVerificationToken(
+		c,
+		opaque.Hash(token),
+	)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Warn("токен подтверждения не найден")
+
+			return operr.Error(op, ErrInvalidVerificationToken)
+		}
+
+		log.Error("не удалось получить токен подтверждения", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", verification.UserID))
+
+	if !verification.Usable(time.Now()) {
+		log.Warn("токен подтверждения использован или истек")
+
+		return operr.Error(op, ErrInvalidVerificationToken)
+	}
+
+	if err = a.verifyStorage.VerifyEmail(c, verification); err != nil {
+		if errors.Is(err, storage.ErrTokenUsed) ||
+			errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("токен подтверждения не подходит", sl.Err(err))
+
+			return operr.Error(op, ErrInvalidVerificationToken)
+		}
+
+		log.Error("не удалось подтвердить email", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("email подтвержден")
+
+	return nil
+}
+
+// ResendVerification повторно отправляет письмо с подтверждением email
+// пользователю организации приложения appID, при appID = 0 —
+// организации по умолчанию.
+//
+// Как и RequestPasswordReset, отвечает одинаково для известных,
+// неизвестных и уже подтвержденных email.
+func (a *Auth) ResendVerification(
+	c context.Context,
+	email string,
+	appID int32,
+) error {
+	const op = "Auth.ResendVerification"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+	)
+
+	tenantID, err := a.tenantOf(c, log, appID)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	user, err := a.usrProvider.User(c, tenantID, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("подтверждение для неизвестного email")
+
+			return nil
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	if user.EmailVerified {
+		log.Info("email уже подтвержден")
+
+		return nil
+	}
+
+	a.sendVerification(c, log, user)
+
+	return nil
+}
+
+// sendVerification отправляет пользователю ссылку подтверждения email.
+//
+// Ошибки только пишутся в лог: регистрация не должна падать
+// из-за почты, а письмо можно запросить повторно.
+func (a *Auth) sendVerification(
+	c context.Context,
+	log *slog.Logger,
+	user models.User,
+) {
+	token, err := opaque.New()
+	if err != nil {
+		log.Error("не удалось сгенерировать токен подтверждения", sl.Err(err))
+
+		return
+	}
+
+	now := time.Now()
+
+	err = a.verifyStorage.SaveEmailVerificationToken(
+		c,
+		models.EmailVerificationToken{
+			Hash:      opaque.Hash(token),
+			UserID:    user.ID,
+			Email:     user.Email,
+			CreatedAt: now,
+			ExpiresAt: now.Add(a.cfg.EmailVerificationTTL),
+		},
+	)
+	if err != nil {
+		log.Error("не удалось сохранить токен подтверждения", sl.Err(err))
+
+		return
+	}
+
+	err = a.notifier.Send(c, notifier.Message{
+		To:      user.Email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf(
+			"Чтобы подтвердить адрес, перейдите по ссылке:\n\n%s\n\n"+
+				"Ссылка действует %s.\n",
+			linkWithToken(a.cfg.EmailVerificationURL, token),
+			a.cfg.EmailVerificationTTL,
+		),
+	})
+	if err != nil {
+		log.Error("не удалось отправить письмо", sl.Err(err))
+
+		return
+	}
+
+	log.Info("отправлена ссылка подтверждения email")
+}
+
+// checkEmailVerified не пускает пользователя с неподтвержденным email
+// в приложение, которое этого требует.
+func checkEmailVerified(
+	log *slog.Logger,
+	user models.User,
+	app models.App,
+) error {
+	if app.RequireVerifiedEmail && !user.EmailVerified {
+		log.Warn("email не подтвержден", slog.Int64("user_id", user.ID))
+
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
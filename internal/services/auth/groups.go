@@ -0,0 +1,420 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+)
+
+var (
+	ErrGroupExists         = errors.New("группа уже существует")
+	ErrGroupNotFound       = errors.New("группа не найдена")
+	ErrGroupCycle          = errors.New("группа не может входить сама в себя")
+	ErrGroupMemberNotFound = errors.New("участник не состоит в группе")
+	ErrTenantMismatch      = errors.New("объекты принадлежат разным организациям")
+)
+
+// CreateGroup создает группу name в организации tenantID,
+// при tenantID = 0 — в организации по умолчанию.
+// Доступно только администратору.
+func (a *Auth) CreateGroup(
+	c context.Context,
+	accessToken string,
+	tenantID int64,
+	name string,
+) (models.Group, error) {
+	const op = "Auth.CreateGroup"
+
+	if tenantID == 0 {
+		tenantID = models.DefaultTenantID
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("tenant_id", tenantID),
+		slog.String("group", name),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return models.Group{}, operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	if _, err = a.tenantStorage.Tenant(c, tenantID); err != nil {
+		if errors.Is(err, storage.ErrTenantNotFound) {
+			log.Warn("организация не найдена", sl.Err(err))
+
+			return models.Group{}, operr.Error(op, ErrTenantNotFound)
+		}
+
+		log.Error("не удалось получить организацию", sl.Err(err))
+
+		return models.Group{}, operr.Error(op, err)
+	}
+
+	group := models.Group{TenantID: tenantID, Name: name}
+
+	group.ID, err = a.groupStorage.SaveGroup(c, group)
+	if err != nil {
+		if errors.Is(err, storage.ErrGroupExists) {
+			log.Warn("группа уже существует", sl.Err(err))
+
+			return models.Group{}, operr.Error(op, ErrGroupExists)
+		}
+
+		log.Error("не удалось сохранить группу", sl.Err(err))
+
+		return models.Group{}, operr.Error(op, err)
+	}
+
+	log.Info("группа создана", slog.Int64("group_id", group.ID))
+
+	return group, nil
+}
+
+// ListGroups возвращает группы организации tenantID,
+// при tenantID = 0 — организации по умолчанию.
+// Доступно только администратору.
+func (a *Auth) ListGroups(
+	c context.Context,
+	accessToken string,
+	tenantID int64,
+) ([]models.Group, error) {
+	const op = "Auth.ListGroups"
+
+	if tenantID == 0 {
+		tenantID = models.DefaultTenantID
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("tenant_id", tenantID),
+	)
+
+	if _, err := a.requireAdmin(c, log, accessToken); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	groups, err := a.groupStorage.Groups(c, tenantID)
+	if err != nil {
+		log.Error("не удалось получить группы", sl.Err(err))
+
+		return nil, operr.Error(op, err)
+	}
+
+	return groups, nil
+}
+
+// DeleteGroup удаляет группу. Ее участники и вложенные группы
+// теряют роли, полученные через нее. Доступно только администратору.
+func (a *Auth) DeleteGroup(
+	c context.Context,
+	accessToken string,
+	groupID int64,
+) error {
+	const op = "Auth.DeleteGroup"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("group_id", groupID),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	if err = a.groupStorage.DeleteGroup(c, groupID); err != nil {
+		if errors.Is(err, storage.ErrGroupNotFound) {
+			log.Warn("группа не найдена", sl.Err(err))
+
+			return operr.Error(op, ErrGroupNotFound)
+		}
+
+		log.Error("не удалось удалить группу", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("группа удалена")
+
+	return nil
+}
+
+// AddGroupMember добавляет в группу groupID пользователя userID
+// или группу childID — должно быть указано что-то одно. Участник
+// должен принадлежать той же организации, что и группа.
+// Доступно только администратору.
+//
+// Группу, в которую groupID уже входит, добавить нельзя:
+// возвращается ErrGroupCycle.
+func (a *Auth) AddGroupMember(
+	c context.Context,
+	accessToken string,
+	groupID int64,
+	userID int64,
+	childID int64,
+) error {
+	const op = "Auth.AddGroupMember"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("group_id", groupID),
+		slog.Int64("user_id", userID),
+		slog.Int64("child_id", childID),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	group, err := a.group(c, log, groupID)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	if userID != 0 {
+		user, err := a.usrProvider.UserByID(c, userID)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				log.Warn("пользователь не найден", sl.Err(err))
+
+				return operr.Error(op, ErrUserNotFound)
+			}
+
+			log.Error("не удалось получить пользователя", sl.Err(err))
+
+			return operr.Error(op, err)
+		}
+
+		if user.TenantID != group.TenantID {
+			log.Warn("пользователь из другой организации")
+
+			return operr.Error(op, ErrTenantMismatch)
+		}
+
+		if err = a.groupStorage.AddGroupUser(c, groupID, userID); err != nil {
+			log.Error("не удалось добавить пользователя в группу", sl.Err(err))
+
+			return operr.Error(op, err)
+		}
+
+		log.Info("пользователь добавлен в группу")
+
+		return nil
+	}
+
+	child, err := a.group(c, log, childID)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	if child.TenantID != group.TenantID {
+		log.Warn("группа из другой организации")
+
+		return operr.Error(op, ErrTenantMismatch)
+	}
+
+	if err = a.groupStorage.AddSubgroup(c, groupID, childID); err != nil {
+		if errors.Is(err, storage.ErrGroupCycle) {
+			log.Warn("вложение групп образует цикл", sl.Err(err))
+
+			return operr.Error(op, ErrGroupCycle)
+		}
+
+		log.Error("не удалось вложить группу", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("группа вложена")
+
+	return nil
+}
+
+// RemoveGroupMember удаляет из группы groupID пользователя userID
+// или группу childID. Доступно только администратору.
+func (a *Auth) RemoveGroupMember(
+	c context.Context,
+	accessToken string,
+	groupID int64,
+	userID int64,
+	childID int64,
+) error {
+	const op = "Auth.RemoveGroupMember"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("group_id", groupID),
+		slog.Int64("user_id", userID),
+		slog.Int64("child_id", childID),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	if userID != 0 {
+		err = a.groupStorage.RemoveGroupUser(c, groupID, userID)
+	} else {
+		err = a.groupStorage.RemoveSubgroup(c, groupID, childID)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrGroupMemberNotFound) {
+			log.Warn("участник не состоит в группе", sl.Err(err))
+
+			return operr.Error(op, ErrGroupMemberNotFound)
+		}
+
+		log.Error("не удалось удалить участника группы", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("участник удален из группы")
+
+	return nil
+}
+
+// AssignGroupRole назначает роль roleID группе groupID: она действует
+// для всех, кто входит в группу напрямую или через вложенные группы.
+// Роль приложения должна принадлежать организации группы.
+// Доступно только администратору.
+func (a *Auth) AssignGroupRole(
+	c context.Context,
+	accessToken string,
+	groupID int64,
+	roleID int64,
+) error {
+	const op = "Auth.AssignGroupRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("group_id", groupID),
+		slog.Int64("role_id", roleID),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	group, err := a.group(c, log, groupID)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	role, err := a.roleStorage.Role(c, roleID)
+	if err != nil {
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			log.Warn("роль не найдена", sl.Err(err))
+
+			return operr.Error(op, ErrRoleNotFound)
+		}
+
+		log.Error("не удалось получить роль", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	if !role.Global() {
+		app, err := a.app(c, log, int32(role.AppID))
+		if err != nil {
+			return operr.Error(op, err)
+		}
+
+		if app.TenantID != group.TenantID {
+			log.Warn("роль приложения другой организации")
+
+			return operr.Error(op, ErrTenantMismatch)
+		}
+	}
+
+	if err = a.groupStorage.AssignGroupRole(c, groupID, roleID); err != nil {
+		log.Error("не удалось назначить роль группе", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("роль назначена группе")
+
+	return nil
+}
+
+// UnassignGroupRole снимает роль roleID с группы groupID.
+// Доступно только администратору.
+func (a *Auth) UnassignGroupRole(
+	c context.Context,
+	accessToken string,
+	groupID int64,
+	roleID int64,
+) error {
+	const op = "Auth.UnassignGroupRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("group_id", groupID),
+		slog.Int64("role_id", roleID),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	if err = a.groupStorage.UnassignGroupRole(c, groupID, roleID); err != nil {
+		if errors.Is(err, storage.ErrRoleNotAssigned) {
+			log.Warn("роль не назначена группе", sl.Err(err))
+
+			return operr.Error(op, ErrRoleNotAssigned)
+		}
+
+		log.Error("не удалось снять роль с группы", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("роль снята с группы")
+
+	return nil
+}
+
+// group возвращает группу id или ErrGroupNotFound, если ее нет.
+func (a *Auth) group(
+	c context.Context,
+	log *slog.Logger,
+	id int64,
+) (models.Group, error) {
+	group, err := a.groupStorage.Group(c, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrGroupNotFound) {
+			log.Warn("группа не найдена", sl.Err(err))
+
+			return models.Group{}, ErrGroupNotFound
+		}
+
+		log.Error("не удалось получить группу", sl.Err(err))
+
+		return models.Group{}, err
+	}
+
+	return group, nil
+}
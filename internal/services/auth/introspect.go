@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/jwt"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"strconv"
+)
+
+// Introspect проверяет access-токен и возвращает его утверждения.
+//
+// Токен считается активным, если подпись верна, срок не истек,
+// токен не отозван и пользователь все еще существует.
+// Недействительный токен не является ошибкой: возвращается Active = false.
+// Для токена API-ключа возвращаются области ключа, без ролей и разрешений.
+func (a *Auth) Introspect(
+	c context.Context,
+	token string,
+) (models.TokenInfo, error) {
+	const op = "Auth.Introspect"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.verifyAccessToken(c, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			log.Info("токен неактивен", sl.Err(err))
+
+			return models.TokenInfo{}, nil
+		}
+
+		log.Error("не удалось проверить токен", sl.Err(err))
+
+		return models.TokenInfo{}, operr.Error(op, err)
+	}
+
+	if claims.Service() {
+		return a.introspectService(c, log, claims)
+	}
+
+	log = log.With(slog.Int64("user_id", claims.UID))
+
+	user, err := a.usrProvider.UserByID(c, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("пользователь токена больше не существует")
+
+			return models.TokenInfo{}, nil
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return models.TokenInfo{}, operr.Error(op, err)
+	}
+
+	info := models.TokenInfo{
+		Active:        true,
+		ID:            claims.ID,
+		Subject:       claims.Subject,
+		UserID:        claims.UID,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		AppID:         claims.AppID,
+		TenantID:      user.TenantID,
+		ClientID:      strconv.Itoa(claims.AppID),
+		IssuedAt:      claims.IssuedAt.Time,
+		ExpiresAt:     claims.ExpiresAt.Time,
+	}
+
+	// Токен API-ключа дает только области ключа, а не все права
+	// пользователя.
+	if claims.APIKey != "" {
+		info.Scope = claims.Scope
+		info.Roles = []string{}
+		info.Permissions = []string{}
+		info.Groups = []string{}
+
+		return info, nil
+	}
+
+	// Роли берутся текущие, а не из токена: снятая роль
+	// перестает действовать до истечения токена.
+	access, err := a.userAccess(c, log, claims.UID, claims.AppID)
+	if err != nil {
+		return models.TokenInfo{}, operr.Error(op, err)
+	}
+
+	info.Roles = access.Roles
+	info.Permissions = access.Permissions
+	info.Groups = access.Groups
+
+	return info, nil
+}
+
+// introspectService дополняет Introspect для токенов сервисных аккаунтов.
+//
+// Токен удаленного аккаунта неактивен.
+func (a *Auth) introspectService(
+	c context.Context,
+	log *slog.Logger,
+	claims jwt.Claims,
+) (models.TokenInfo, error) {
+	const op = "Auth.introspectService"
+
+	log = log.With(slog.String("client_id", claims.ClientID))
+
+	account, err := a.svcProvider.ServiceAccount(c, claims.ClientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrServiceAccountNotFound) {
+			log.Info("сервисный аккаунт токена больше не существует")
+
+			return models.TokenInfo{}, nil
+		}
+
+		log.Error("не удалось получить сервисный аккаунт", sl.Err(err))
+
+		return models.TokenInfo{}, operr.Error(op, err)
+	}
+
+	if account.AppID != claims.AppID || account.Subject() != claims.Subject {
+		log.Warn("токен не соответствует сервисному аккаунту")
+
+		return models.TokenInfo{}, nil
+	}
+
+	return models.TokenInfo{
+		Active:      true,
+		ID:          claims.ID,
+		Subject:     claims.Subject,
+		AppID:       claims.AppID,
+		TenantID:    claims.TenantID,
+		ClientID:    claims.ClientID,
+		Scope:       claims.Scope,
+		Roles:       []string{},
+		Permissions: []string{},
+		IssuedAt:    claims.IssuedAt.Time,
+		ExpiresAt:   claims.ExpiresAt.Time,
+	}, nil
+}
@@ -0,0 +1,344 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/notifier"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrInvalidInvitation  = errors.New("приглашение недействительно или устарело")
+	ErrInvitationNotFound = errors.New("приглашение не найдено")
+	ErrInvitationRole     = errors.New("роль не действует в приложении приглашения")
+)
+
+// CreateInvitation приглашает пользователя с адресом email
+// в приложение appID с ролью roleID (0 — без роли) и отправляет
+// ему ссылку для принятия приглашения. Роль должна быть глобальной
+// или ролью этого приложения. Доступно только администратору.
+func (a *Auth) CreateInvitation(
+	c context.Context,
+	accessToken string,
+	email string,
+	roleID int64,
+	appID int32,
+) (models.Invitation, error) {
+	const op = "Auth.CreateInvitation"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+		slog.Int64("role_id", roleID),
+		slog.Int("app_id", int(appID)),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return models.Invitation{}, operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	app, err := a.app(c, log, appID)
+	if err != nil {
+		return models.Invitation{}, operr.Error(op, err)
+	}
+
+	if roleID != 0 {
+		role, err := a.roleStorage.Role(c, roleID)
+		if err != nil {
+			if errors.Is(err, storage.ErrRoleNotFound) {
+				log.Warn("роль не найдена", sl.Err(err))
+
+				return models.Invitation{}, operr.Error(op, ErrRoleNotFound)
+			}
+
+			log.Error("не удалось получить роль", sl.Err(err))
+
+			return models.Invitation{}, operr.Error(op, err)
+		}
+
+		if !role.Global() && role.AppID != app.ID {
+			log.Warn("роль другого приложения")
+
+			return models.Invitation{}, operr.Error(op, ErrInvitationRole)
+		}
+	}
+
+	token, err := opaque.New()
+	if err != nil {
+		log.Error("не удалось сгенерировать токен приглашения", sl.Err(err))
+
+		return models.Invitation{}, operr.Error(op, err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+
+	inv := models.Invitation{
+		Hash:      opaque.Hash(token),
+		AppID:     app.ID,
+		RoleID:    roleID,
+		Email:     email,
+		InvitedBy: adminID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.cfg.InvitationTTL),
+	}
+
+	inv.ID, err = a.inviteStorage.SaveInvitation(c, inv)
+	if err != nil {
+		log.Error("не удалось сохранить приглашение", sl.Err(err))
+
+		return models.Invitation{}, operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("invitation_id", inv.ID))
+
+	err = a.notifier.Send(c, notifier.Message{
+		To:      email,
+		Subject: "Приглашение в " + app.Name,
+		Body: fmt.Sprintf(
+			"Вас пригласили в %s. Чтобы принять приглашение, "+
+				"перейдите по ссылке:\n\n%s\n\n"+
+				"Ссылка действует %s. Если вы не ждали приглашения, "+
+				"просто проигнорируйте это письмо.\n",
+			app.Name,
+			linkWithToken(a.cfg.InvitationURL, token),
+			a.cfg.InvitationTTL,
+		),
+	})
+	if err != nil {
+		// Без письма приглашение никто не примет: ссылка есть только в нем.
+		log.Error("не удалось отправить приглашение", sl.Err(err))
+
+		return models.Invitation{}, operr.Error(op, err)
+	}
+
+	log.Info("приглашение отправлено")
+
+	return inv, nil
+}
+
+// ListInvitations возвращает приглашения в приложение appID,
+// при appID = 0 — во все приложения. Доступно только администратору.
+func (a *Auth) ListInvitations(
+	c context.Context,
+	accessToken string,
+	appID int32,
+) ([]models.Invitation, error) {
+	const op = "Auth.ListInvitations"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", int(appID)),
+	)
+
+	if _, err := a.requireAdmin(c, log, accessToken); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	invitations, err := a.inviteStorage.Invitations(c, int(appID))
+	if err != nil {
+		log.Error("не удалось получить приглашения", sl.Err(err))
+
+		return nil, operr.Error(op, err)
+	}
+
+	return invitations, nil
+}
+
+// RevokeInvitation отзывает еще не принятое приглашение.
+// Доступно только администратору.
+func (a *Auth) RevokeInvitation(
+	c context.Context,
+	accessToken string,
+	invitationID int64,
+) error {
+	const op = "Auth.RevokeInvitation"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("invitation_id", invitationID),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	err = a.inviteStorage.RevokeInvitation(c, invitationID, time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrInvitationNotFound) {
+			log.Warn("приглашение не найдено", sl.Err(err))
+
+			return operr.Error(op, ErrInvitationNotFound)
+		}
+
+		log.Error("не удалось отозвать приглашение", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("приглашение отозвано")
+
+	return nil
+}
+
+// AcceptInvitation принимает приглашение по токену из письма
+// и возвращает ID пользователя.
+//
+// Если в организации приложения уже есть пользователь с адресом
+// приглашения, password должен быть его паролем: приглашение
+// присоединяется к существующей учетной записи. Иначе учетная
+// запись создается с паролем password, как при регистрации.
+// Роль из приглашения назначается пользователю, а email считается
+// подтвержденным: ссылка пришла на него.
+func (a *Auth) AcceptInvitation(
+	c context.Context,
+	token string,
+	password string,
+) (int64, error) {
+	const op = "Auth.AcceptInvitation"
+
+	log := a.log.With(slog.String("op", op))
+
+	inv, err := a.inviteStorage.Invitation(c, opaque.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Warn("приглашение не найдено")
+
+			return 0, operr.Error(op, ErrInvalidInvitation)
+		}
+
+		log.Error("не удалось получить приглашение", sl.Err(err))
+
+		return 0, operr.Error(op, err)
+	}
+
+	log = log.With(
+		slog.Int64("invitation_id", inv.ID),
+		slog.String("email", inv.Email),
+	)
+
+	if !inv.Pending(time.Now()) {
+		log.Warn("приглашение принято, отозвано или истекло")
+
+		return 0, operr.Error(op, ErrInvalidInvitation)
+	}
+
+	app, err := a.app(c, log, int32(inv.AppID))
+	if err != nil {
+		if errors.Is(err, ErrInvalidAppID) {
+			return 0, operr.Error(op, ErrInvalidInvitation)
+		}
+
+		return 0, operr.Error(op, err)
+	}
+
+	userID, err := a.acceptInvitation(c, log, inv, app.TenantID, password)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenUsed) {
+			log.Warn("приглашение уже принято", sl.Err(err))
+
+			return 0, operr.Error(op, ErrInvalidInvitation)
+		}
+
+		return 0, operr.Error(op, err)
+	}
+
+	log.Info("приглашение принято", slog.Int64("user_id", userID))
+
+	return userID, nil
+}
+
+// acceptInvitation принимает приглашение inv существующим пользователем
+// организации tenantID с адресом приглашения, проверив его пароль,
+// или регистрирует нового с паролем password. Новый пользователь
+// создается в одной транзакции с принятием приглашения, так что
+// при повторном или параллельном принятии лишняя учетная запись
+// не остается.
+//
+// Пароль существующего пользователя проверяется с теми же
+// ограничениями неудачных попыток, что и при входе.
+func (a *Auth) acceptInvitation(
+	c context.Context,
+	log *slog.Logger,
+	inv models.Invitation,
+	tenantID int64,
+	password string,
+) (int64, error) {
+	user, err := a.usrProvider.User(c, tenantID, inv.Email)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return a.acceptInvitationNewUser(c, log, inv, tenantID, password)
+	}
+	if err != nil {
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return 0, err
+	}
+
+	userKey := a.userThrottleKey(user.ID)
+	if err = a.checkThrottle(c, log, userKey); err != nil {
+		return 0, err
+	}
+
+	if err = a.verifyPassword(log, user, password); err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			a.recordLoginFailure(c, log, userKey)
+		}
+
+		return 0, err
+	}
+
+	a.resetLoginFailures(c, log, user.ID)
+
+	err = a.inviteStorage.AcceptInvitation(c, inv.ID, user.ID, time.Now())
+	if err != nil {
+		if !errors.Is(err, storage.ErrTokenUsed) {
+			log.Error("не удалось принять приглашение", sl.Err(err))
+		}
+
+		return 0, err
+	}
+
+	return user.ID, nil
+}
+
+// acceptInvitationNewUser регистрирует пользователя так же,
+// как Register, но в одной транзакции с принятием приглашения.
+//
+// В отличие от Register email сразу считается подтвержденным:
+// ссылка приглашения пришла на этот адрес, поэтому письмо
+// подтверждения не отправляется. Регистрация без раскрытия занятых
+// email здесь не нужна: занятый адрес обрабатывает acceptInvitation,
+// а ErrUserExists возможен только при параллельной регистрации.
+func (a *Auth) acceptInvitationNewUser(
+	c context.Context,
+	log *slog.Logger,
+	inv models.Invitation,
+	tenantID int64,
+	password string,
+) (int64, error) {
+	return a.saveNewUser(c, log, inv.Email, password, func(
+		passHash []byte,
+		createdAt time.Time,
+	) (int64, error) {
+		return a.inviteStorage.AcceptInvitationNewUser(
+			c,
+			inv.ID,
+			tenantID,
+			inv.Email,
+			passHash,
+			createdAt,
+		)
+	})
+}
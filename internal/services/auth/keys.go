@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"time"
+)
+
+// JWKS возвращает открытые ключи, которыми подписываются токены.
+//
+// По ним потребители проверяют токены без общего секрета.
+// Публикуются все ключи, которые сейчас принимаются при проверке,
+// включая следующий и недавно выведенные.
+func (a *Auth) JWKS() []models.JWK {
+	return a.keys.Keys().JWKS(time.Now())
+}
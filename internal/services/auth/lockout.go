@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrTooManyAttempts = errors.New("слишком много неудачных попыток входа")
+	ErrAccountLocked   = errors.New("вход временно заблокирован")
+)
+
+// RetryError отказ во входе из-за неудачных попыток.
+// Err — ErrTooManyAttempts или ErrAccountLocked.
+type RetryError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s, повторите через %s", e.Err, e.RetryAfter)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// Lockout ограничение подбора паролей.
+//
+// После DelayAfter неудач подряд каждая следующая открывает окно,
+// в которое вход не проверяется: BaseDelay, дальше вдвое больше,
+// но не больше MaxDelay. После LockAfter неудач вход блокируется
+// на LockDuration. Счетчик обнуляется, если неудач не было дольше
+// ResetAfter. Порог не больше нуля отключает соответствующее
+// ограничение.
+type Lockout struct {
+	UserDelayAfter int
+	UserLockAfter  int
+	IPDelayAfter   int
+	IPLockAfter    int
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	LockDuration   time.Duration
+	ResetAfter     time.Duration
+}
+
+// throttleKey счетчик, по которому ограничивается вход, и его пороги.
+type throttleKey struct {
+	scope      string
+	subject    string
+	delayAfter int
+	lockAfter  int
+}
+
+func (a *Auth) userThrottleKey(userID int64) throttleKey {
+	return throttleKey{
+		scope:      models.LoginThrottleUser,
+		subject:    strconv.FormatInt(userID, 10),
+		delayAfter: a.cfg.Lockout.UserDelayAfter,
+		lockAfter:  a.cfg.Lockout.UserLockAfter,
+	}
+}
+
+// emailThrottleKey счетчик входов с email, которого нет в организации
+// tenantID. Пороги те же, что у пользователя, иначе по ответу
+// на очередную неудачу было бы видно, зарегистрирован ли email.
+func (a *Auth) emailThrottleKey(tenantID int64, email string) throttleKey {
+	return throttleKey{
+		scope:      models.LoginThrottleEmail,
+		subject:    strconv.FormatInt(tenantID, 10) + ":" + email,
+		delayAfter: a.cfg.Lockout.UserDelayAfter,
+		lockAfter:  a.cfg.Lockout.UserLockAfter,
+	}
+}
+
+func (a *Auth) ipThrottleKey(ip string) throttleKey {
+	return throttleKey{
+		scope:      models.LoginThrottleIP,
+		subject:    ip,
+		delayAfter: a.cfg.Lockout.IPDelayAfter,
+		lockAfter:  a.cfg.Lockout.IPLockAfter,
+	}
+}
+
+// checkThrottle возвращает *RetryError, если вход по ключу
+// заблокирован или еще не истекло окно задержки.
+func (a *Auth) checkThrottle(
+	c context.Context,
+	log *slog.Logger,
+	key throttleKey,
+) error {
+	throttle, err := a.throttleStorage.LoginThrottle(c, key.scope, key.subject)
+	if err != nil {
+		log.Error("не удалось получить счетчик неудачных входов", sl.Err(err))
+
+		return err
+	}
+
+	now := time.Now()
+
+	switch {
+	case throttle.Locked(now):
+		log.Warn(
+			"вход заблокирован",
+			slog.String("scope", key.scope),
+			slog.Time("locked_until", throttle.LockedUntil),
+		)
+
+		return &RetryError{
+			Err:        ErrAccountLocked,
+			RetryAfter: retryAfter(now, throttle.LockedUntil),
+		}
+	case throttle.Blocked(now):
+		log.Warn(
+			"вход отложен после неудачных попыток",
+			slog.String("scope", key.scope),
+			slog.Time("blocked_until", throttle.BlockedUntil),
+		)
+
+		return &RetryError{
+			Err:        ErrTooManyAttempts,
+			RetryAfter: retryAfter(now, throttle.BlockedUntil),
+		}
+	}
+
+	return nil
+}
+
+// recordLoginFailure учитывает неудачный вход по каждому ключу
+// и, если пороги превышены, назначает задержку или блокировку.
+//
+// Ошибки только пишутся в лог: на ответ пользователю
+// (неверные учетные данные) они не влияют.
+func (a *Auth) recordLoginFailure(
+	c context.Context,
+	log *slog.Logger,
+	keys ...throttleKey,
+) {
+	now := time.Now()
+
+	for _, key := range keys {
+		failures, err := a.throttleStorage.RecordLoginFailure(
+			c,
+			key.scope,
+			key.subject,
+			now,
+			a.cfg.Lockout.ResetAfter,
+		)
+		if err != nil {
+			log.Error("не удалось учесть неудачный вход", sl.Err(err))
+
+			continue
+		}
+
+		var blockedUntil, lockedUntil time.Time
+		switch {
+		case key.lockAfter > 0 && failures >= key.lockAfter:
+			lockedUntil = ceilSecond(now.Add(a.cfg.Lockout.LockDuration))
+
+			log.Warn(
+				"вход заблокирован после неудачных попыток",
+				slog.String("scope", key.scope),
+				slog.Int("failures", failures),
+			)
+		case key.delayAfter > 0 && failures >= key.delayAfter:
+			blockedUntil = ceilSecond(now.Add(a.backoff(failures - key.delayAfter)))
+		default:
+			continue
+		}
+
+		err = a.throttleStorage.SetLoginBlock(
+			c,
+			key.scope,
+			key.subject,
+			blockedUntil,
+			lockedUntil,
+		)
+		if err != nil {
+			log.Error("не удалось ограничить вход", sl.Err(err))
+		}
+	}
+}
+
+// resetLoginFailures обнуляет счетчик пользователя после успешного входа.
+//
+// Счетчик IP-адреса не обнуляется: иначе перебор паролей
+// к чужим учетным записям можно было бы перемежать входом в свою.
+func (a *Auth) resetLoginFailures(
+	c context.Context,
+	log *slog.Logger,
+	userID int64,
+) {
+	key := a.userThrottleKey(userID)
+
+	err := a.throttleStorage.ResetLoginFailures(c, key.scope, key.subject)
+	if err != nil {
+		log.Error("не удалось обнулить счетчик неудачных входов", sl.Err(err))
+	}
+}
+
+// backoff возвращает задержку после n-й неудачи сверх порога:
+// BaseDelay * 2^n, но не больше MaxDelay.
+func (a *Auth) backoff(n int) time.Duration {
+	delay := a.cfg.Lockout.BaseDelay
+	for range n {
+		if delay >= a.cfg.Lockout.MaxDelay {
+			break
+		}
+		delay *= 2
+	}
+
+	return min(delay, a.cfg.Lockout.MaxDelay)
+}
+
+// retryAfter округляет время до until вверх до секунды.
+func retryAfter(now time.Time, until time.Time) time.Duration {
+	d := until.Sub(now)
+	if rem := d % time.Second; rem != 0 {
+		d += time.Second - rem
+	}
+
+	return d
+}
+
+// ceilSecond округляет t вверх до секунды: окна хранятся
+// с точностью до секунды и при отбрасывании дробной части
+// задержка могла бы оказаться короче BaseDelay.
+func ceilSecond(t time.Time) time.Time {
+	if r := t.Truncate(time.Second); !r.Equal(t) {
+		return r.Add(time.Second)
+	}
+
+	return t
+}
+
+// UnlockAccount снимает ограничения входа с пользователя userID
+// и (или) IP-адреса ip. Доступно только администратору.
+func (a *Auth) UnlockAccount(
+	c context.Context,
+	accessToken string,
+	userID int64,
+	ip string,
+) error {
+	const op = "Auth.UnlockAccount"
+
+	log := a.log.With(slog.String("op", op))
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log = log.With(
+		slog.Int64("admin_id", adminID),
+		slog.Int64("user_id", userID),
+		slog.String("ip", ip),
+	)
+
+	var keys []throttleKey
+	if userID != 0 {
+		keys = append(keys, a.userThrottleKey(userID))
+	}
+	if ip != "" {
+		keys = append(keys, a.ipThrottleKey(ip))
+	}
+
+	for _, key := range keys {
+		err = a.throttleStorage.ResetLoginFailures(c, key.scope, key.subject)
+		if err != nil {
+			log.Error("не удалось снять ограничение входа", sl.Err(err))
+
+			return operr.Error(op, err)
+		}
+	}
+
+	log.Info("ограничения входа сняты")
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"time"
+)
+
+// Logout завершает текущий сеанс: отзывает access-токен
+// и семейство refresh-токенов его сеанса (sid), а также,
+// если передан, refresh-токен вместе со всем его семейством.
+func (a *Auth) Logout(
+	c context.Context,
+	accessToken string,
+	refreshToken string,
+) error {
+	const op = "Auth.Logout"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.verifyAccessToken(c, accessToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			log.Warn("недействительный access-токен", sl.Err(err))
+		} else {
+			log.Error("не удалось проверить access-токен", sl.Err(err))
+		}
+
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", claims.UID))
+
+	log.Info("выход из сеанса")
+
+	err = a.tokenStorage.RevokeAccessToken(c, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		log.Error("не удалось отозвать access-токен", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	// Семейство refresh-токенов совпадает с сеансом, поэтому сеанс
+	// завершается и без refresh-токена в запросе.
+	if claims.SessionID != "" {
+		err = a.tokenStorage.RevokeTokenFamily(c, claims.SessionID)
+		if err != nil {
+			log.Error("не удалось отозвать семейство токенов", sl.Err(err))
+
+			return operr.Error(op, err)
+		}
+	}
+
+	if refreshToken != "" {
+		rt, err := a.tokenStorage.RefreshToken(c, opaque.Hash(refreshToken))
+		switch {
+		case errors.Is(err, storage.ErrTokenNotFound):
+			log.Warn("refresh-токен не найден", sl.Err(err))
+		case err != nil:
+			log.Error("не удалось получить refresh-токен", sl.Err(err))
+
+			return operr.Error(op, err)
+		case rt.UserID != claims.UID:
+			log.Warn("refresh-токен принадлежит другому пользователю")
+		default:
+			if err = a.tokenStorage.RevokeTokenFamily(c, rt.FamilyID); err != nil {
+				log.Error("не удалось отозвать семейство токенов", sl.Err(err))
+
+				return operr.Error(op, err)
+			}
+		}
+	}
+
+	log.Info("сеанс завершен")
+
+	return nil
+}
+
+// LogoutAll завершает все сеансы пользователя, которому выдан accessToken.
+func (a *Auth) LogoutAll(c context.Context, accessToken string) error {
+	const op = "Auth.LogoutAll"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.verifyAccountToken(c, accessToken)
+	if err != nil {
+		logTokenError(log, err)
+
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", claims.UID))
+
+	log.Info("выход из всех сеансов")
+
+	if err = a.tokenStorage.RevokeUserTokens(c, claims.UID, time.Now()); err != nil {
+		log.Error("не удалось отозвать токены пользователя", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("все сеансы завершены")
+
+	return nil
+}
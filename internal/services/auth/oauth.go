@@ -0,0 +1,350 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/jwt"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrInvalidRedirectURI   = errors.New("адрес возврата не зарегистрирован")
+	ErrInvalidCodeChallenge = errors.New("неверный code_challenge")
+	ErrInvalidGrant         = errors.New("недействительный код авторизации")
+	ErrInvalidClient        = errors.New("неверные данные клиента")
+)
+
+// ScopeOpenID область, при которой вместе с токенами выдается ID-токен.
+const ScopeOpenID = "openid"
+
+// pkceValue допустимые code_verifier и S256 code_challenge (RFC 7636).
+var pkceValue = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// ValidateAuthorize проверяет запрос авторизации OAuth
+// до того, как показывать пользователю форму входа.
+//
+// ErrInvalidAppID и ErrInvalidRedirectURI означают, что перенаправлять
+// пользователя по redirect_uri нельзя.
+func (a *Auth) ValidateAuthorize(
+	c context.Context,
+	r models.AuthorizeRequest,
+) error {
+	const op = "Auth.ValidateAuthorize"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", int(r.AppID)),
+	)
+
+	app, err := a.app(c, log, r.AppID)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	if !app.AllowsRedirect(r.RedirectURI) {
+		log.Warn(
+			"незарегистрированный адрес возврата",
+			slog.String("redirect_uri", r.RedirectURI),
+		)
+
+		return operr.Error(op, ErrInvalidRedirectURI)
+	}
+
+	if !pkceValue.MatchString(r.CodeChallenge) {
+		return operr.Error(op, ErrInvalidCodeChallenge)
+	}
+
+	return nil
+}
+
+// Authorize проверяет учетные данные пользователя тем же путем,
+// что и Login, и выдает код авторизации OAuth для приложения.
+//
+// Если у пользователя включен второй фактор, otp обязателен:
+// без него возвращается ErrSecondFactorRequired.
+func (a *Auth) Authorize(
+	c context.Context,
+	r models.AuthorizeRequest,
+	email string,
+	password string,
+	otp string,
+	clientIP string,
+) (string, error) {
+	const op = "Auth.Authorize"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+		slog.Int("app_id", int(r.AppID)),
+	)
+
+	if err := a.ValidateAuthorize(c, r); err != nil {
+		return "", operr.Error(op, err)
+	}
+
+	app, err := a.app(c, log, r.AppID)
+	if err != nil {
+		return "", operr.Error(op, err)
+	}
+
+	user, err := a.authenticate(c, log, app.TenantID, email, password, clientIP)
+	if err != nil {
+		return "", operr.Error(op, err)
+	}
+
+	if err = checkEmailVerified(log, user, app); err != nil {
+		return "", operr.Error(op, err)
+	}
+
+	enabled, err := a.secondFactorEnabled(c, user.ID)
+	if err != nil {
+		log.Error("не удалось проверить второй фактор", sl.Err(err))
+
+		return "", operr.Error(op, err)
+	}
+	if enabled {
+		if otp == "" {
+			return "", operr.Error(op, ErrSecondFactorRequired)
+		}
+
+		err = a.checkSecondFactorThrottled(c, log, user.ID, otp, clientIP)
+		if err != nil {
+			return "", operr.Error(op, err)
+		}
+	}
+
+	a.resetLoginFailures(c, log, user.ID)
+
+	code, err := opaque.New()
+	if err != nil {
+		log.Error("не удалось сгенерировать код", sl.Err(err))
+
+		return "", operr.Error(op, err)
+	}
+
+	err = a.oauthStorage.SaveAuthCode(c, models.AuthCode{
+		Hash:          opaque.Hash(code),
+		AppID:         int(r.AppID),
+		UserID:        user.ID,
+		RedirectURI:   r.RedirectURI,
+		CodeChallenge: r.CodeChallenge,
+		Scope:         r.Scope,
+		Nonce:         r.Nonce,
+		AuthTime:      time.Now(),
+		ExpiresAt:     time.Now().Add(a.cfg.AuthCodeTTL),
+	})
+	if err != nil {
+		log.Error("не удалось сохранить код", sl.Err(err))
+
+		return "", operr.Error(op, err)
+	}
+
+	log.Info("выдан код авторизации")
+
+	return code, nil
+}
+
+// ExchangeCode обменивает код авторизации на пару токенов.
+//
+// Код одноразовый и расходуется только после проверки клиента,
+// redirect_uri и PKCE. Повторный обмен отзывает токены,
+// выданные при первом обмене (RFC 6749, раздел 4.1.2).
+// Сеанс записывается на клиента client, который обменивает код.
+func (a *Auth) ExchangeCode(
+	c context.Context,
+	code string,
+	appID int32,
+	clientSecret string,
+	redirectURI string,
+	codeVerifier string,
+	client models.ClientInfo,
+) (models.TokenPair, error) {
+	const op = "Auth.ExchangeCode"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", int(appID)),
+	)
+
+	app, err := a.oauthClient(c, log, appID, clientSecret)
+	if err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	hash := opaque.Hash(code)
+
+	authCode, err := a.oauthStorage.AuthCode(c, hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Warn("код авторизации не найден")
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidGrant)
+		}
+
+		log.Error("не удалось получить код авторизации", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	// Код проверяется до использования: иначе запрос без верного
+	// code_verifier сжигал бы чужой код и отзывал выданные по нему токены.
+	if authCode.AppID != app.ID ||
+		authCode.RedirectURI != redirectURI ||
+		!authCode.ExpiresAt.After(time.Now()) ||
+		!verifyPKCE(codeVerifier, authCode.CodeChallenge) {
+		log.Warn("код авторизации не прошел проверку")
+
+		return models.TokenPair{}, operr.Error(op, ErrInvalidGrant)
+	}
+
+	familyID, err := opaque.New()
+	if err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	authCode, err = a.oauthStorage.UseAuthCode(c, hash, familyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTokenNotFound):
+			log.Warn("код авторизации не найден")
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidGrant)
+		case errors.Is(err, storage.ErrTokenUsed):
+			log.Warn("повторный обмен кода авторизации, токены отзываются")
+
+			if authCode.FamilyID == "" {
+				return models.TokenPair{}, operr.Error(op, ErrInvalidGrant)
+			}
+
+			err = a.tokenStorage.RevokeTokenFamily(c, authCode.FamilyID)
+			if err != nil {
+				log.Error("не удалось отозвать семейство токенов", sl.Err(err))
+
+				return models.TokenPair{}, operr.Error(op, err)
+			}
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidGrant)
+		}
+
+		log.Error("не удалось использовать код авторизации", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	user, err := a.usrProvider.UserByID(c, authCode.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.TokenPair{}, operr.Error(op, ErrInvalidGrant)
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	if err = checkUserEnabled(log, user); err != nil {
+		return models.TokenPair{}, operr.Error(op, ErrInvalidGrant)
+	}
+
+	tokens, err := a.openSession(c, user, app, familyID, client)
+	if err != nil {
+		log.Error("не удалось выпустить токены", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	if authCode.HasScope(ScopeOpenID) {
+		tokens.IDToken, err = jwt.NewIDToken(
+			user,
+			app,
+			a.cfg.TokenTTL,
+			a.keys.Keys(),
+			a.cfg.Issuer,
+			authCode.AuthTime,
+			authCode.Nonce,
+		)
+		if err != nil {
+			log.Error("не удалось выпустить ID-токен", sl.Err(err))
+
+			return models.TokenPair{}, operr.Error(op, err)
+		}
+	}
+
+	log.Info("код авторизации обменян на токены")
+
+	return tokens, nil
+}
+
+// ExchangeRefreshToken обменивает refresh-токен на новую пару
+// токенов по запросу OAuth-клиента appID. Токен должен быть выдан
+// этому же клиенту, иначе возвращается ErrInvalidToken.
+func (a *Auth) ExchangeRefreshToken(
+	c context.Context,
+	refreshToken string,
+	appID int32,
+	clientSecret string,
+) (models.TokenPair, error) {
+	const op = "Auth.ExchangeRefreshToken"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", int(appID)),
+	)
+
+	app, err := a.oauthClient(c, log, appID, clientSecret)
+	if err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	return a.refresh(c, log, op, refreshToken, app.ID)
+}
+
+// oauthClient возвращает приложение OAuth-клиента appID.
+// Секрет необязателен для публичных клиентов, но если передан,
+// должен совпадать.
+func (a *Auth) oauthClient(
+	c context.Context,
+	log *slog.Logger,
+	appID int32,
+	clientSecret string,
+) (models.App, error) {
+	app, err := a.app(c, log, appID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidAppID) {
+			return models.App{}, ErrInvalidClient
+		}
+
+		return models.App{}, err
+	}
+
+	if clientSecret != "" &&
+		subtle.ConstantTimeCompare([]byte(clientSecret), []byte(app.Secret)) != 1 {
+		log.Warn("неверный секрет клиента")
+
+		return models.App{}, ErrInvalidClient
+	}
+
+	return app, nil
+}
+
+// verifyPKCE сверяет code_verifier с сохраненным S256 code_challenge.
+func verifyPKCE(verifier, challenge string) bool {
+	if !pkceValue.MatchString(verifier) {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+)
+
+// algHS256 алгоритм подписи секретом приложения,
+// используется, когда связка ключей пуста.
+const algHS256 = "HS256"
+
+// UserInfo возвращает пользователя, которому выдан access-токен,
+// и его профиль (эндпоинт userinfo OpenID Connect).
+func (a *Auth) UserInfo(
+	c context.Context,
+	accessToken string,
+) (models.User, models.Profile, error) {
+	const op = "Auth.UserInfo"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.verifyUserToken(c, accessToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			log.Info("недействительный access-токен", sl.Err(err))
+		} else {
+			log.Error("не удалось проверить access-токен", sl.Err(err))
+		}
+
+		return models.User{}, models.Profile{}, operr.Error(op, err)
+	}
+
+	user, err := a.usrProvider.UserByID(c, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("пользователь токена больше не существует")
+
+			return models.User{}, models.Profile{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return models.User{}, models.Profile{}, operr.Error(op, err)
+	}
+
+	profile, err := a.profileStorage.Profile(c, user.ID)
+	if err != nil {
+		log.Error("не удалось получить профиль", sl.Err(err))
+
+		return models.User{}, models.Profile{}, operr.Error(op, err)
+	}
+
+	return user, profile, nil
+}
+
+// SigningAlgs возвращает алгоритмы, которыми подписываются токены.
+func (a *Auth) SigningAlgs() []string {
+	algs := a.keys.Keys().Algs()
+	if len(algs) == 0 {
+		return []string{algHS256}
+	}
+
+	return algs
+}
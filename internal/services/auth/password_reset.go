@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/notifier"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+var ErrInvalidResetToken = errors.New("недействительный токен сброса пароля")
+
+// RequestPasswordReset отправляет на email ссылку для сброса пароля
+// пользователю организации приложения appID, при appID = 0 —
+// организации по умолчанию.
+//
+// Ответ не зависит от того, есть ли такой пользователь:
+// иначе по нему можно было бы перебирать зарегистрированные email.
+// По той же причине письмо отправляется в фоне, а ошибки доставки
+// только пишутся в лог.
+func (a *Auth) RequestPasswordReset(
+	c context.Context,
+	email string,
+	appID int32,
+) error {
+	const op = "Auth.RequestPasswordReset"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+	)
+
+	tenantID, err := a.tenantOf(c, log, appID)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	user, err := a.usrProvider.User(c, tenantID, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("сброс пароля для неизвестного email")
+
+			return nil
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	// Письмо отправляется в фоне: иначе по времени ответа было бы
+	// видно, что email зарегистрирован.
+	go a.sendPasswordReset(context.WithoutCancel(c), log, user)
+
+	return nil
+}
+
+// sendPasswordReset сохраняет токен сброса пароля и отправляет
+// пользователю ссылку с ним. Ошибки только пишутся в лог.
+func (a *Auth) sendPasswordReset(
+	c context.Context,
+	log *slog.Logger,
+	user models.User,
+) {
+	token, err := opaque.New()
+	if err != nil {
+		log.Error("не удалось сгенерировать токен сброса", sl.Err(err))
+
+		return
+	}
+
+	now := time.Now()
+
+	err = a.resetStorage.SavePasswordResetToken(c, models.PasswordResetToken{
+		Hash:      opaque.Hash(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.cfg.PasswordResetTTL),
+	})
+	if err != nil {
+		log.Error("не удалось сохранить токен сброса", sl.Err(err))
+
+		return
+	}
+
+	err = a.notifier.Send(c, notifier.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf(
+			"Чтобы задать новый пароль, перейдите по ссылке:\n\n%s\n\n"+
+				"Ссылка действует %s. Если вы не запрашивали сброс, "+
+				"просто проигнорируйте это письмо.\n",
+			linkWithToken(a.cfg.PasswordResetURL, token),
+			a.cfg.PasswordResetTTL,
+		),
+	})
+	if err != nil {
+		log.Error("не удалось отправить письмо", sl.Err(err))
+
+		return
+	}
+
+	log.Info("отправлена ссылка для сброса пароля")
+}
+
+// ConfirmPasswordReset задает новый пароль по токену из письма
+// и завершает все сеансы пользователя.
+func (a *Auth) ConfirmPasswordReset(
+	c context.Context,
+	token string,
+	newPassword string,
+) error {
+	const op = "Auth.ConfirmPasswordReset"
+
+	log := a.log.With(slog.String("op", op))
+
+	reset, err := a.resetStorage.PasswordResetToken(c, opaque.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Warn("токен сброса не найден")
+
+			return operr.Error(op, ErrInvalidResetToken)
+		}
+
+		log.Error("не удалось получить токен сброса", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", reset.UserID))
+
+	now := time.Now()
+
+	if !reset.Usable(now) {
+		log.Warn("токен сброса использован или истек")
+
+		return operr.Error(op, ErrInvalidResetToken)
+	}
+
+	user, err := a.usrProvider.UserByID(c, reset.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("пользователь не найден", sl.Err(err))
+
+			return operr.Error(op, ErrInvalidResetToken)
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	if err = a.checkPassword(log, newPassword, user.Email); err != nil {
+		return operr.Error(op, err)
+	}
+
+	passHash, err := a.hasher.Hash(newPassword)
+	if err != nil {
+		log.Error("не удалось сгенерировать хэш пароля", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	err = a.resetStorage.ResetPassword(c, reset.ID, reset.UserID, passHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenUsed) ||
+			errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("токен сброса уже использован", sl.Err(err))
+
+			return operr.Error(op, ErrInvalidResetToken)
+		}
+
+		log.Error("не удалось сменить пароль", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	// Сброс пароля обычно означает, что прежний мог утечь:
+	// сеансы, открытые с ним, больше не должны работать.
+	if err = a.tokenStorage.RevokeUserTokens(c, reset.UserID, now); err != nil {
+		log.Error("не удалось отозвать токены пользователя", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("пароль сброшен, сеансы завершены")
+
+	return nil
+}
+
+// linkWithToken возвращает ссылку base с токеном в параметре token.
+func linkWithToken(base string, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/profileschema"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"time"
+)
+
+// GetUser возвращает пользователя и его профиль: по userID, по email
+// в организации tenantID (0 — по умолчанию) или, если не указано
+// ни то ни другое, владельца accessToken. Чужих пользователей
+// может получать только администратор.
+func (a *Auth) GetUser(
+	c context.Context,
+	accessToken string,
+	userID int64,
+	email string,
+	tenantID int64,
+) (models.User, models.Profile, error) {
+	const op = "Auth.GetUser"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+		slog.String("email", email),
+	)
+
+	var (
+		user models.User
+		err  error
+	)
+	if email != "" {
+		if _, err = a.requireAdmin(c, log, accessToken); err != nil {
+			return models.User{}, models.Profile{}, operr.Error(op, err)
+		}
+
+		if tenantID == 0 {
+			tenantID = models.DefaultTenantID
+		}
+
+		user, err = a.usrProvider.User(c, tenantID, email)
+	} else {
+		userID, err = a.selfOrAdmin(c, log, accessToken, userID)
+		if err != nil {
+			return models.User{}, models.Profile{}, operr.Error(op, err)
+		}
+
+		user, err = a.usrProvider.UserByID(c, userID)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("пользователь не найден", sl.Err(err))
+
+			return models.User{}, models.Profile{}, operr.Error(op, ErrUserNotFound)
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return models.User{}, models.Profile{}, operr.Error(op, err)
+	}
+
+	profile, err := a.profileStorage.Profile(c, user.ID)
+	if err != nil {
+		log.Error("не удалось получить профиль", sl.Err(err))
+
+		return models.User{}, models.Profile{}, operr.Error(op, err)
+	}
+
+	return user, profile, nil
+}
+
+// UpdateProfile заменяет профиль пользователя profile.UserID целиком,
+// при пустом UserID — профиль владельца accessToken. Чужие профили
+// может менять только администратор. Профиль проверяется схемой
+// из конфига, при нарушениях возвращается *profileschema.Error.
+func (a *Auth) UpdateProfile(
+	c context.Context,
+	accessToken string,
+	profile models.Profile,
+) (models.Profile, error) {
+	const op = "Auth.UpdateProfile"
+
+	log := a.log.With(slog.String("op", op))
+
+	if _, err := a.verifyAccountToken(c, accessToken); err != nil {
+		logTokenError(log, err)
+
+		return models.Profile{}, operr.Error(op, err)
+	}
+
+	userID, err := a.selfOrAdmin(c, log, accessToken, profile.UserID)
+	if err != nil {
+		return models.Profile{}, operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", userID))
+
+	if _, err = a.usrProvider.UserByID(c, userID); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("пользователь не найден", sl.Err(err))
+
+			return models.Profile{}, operr.Error(op, ErrUserNotFound)
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return models.Profile{}, operr.Error(op, err)
+	}
+
+	if err = a.cfg.ProfileSchema.Check(profile); err != nil {
+		var schemaErr *profileschema.Error
+		if errors.As(err, &schemaErr) {
+			fields := make([]string, 0, len(schemaErr.Violations))
+			for _, v := range schemaErr.Violations {
+				fields = append(fields, v.Field)
+			}
+
+			log.Info("профиль не соответствует схеме", slog.Any("fields", fields))
+		}
+
+		return models.Profile{}, operr.Error(op, err)
+	}
+
+	profile.UserID = userID
+	profile.UpdatedAt = time.Now()
+	if profile.Attributes == nil {
+		profile.Attributes = map[string]string{}
+	}
+
+	if err = a.profileStorage.SaveProfile(c, profile); err != nil {
+		log.Error("не удалось сохранить профиль", sl.Err(err))
+
+		return models.Profile{}, operr.Error(op, err)
+	}
+
+	log.Info("профиль обновлен")
+
+	return profile, nil
+}
+
+// selfOrAdmin возвращает пользователя, к которому обращается
+// владелец accessToken: его самого при пустом или собственном userID,
+// иначе userID, если владелец токена администратор.
+func (a *Auth) selfOrAdmin(
+	c context.Context,
+	log *slog.Logger,
+	accessToken string,
+	userID int64,
+) (int64, error) {
+	claims, err := a.verifyUserToken(c, accessToken)
+	if err != nil {
+		logTokenError(log, err)
+
+		return 0, err
+	}
+
+	if userID == 0 || userID == claims.UID {
+		return claims.UID, nil
+	}
+
+	if _, err = a.requireAdmin(c, log, accessToken); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/jwt"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"time"
+)
+
+// Refresh обменивает refresh-токен на новую пару токенов.
+//
+// Каждый refresh-токен одноразовый: при обмене он помечается обменянным,
+// а взамен выдается новый из того же семейства.
+// Повторное предъявление обменянного токена означает, что он утек,
+// поэтому отзывается все семейство целиком. Если учетная запись
+// отключена, возвращает ErrUserDisabled.
+func (a *Auth) Refresh(
+	c context.Context,
+	refreshToken string,
+) (models.TokenPair, error) {
+	const op = "Auth.Refresh"
+
+	return a.refresh(c, a.log.With(slog.String("op", op)), op, refreshToken, 0)
+}
+
+// refresh обменивает refresh-токен. Если appID не 0, токен
+// должен быть выдан приложению appID.
+func (a *Auth) refresh(
+	c context.Context,
+	log *slog.Logger,
+	op string,
+	refreshToken string,
+	appID int,
+) (models.TokenPair, error) {
+	log.Info("обновление токенов")
+
+	current, err := a.tokenStorage.RefreshToken(c, opaque.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Warn("refresh-токен не найден", sl.Err(err))
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		log.Error("не удалось получить refresh-токен", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	log = log.With(
+		slog.Int64("user_id", current.UserID),
+		slog.String("family_id", current.FamilyID),
+	)
+
+	if current.Rotated() {
+		log.Warn("повторное использование refresh-токена, семейство отзывается")
+
+		return models.TokenPair{}, a.revokeFamily(c, op, current.FamilyID)
+	}
+
+	if appID != 0 && current.AppID != appID {
+		log.Warn("refresh-токен выдан другому приложению")
+
+		return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+	}
+
+	if current.Revoked() || current.Expired(time.Now()) {
+		log.Info("refresh-токен отозван или истек")
+
+		return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+	}
+
+	user, err := a.usrProvider.UserByID(c, current.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("пользователь не найден", sl.Err(err))
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	if err = checkUserEnabled(log, user); err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	app, err := a.appProvider.App(c, int32(current.AppID))
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("приложение не найдено", sl.Err(err))
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		log.Error("не удалось получить приложение", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	tokens, next, err := a.issueTokens(c, user, app, current.FamilyID)
+	if err != nil {
+		log.Error("не удалось выпустить токены", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	err = a.tokenStorage.RotateRefreshToken(c, current.ID, next)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenRotated) {
+			log.Warn("refresh-токен обменян параллельно, семейство отзывается")
+
+			return models.TokenPair{}, a.revokeFamily(c, op, current.FamilyID)
+		}
+
+		log.Error("не удалось обменять refresh-токен", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	err = a.sessionStorage.TouchSession(c, current.FamilyID, time.Now(), next.ExpiresAt)
+	if err != nil {
+		log.Error("не удалось отметить активность сеанса", sl.Err(err))
+	}
+
+	log.Info("токены обновлены")
+
+	return tokens, nil
+}
+
+// revokeFamily отзывает семейство refresh-токенов после обнаружения
+// повторного использования.
+//
+// Клиенту в любом случае сообщается ErrInvalidToken,
+// ошибка хранилища возвращается только если отзыв не удался.
+func (a *Auth) revokeFamily(c context.Context, op, familyID string) error {
+	if err := a.tokenStorage.RevokeTokenFamily(c, familyID); err != nil {
+		a.log.Error(
+			"не удалось отозвать семейство токенов",
+			slog.String("op", op),
+			slog.String("family_id", familyID),
+			sl.Err(err),
+		)
+
+		return operr.Error(op, err)
+	}
+
+	return operr.Error(op, ErrInvalidToken)
+}
+
+// startSession начинает новый сеанс с клиента client
+// и выпускает первую пару токенов.
+func (a *Auth) startSession(
+	c context.Context,
+	user models.User,
+	app models.App,
+	client models.ClientInfo,
+) (models.TokenPair, error) {
+	sessionID, err := opaque.New()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return a.openSession(c, user, app, sessionID, client)
+}
+
+// openSession сохраняет сеанс sessionID, выпускает первую пару токенов
+// и сохраняет refresh-токен. sessionID становится семейством
+// refresh-токенов сеанса.
+func (a *Auth) openSession(
+	c context.Context,
+	user models.User,
+	app models.App,
+	sessionID string,
+	client models.ClientInfo,
+) (models.TokenPair, error) {
+	tokens, refresh, err := a.issueTokens(c, user, app, sessionID)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	now := time.Now()
+
+	err = a.sessionStorage.SaveSession(c, models.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		AppID:      app.ID,
+		Client:     client,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  refresh.ExpiresAt,
+	})
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	if err = a.tokenStorage.SaveRefreshToken(c, refresh); err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return tokens, nil
+}
+
+// issueTokens выпускает access-токен и новый refresh-токен семейства familyID.
+// Семейство совпадает с сеансом и попадает в access-токен как sid,
+// туда же попадают роли и разрешения пользователя в приложении.
+//
+// Возвращает пару для клиента и запись refresh-токена,
+// которую вызывающий должен сохранить.
+func (a *Auth) issueTokens(
+	c context.Context,
+	user models.User,
+	app models.App,
+	familyID string,
+) (models.TokenPair, models.RefreshToken, error) {
+	userAccess, err := a.userAccess(c, a.log, user.ID, app.ID)
+	if err != nil {
+		return models.TokenPair{}, models.RefreshToken{}, err
+	}
+
+	generation, err := a.tokenStorage.TokenGeneration(c, user.ID)
+	if err != nil {
+		return models.TokenPair{}, models.RefreshToken{}, err
+	}
+
+	access, err := jwt.NewToken(
+		user,
+		app,
+		familyID,
+		generation,
+		userAccess,
+		a.cfg.TokenTTL,
+		a.keys.Keys(),
+		a.cfg.Issuer,
+	)
+	if err != nil {
+		return models.TokenPair{}, models.RefreshToken{}, err
+	}
+
+	refresh, err := opaque.New()
+	if err != nil {
+		return models.TokenPair{}, models.RefreshToken{}, err
+	}
+
+	record := models.RefreshToken{
+		Hash:      opaque.Hash(refresh),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		AppID:     app.ID,
+		ExpiresAt: time.Now().Add(a.cfg.RefreshTTL),
+	}
+
+	tokens := models.TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    a.cfg.TokenTTL,
+	}
+
+	return tokens, record, nil
+}
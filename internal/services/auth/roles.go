@@ -0,0 +1,341 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"slices"
+)
+
+var (
+	ErrRoleExists      = errors.New("роль уже существует")
+	ErrRoleNotFound    = errors.New("роль не найдена")
+	ErrRoleNotAssigned = errors.New("роль не назначена")
+)
+
+// CreateRole создает роль name с разрешениями permissions
+// в приложении appID, при appID = 0 — глобальную.
+// Доступно только администратору.
+func (a *Auth) CreateRole(
+	c context.Context,
+	accessToken string,
+	appID int32,
+	name string,
+	permissions []string,
+) (models.Role, error) {
+	const op = "Auth.CreateRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", int(appID)),
+		slog.String("role", name),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return models.Role{}, operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	if appID != 0 {
+		if _, err = a.app(c, log, appID); err != nil {
+			return models.Role{}, operr.Error(op, err)
+		}
+	}
+
+	role := models.Role{
+		AppID:       int(appID),
+		Name:        name,
+		Permissions: permissions,
+	}
+
+	role.ID, err = a.roleStorage.SaveRole(c, role)
+	if err != nil {
+		if errors.Is(err, storage.ErrRoleExists) {
+			log.Warn("роль уже существует", sl.Err(err))
+
+			return models.Role{}, operr.Error(op, ErrRoleExists)
+		}
+
+		log.Error("не удалось сохранить роль", sl.Err(err))
+
+		return models.Role{}, operr.Error(op, err)
+	}
+
+	log.Info("роль создана", slog.Int64("role_id", role.ID))
+
+	return role, nil
+}
+
+// ListRoles возвращает роли приложения appID, при appID = 0 — глобальные.
+// Доступно только администратору.
+func (a *Auth) ListRoles(
+	c context.Context,
+	accessToken string,
+	appID int32,
+) ([]models.Role, error) {
+	const op = "Auth.ListRoles"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", int(appID)),
+	)
+
+	if _, err := a.requireAdmin(c, log, accessToken); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	roles, err := a.roleStorage.Roles(c, int(appID))
+	if err != nil {
+		log.Error("не удалось получить роли", sl.Err(err))
+
+		return nil, operr.Error(op, err)
+	}
+
+	return roles, nil
+}
+
+// DeleteRole удаляет роль и снимает ее со всех пользователей.
+// Доступно только администратору.
+func (a *Auth) DeleteRole(
+	c context.Context,
+	accessToken string,
+	roleID int64,
+) error {
+	const op = "Auth.DeleteRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("role_id", roleID),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	if err = a.roleStorage.DeleteRole(c, roleID); err != nil {
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			log.Warn("роль не найдена", sl.Err(err))
+
+			return operr.Error(op, ErrRoleNotFound)
+		}
+
+		log.Error("не удалось удалить роль", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("роль удалена")
+
+	return nil
+}
+
+// AssignRole назначает роль roleID пользователю userID.
+// Роль приложения назначается только пользователю организации,
+// которой принадлежит приложение. Доступно только администратору.
+func (a *Auth) AssignRole(
+	c context.Context,
+	accessToken string,
+	userID int64,
+	roleID int64,
+) error {
+	const op = "Auth.AssignRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+		slog.Int64("role_id", roleID),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	user, err := a.usrProvider.UserByID(c, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("пользователь не найден", sl.Err(err))
+
+			return operr.Error(op, ErrUserNotFound)
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	role, err := a.roleStorage.Role(c, roleID)
+	if err != nil {
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			log.Warn("роль не найдена", sl.Err(err))
+
+			return operr.Error(op, ErrRoleNotFound)
+		}
+
+		log.Error("не удалось получить роль", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	if !role.Global() {
+		app, err := a.app(c, log, int32(role.AppID))
+		if err != nil {
+			return operr.Error(op, err)
+		}
+
+		if app.TenantID != user.TenantID {
+			log.Warn("роль приложения другой организации")
+
+			return operr.Error(op, ErrTenantMismatch)
+		}
+	}
+
+	if err = a.roleStorage.AssignRole(c, userID, roleID); err != nil {
+		log.Error("не удалось назначить роль", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("роль назначена")
+
+	return nil
+}
+
+// UnassignRole снимает роль roleID с пользователя userID.
+// Доступно только администратору.
+func (a *Auth) UnassignRole(
+	c context.Context,
+	accessToken string,
+	userID int64,
+	roleID int64,
+) error {
+	const op = "Auth.UnassignRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+		slog.Int64("role_id", roleID),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	if err = a.roleStorage.UnassignRole(c, userID, roleID); err != nil {
+		if errors.Is(err, storage.ErrRoleNotAssigned) {
+			log.Warn("роль не назначена", sl.Err(err))
+
+			return operr.Error(op, ErrRoleNotAssigned)
+		}
+
+		log.Error("не удалось снять роль", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("роль снята")
+
+	return nil
+}
+
+// CheckPermission сообщает, дают ли роли пользователя userID
+// в приложении appID разрешение permission.
+func (a *Auth) CheckPermission(
+	c context.Context,
+	userID int64,
+	appID int32,
+	permission string,
+) (bool, error) {
+	const op = "Auth.CheckPermission"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+		slog.Int("app_id", int(appID)),
+		slog.String("permission", permission),
+	)
+
+	if _, err := a.usrProvider.UserByID(c, userID); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("пользователь не найден", sl.Err(err))
+
+			return false, operr.Error(op, ErrUserNotFound)
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return false, operr.Error(op, err)
+	}
+
+	access, err := a.userAccess(c, log, userID, int(appID))
+	if err != nil {
+		return false, operr.Error(op, err)
+	}
+
+	allowed := access.Has(permission)
+
+	log.Info("разрешение проверено", slog.Bool("allowed", allowed))
+
+	return allowed, nil
+}
+
+// userAccess возвращает роли и разрешения пользователя в приложении,
+// в том числе полученные через группы, и сами группы.
+//
+// Глобальные роли с правами администратора действуют только при
+// включенном втором факторе, если этого требует конфиг.
+func (a *Auth) userAccess(
+	c context.Context,
+	log *slog.Logger,
+	userID int64,
+	appID int,
+) (models.Access, error) {
+	roles, err := a.roleStorage.UserRoles(c, userID, appID)
+	if err != nil {
+		log.Error("не удалось получить роли пользователя", sl.Err(err))
+
+		return models.Access{}, err
+	}
+
+	grantsAdmin := func(r models.Role) bool {
+		return r.Global() && slices.Contains(r.Permissions, models.PermissionAdmin)
+	}
+
+	if slices.ContainsFunc(roles, grantsAdmin) {
+		isAdmin, err := a.adminWithSecondFactor(c, log, userID, true)
+		if err != nil {
+			return models.Access{}, err
+		}
+		if !isAdmin {
+			roles = slices.DeleteFunc(roles, grantsAdmin)
+		}
+	}
+
+	groups, err := a.groupStorage.UserGroups(c, userID)
+	if err != nil {
+		log.Error("не удалось получить группы пользователя", sl.Err(err))
+
+		return models.Access{}, err
+	}
+
+	access := models.NewAccess(roles)
+	for _, group := range groups {
+		access.Groups = append(access.Groups, group.Name)
+	}
+
+	return access, nil
+}
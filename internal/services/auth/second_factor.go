@@ -0,0 +1,430 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/opaque"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"github.com/h1lton/sso-grpc-ntc/pkg/totp"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// recoveryCodeCount сколько кодов восстановления выдается за раз.
+	recoveryCodeCount = 10
+	// challengeMaxAttempts сколько кодов можно попробовать на один вызов.
+	challengeMaxAttempts = 5
+	// totpSkew допуск расхождения часов в шагах TOTP.
+	totpSkew = 1
+)
+
+var (
+	ErrSecondFactorRequired = errors.New("требуется код второго фактора")
+	ErrInvalidOTP           = errors.New("неверный код второго фактора")
+	ErrTOTPEnabled          = errors.New("второй фактор уже включен")
+	ErrTOTPNotEnrolled      = errors.New("второй фактор не настроен")
+	ErrTOTPNotAllowed       = errors.New(
+		"второй фактор нельзя настроить по токену API-ключа",
+	)
+)
+
+// EnrollTOTP создает секрет TOTP пользователю, которому выдан
+// accessToken. Второй фактор включается только после ConfirmTOTP.
+//
+// Возвращает секрет и ссылку otpauth:// для QR-кода.
+func (a *Auth) EnrollTOTP(
+	c context.Context,
+	accessToken string,
+) (secret string, uri string, err error) {
+	const op = "Auth.EnrollTOTP"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.verifyUserToken(c, accessToken)
+	if err != nil {
+		logTokenError(log, err)
+
+		return "", "", operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", claims.UID))
+
+	// Утекший API-ключ не должен позволять подменить второй фактор.
+	if claims.APIKey != "" {
+		log.Warn("попытка подключить второй фактор по токену API-ключа")
+
+		return "", "", operr.Error(op, ErrTOTPNotAllowed)
+	}
+
+	secret, err = totp.NewSecret()
+	if err != nil {
+		return "", "", operr.Error(op, err)
+	}
+
+	if err = a.mfaStorage.SaveTOTP(c, claims.UID, secret); err != nil {
+		if errors.Is(err, storage.ErrTOTPEnabled) {
+			log.Warn("второй фактор уже включен")
+
+			return "", "", operr.Error(op, ErrTOTPEnabled)
+		}
+
+		log.Error("не удалось сохранить секрет", sl.Err(err))
+
+		return "", "", operr.Error(op, err)
+	}
+
+	log.Info("начато подключение второго фактора")
+
+	return secret, totp.URI(a.cfg.TOTPIssuer, claims.Email, secret), nil
+}
+
+// ConfirmTOTP включает второй фактор, если code подходит
+// к секрету из EnrollTOTP, и выдает коды восстановления.
+//
+// Коды возвращаются один раз: хранятся только их хэши.
+func (a *Auth) ConfirmTOTP(
+	c context.Context,
+	accessToken string,
+	code string,
+) ([]string, error) {
+	const op = "Auth.ConfirmTOTP"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.verifyUserToken(c, accessToken)
+	if err != nil {
+		logTokenError(log, err)
+
+		return nil, operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", claims.UID))
+
+	if claims.APIKey != "" {
+		log.Warn("попытка подключить второй фактор по токену API-ключа")
+
+		return nil, operr.Error(op, ErrTOTPNotAllowed)
+	}
+
+	t, err := a.mfaStorage.TOTP(c, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return nil, operr.Error(op, ErrTOTPNotEnrolled)
+		}
+
+		log.Error("не удалось получить секрет", sl.Err(err))
+
+		return nil, operr.Error(op, err)
+	}
+	if t.Enabled() {
+		return nil, operr.Error(op, ErrTOTPEnabled)
+	}
+
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		log.Info("неверный код при подключении второго фактора")
+
+		return nil, operr.Error(op, ErrInvalidOTP)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	err = a.mfaStorage.ConfirmTOTP(c, claims.UID, step, hashes)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPEnabled) {
+			return nil, operr.Error(op, ErrTOTPEnabled)
+		}
+
+		log.Error("не удалось включить второй фактор", sl.Err(err))
+
+		return nil, operr.Error(op, err)
+	}
+
+	log.Info("второй фактор включен")
+
+	return codes, nil
+}
+
+// VerifySecondFactor завершает вход по вызову из Login:
+// принимает код TOTP или код восстановления и выдает токены.
+//
+// На один вызов дается challengeMaxAttempts попыток, а неверные коды
+// засчитываются в ограничение подбора так же, как неверные пароли,
+// поэтому новый вызов из Login не дает новых попыток.
+// Для неизвестного, истекшего или исчерпанного вызова
+// возвращает ErrInvalidToken, для неверного кода — ErrInvalidOTP,
+// при ограниченном входе — *RetryError.
+func (a *Auth) VerifySecondFactor(
+	c context.Context,
+	challenge string,
+	code string,
+	client models.ClientInfo,
+) (models.TokenPair, error) {
+	const op = "Auth.VerifySecondFactor"
+
+	log := a.log.With(slog.String("op", op))
+
+	ch, err := a.mfaStorage.LoginChallenge(c, opaque.Hash(challenge))
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			log.Warn("вызов не найден")
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		log.Error("не удалось получить вызов", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", ch.UserID))
+
+	err = a.mfaStorage.AttemptLoginChallenge(c, ch.ID, challengeMaxAttempts)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenUsed) {
+			log.Warn("вызов использован, истек или исчерпал попытки")
+
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		log.Error("не удалось засчитать попытку", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	err = a.checkSecondFactorThrottled(c, log, ch.UserID, code, client.IP)
+	if err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	a.resetLoginFailures(c, log, ch.UserID)
+
+	if err = a.mfaStorage.UseLoginChallenge(c, ch.ID); err != nil {
+		if errors.Is(err, storage.ErrTokenUsed) {
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	user, err := a.usrProvider.UserByID(c, ch.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.TokenPair{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		log.Error("не удалось получить пользователя", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	if err = checkUserEnabled(log, user); err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	app, err := a.app(c, log, int32(ch.AppID))
+	if err != nil {
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	tokens, err := a.startSession(c, user, app, client)
+	if err != nil {
+		log.Error("не удалось выпустить токены", sl.Err(err))
+
+		return models.TokenPair{}, operr.Error(op, err)
+	}
+
+	log.Info("пользователь вошел со вторым фактором")
+
+	return tokens, nil
+}
+
+// secondFactorEnabled сообщает, включен ли у пользователя второй фактор.
+func (a *Auth) secondFactorEnabled(c context.Context, userID int64) (bool, error) {
+	t, err := a.mfaStorage.TOTP(c, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return t.Enabled(), nil
+}
+
+// checkSecondFactor проверяет код TOTP или код восстановления
+// пользователя и помечает его использованным.
+//
+// Неверный или повторно использованный код дает ErrInvalidOTP.
+func (a *Auth) checkSecondFactor(
+	c context.Context,
+	log *slog.Logger,
+	userID int64,
+	code string,
+) error {
+	code = strings.TrimSpace(code)
+
+	if len(code) != totp.Digits {
+		err := a.mfaStorage.UseRecoveryCode(c, userID, recoveryCodeHash(code))
+		if err != nil {
+			if errors.Is(err, storage.ErrTokenNotFound) {
+				log.Warn("неверный код восстановления")
+
+				return ErrInvalidOTP
+			}
+
+			log.Error("не удалось использовать код восстановления", sl.Err(err))
+
+			return err
+		}
+
+		log.Warn("вход по коду восстановления")
+
+		return nil
+	}
+
+	t, err := a.mfaStorage.TOTP(c, userID)
+	if err != nil {
+		log.Error("не удалось получить секрет", sl.Err(err))
+
+		return err
+	}
+
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		log.Warn("неверный код TOTP")
+
+		return ErrInvalidOTP
+	}
+
+	if err = a.mfaStorage.UseTOTPStep(c, userID, step); err != nil {
+		if errors.Is(err, storage.ErrTokenUsed) {
+			log.Warn("повторное использование кода TOTP")
+
+			return ErrInvalidOTP
+		}
+
+		log.Error("не удалось запомнить шаг TOTP", sl.Err(err))
+
+		return err
+	}
+
+	return nil
+}
+
+// checkSecondFactorThrottled проверяет код второго фактора, как
+// checkSecondFactor, с учетом ограничения подбора: пока вход
+// пользователя или адреса clientIP ограничен, код не проверяется
+// и возвращается *RetryError, а неверный код засчитывается
+// как неудачный вход.
+func (a *Auth) checkSecondFactorThrottled(
+	c context.Context,
+	log *slog.Logger,
+	userID int64,
+	code string,
+	clientIP string,
+) error {
+	keys := []throttleKey{a.userThrottleKey(userID)}
+	if clientIP != "" {
+		keys = append(keys, a.ipThrottleKey(clientIP))
+	}
+
+	for _, key := range keys {
+		if err := a.checkThrottle(c, log, key); err != nil {
+			return err
+		}
+	}
+
+	err := a.checkSecondFactor(c, log, userID, code)
+	if errors.Is(err, ErrInvalidOTP) {
+		a.recordLoginFailure(c, log, keys...)
+	}
+
+	return err
+}
+
+// newLoginChallenge сохраняет вызов второго фактора
+// для входа user в app и возвращает его.
+func (a *Auth) newLoginChallenge(
+	c context.Context,
+	user models.User,
+	app models.App,
+) (string, error) {
+	challenge, err := opaque.New()
+	if err != nil {
+		return "", err
+	}
+
+	err = a.mfaStorage.SaveLoginChallenge(c, models.LoginChallenge{
+		Hash:      opaque.Hash(challenge),
+		UserID:    user.ID,
+		AppID:     app.ID,
+		ExpiresAt: time.Now().Add(a.cfg.ChallengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// adminWithSecondFactor применяет RequireAdminMFA: администратор
+// без включенного второго фактора не получает прав администратора.
+func (a *Auth) adminWithSecondFactor(
+	c context.Context,
+	log *slog.Logger,
+	userID int64,
+	isAdmin bool,
+) (bool, error) {
+	if !isAdmin || !a.cfg.RequireAdminMFA {
+		return isAdmin, nil
+	}
+
+	enabled, err := a.secondFactorEnabled(c, userID)
+	if err != nil {
+		log.Error("не удалось проверить второй фактор", sl.Err(err))
+
+		return false, err
+	}
+	if !enabled {
+		log.Warn("у администратора не включен второй фактор, права не выданы")
+	}
+
+	return enabled, nil
+}
+
+// newRecoveryCodes генерирует коды восстановления вида xxxxx-xxxxx
+// и их хэши для хранения.
+func newRecoveryCodes() (codes []string, hashes [][]byte, err error) {
+	for range recoveryCodeCount {
+		b := make([]byte, 5)
+		if _, err = rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := hex.EncodeToString(b)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, recoveryCodeHash(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// recoveryCodeHash хэширует код восстановления,
+// не обращая внимания на регистр и дефисы.
+func recoveryCodeHash(code string) []byte {
+	code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+
+	return opaque.Hash(code)
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("сеанс не найден")
+
+// ListSessions возвращает действующие сеансы пользователя,
+// которому выдан accessToken. Сеанс самого токена помечается Current.
+func (a *Auth) ListSessions(
+	c context.Context,
+	accessToken string,
+) ([]models.Session, error) {
+	const op = "Auth.ListSessions"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.verifyAccountToken(c, accessToken)
+	if err != nil {
+		logTokenError(log, err)
+
+		return nil, operr.Error(op, err)
+	}
+
+	sessions, err := a.sessionStorage.Sessions(c, claims.UID, time.Now())
+	if err != nil {
+		log.Error("не удалось получить сеансы", sl.Err(err))
+
+		return nil, operr.Error(op, err)
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession завершает сеанс sessionID пользователя, которому выдан
+// accessToken: его refresh-токены отзываются, а access-токены
+// перестают приниматься сразу.
+func (a *Auth) RevokeSession(
+	c context.Context,
+	accessToken string,
+	sessionID string,
+) error {
+	const op = "Auth.RevokeSession"
+
+	log := a.log.With(slog.String("op", op))
+
+	claims, err := a.verifyAccountToken(c, accessToken)
+	if err != nil {
+		logTokenError(log, err)
+
+		return operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("user_id", claims.UID))
+
+	err = a.sessionStorage.RevokeSession(c, claims.UID, sessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			log.Warn("сеанс не найден", sl.Err(err))
+
+			return operr.Error(op, ErrSessionNotFound)
+		}
+
+		log.Error("не удалось завершить сеанс", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	log.Info("сеанс завершен")
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrTenantExists   = errors.New("организация уже существует")
+	ErrTenantNotFound = errors.New("организация не найдена")
+)
+
+// CreateTenant создает организацию name.
+// Доступно только администратору.
+func (a *Auth) CreateTenant(
+	c context.Context,
+	accessToken string,
+	name string,
+) (models.Tenant, error) {
+	const op = "Auth.CreateTenant"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("tenant", name),
+	)
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return models.Tenant{}, operr.Error(op, err)
+	}
+
+	log = log.With(slog.Int64("admin_id", adminID))
+
+	tenant := models.Tenant{
+		Name:      name,
+		CreatedAt: time.Now().Truncate(time.Second),
+	}
+
+	tenant.ID, err = a.tenantStorage.SaveTenant(c, tenant.Name, tenant.CreatedAt)
+	if err != nil {
+		if errors.Is(err, storage.ErrTenantExists) {
+			log.Warn("организация уже существует", sl.Err(err))
+
+			return models.Tenant{}, operr.Error(op, ErrTenantExists)
+		}
+
+		log.Error("не удалось сохранить организацию", sl.Err(err))
+
+		return models.Tenant{}, operr.Error(op, err)
+	}
+
+	log.Info("организация создана", slog.Int64("tenant_id", tenant.ID))
+
+	return tenant, nil
+}
+
+// ListTenants возвращает все организации.
+// Доступно только администратору.
+func (a *Auth) ListTenants(
+	c context.Context,
+	accessToken string,
+) ([]models.Tenant, error) {
+	const op = "Auth.ListTenants"
+
+	log := a.log.With(slog.String("op", op))
+
+	if _, err := a.requireAdmin(c, log, accessToken); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	tenants, err := a.tenantStorage.Tenants(c)
+	if err != nil {
+		log.Error("не удалось получить организации", sl.Err(err))
+
+		return nil, operr.Error(op, err)
+	}
+
+	return tenants, nil
+}
+
+// TenantResources возвращает организацию tenantID, ее приложения
+// и число ее пользователей. Доступно только администратору.
+func (a *Auth) TenantResources(
+	c context.Context,
+	accessToken string,
+	tenantID int64,
+) (models.TenantResources, error) {
+	const op = "Auth.TenantResources"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("tenant_id", tenantID),
+	)
+
+	if _, err := a.requireAdmin(c, log, accessToken); err != nil {
+		return models.TenantResources{}, operr.Error(op, err)
+	}
+
+	tenant, err := a.tenantStorage.Tenant(c, tenantID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTenantNotFound) {
+			log.Warn("организация не найдена", sl.Err(err))
+
+			return models.TenantResources{}, operr.Error(op, ErrTenantNotFound)
+		}
+
+		log.Error("не удалось получить организацию", sl.Err(err))
+
+		return models.TenantResources{}, operr.Error(op, err)
+	}
+
+	apps, err := a.tenantStorage.TenantApps(c, tenantID)
+	if err != nil {
+		log.Error("не удалось получить приложения организации", sl.Err(err))
+
+		return models.TenantResources{}, operr.Error(op, err)
+	}
+
+	users, err := a.tenantStorage.TenantUserCount(c, tenantID)
+	if err != nil {
+		log.Error("не удалось посчитать пользователей организации", sl.Err(err))
+
+		return models.TenantResources{}, operr.Error(op, err)
+	}
+
+	return models.TenantResources{
+		Tenant:    tenant,
+		Apps:      apps,
+		UserCount: users,
+	}, nil
+}
+
+// tenantOf возвращает организацию приложения appID,
+// при appID = 0 — организацию по умолчанию.
+func (a *Auth) tenantOf(
+	c context.Context,
+	log *slog.Logger,
+	appID int32,
+) (int64, error) {
+	if appID == 0 {
+		return models.DefaultTenantID, nil
+	}
+
+	app, err := a.app(c, log, appID)
+	if err != nil {
+		return 0, err
+	}
+
+	return app.TenantID, nil
+}
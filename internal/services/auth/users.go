@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"time"
+)
+
+// Размер страницы списка пользователей.
+const (
+	defaultUsersPageSize = 50
+	maxUsersPageSize     = 500
+)
+
+var (
+	ErrInvalidPageToken = errors.New("недействительный токен страницы")
+	ErrUserDisabled     = errors.New("учетная запись отключена")
+)
+
+// usersCursor содержимое токена страницы: порядок списка и ключ
+// последнего пользователя предыдущей страницы. Порядок сверяется,
+// чтобы токен не применили к списку с другой сортировкой.
+type usersCursor struct {
+	Sort      string `json:"s"`
+	Desc      bool   `json:"d,omitempty"`
+	ID        int64  `json:"id"`
+	Email     string `json:"e,omitempty"`
+	CreatedAt int64  `json:"c,omitempty"`
+}
+
+// ListUsers возвращает страницу списка пользователей с фильтрами
+// filter в порядке sort и токен следующей страницы, пустой
+// на последней. pageToken — токен из предыдущего ответа,
+// пустой — первая страница. Доступно только администратору.
+func (a *Auth) ListUsers(
+	c context.Context,
+	accessToken string,
+	filter models.UserFilter,
+	sort string,
+	desc bool,
+	pageSize int,
+	pageToken string,
+) ([]models.User, string, error) {
+	const op = "Auth.ListUsers"
+
+	log := a.log.With(slog.String("op", op))
+
+	if _, err := a.requireAdmin(c, log, accessToken); err != nil {
+		return nil, "", operr.Error(op, err)
+	}
+
+	if sort == "" {
+		sort = models.UserSortID
+	}
+
+	q := models.UserQuery{
+		Filter: filter,
+		Sort:   sort,
+		Desc:   desc,
+		Limit:  pageSize,
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultUsersPageSize
+	}
+	q.Limit = min(q.Limit, maxUsersPageSize)
+
+	if pageToken != "" {
+		after, err := decodeUsersCursor(pageToken, sort, desc)
+		if err != nil {
+			log.Warn("недействительный токен страницы", sl.Err(err))
+
+			return nil, "", operr.Error(op, ErrInvalidPageToken)
+		}
+
+		q.After = &after
+	}
+
+	// Лишний пользователь показывает, есть ли следующая страница.
+	q.Limit++
+
+	users, err := a.usrProvider.Users(c, q)
+	if err != nil {
+		log.Error("не удалось получить пользователей", sl.Err(err))
+
+		return nil, "", operr.Error(op, err)
+	}
+
+	if len(users) < q.Limit {
+		return users, "", nil
+	}
+
+	users = users[:q.Limit-1]
+
+	next, err := encodeUsersCursor(users[len(users)-1], sort, desc)
+	if err != nil {
+		log.Error("не удалось сформировать токен страницы", sl.Err(err))
+
+		return nil, "", operr.Error(op, err)
+	}
+
+	return users, next, nil
+}
+
+// SetUserDisabled отключает или снова включает учетную запись
+// пользователя userID. Отключенный пользователь не может войти
+// и обновить токены, а его сеансы и выпущенные токены отзываются.
+// Доступно только администратору.
+func (a *Auth) SetUserDisabled(
+	c context.Context,
+	accessToken string,
+	userID int64,
+	disabled bool,
+) error {
+	const op = "Auth.SetUserDisabled"
+
+	log := a.log.With(slog.String("op", op))
+
+	adminID, err := a.requireAdmin(c, log, accessToken)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	log = log.With(
+		slog.Int64("admin_id", adminID),
+		slog.Int64("user_id", userID),
+		slog.Bool("disabled", disabled),
+	)
+
+	if err = a.usrUpdater.SetUserDisabled(c, userID, disabled); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("пользователь не найден", sl.Err(err))
+
+			return operr.Error(op, ErrUserNotFound)
+		}
+
+		log.Error("не удалось изменить состояние учетной записи", sl.Err(err))
+
+		return operr.Error(op, err)
+	}
+
+	if disabled {
+		err = a.tokenStorage.RevokeUserTokens(c, userID, time.Now())
+		if err != nil {
+			log.Error("не удалось отозвать токены пользователя", sl.Err(err))
+
+			return operr.Error(op, err)
+		}
+	}
+
+	log.Info("состояние учетной записи изменено")
+
+	return nil
+}
+
+// checkUserEnabled не пускает пользователя с отключенной учетной записью.
+func checkUserEnabled(log *slog.Logger, user models.User) error {
+	if user.Disabled {
+		log.Warn("учетная запись отключена", slog.Int64("user_id", user.ID))
+
+		return ErrUserDisabled
+	}
+
+	return nil
+}
+
+func encodeUsersCursor(last models.User, sort string, desc bool) (string, error) {
+	cursor := usersCursor{
+		Sort:  sort,
+		Desc:  desc,
+		ID:    last.ID,
+		Email: last.Email,
+	}
+	if !last.CreatedAt.IsZero() {
+		cursor.CreatedAt = last.CreatedAt.Unix()
+	}
+
+	b, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeUsersCursor возвращает пользователя, за которым начинается
+// страница, с заполненными полями ключа сортировки.
+func decodeUsersCursor(token string, sort string, desc bool) (models.User, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	var cursor usersCursor
+	if err = json.Unmarshal(b, &cursor); err != nil {
+		return models.User{}, err
+	}
+
+	if cursor.Sort != sort || cursor.Desc != desc {
+		return models.User{}, errors.New("токен другого порядка списка")
+	}
+
+	user := models.User{ID: cursor.ID, Email: cursor.Email}
+	if cursor.CreatedAt != 0 {
+		user.CreatedAt = time.Unix(cursor.CreatedAt, 0)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/jwt"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/logger/sl"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"log/slog"
+	"time"
+)
+
+var ErrAPIKeyTokenNotAllowed = errors.New("действие недоступно по токену API-ключа")
+
+// verifyAccessToken проверяет access-токен: подпись, срок действия
+// и отсутствие в списке отозванных.
+//
+// Для любого недействительного токена возвращает ErrInvalidToken.
+func (a *Auth) verifyAccessToken(
+	c context.Context,
+	token string,
+) (jwt.Claims, error) {
+	const op = "Auth.verifyAccessToken"
+
+	claims, err := jwt.Parse(token, a.keys.Keys(), func(appID int) (string, error) {
+		app, err := a.appProvider.App(c, int32(appID))
+		if err != nil {
+			return "", err
+		}
+
+		return app.Secret, nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrInvalidToken) ||
+			errors.Is(err, storage.ErrAppNotFound) {
+			return jwt.Claims{}, operr.Error(op, ErrInvalidToken)
+		}
+
+		return jwt.Claims{}, operr.Error(op, err)
+	}
+
+	revoked, err := a.tokenStorage.AccessTokenRevoked(
+		c,
+		claims.ID,
+		claims.SessionID,
+		claims.UID,
+		claims.Generation,
+	)
+	if err != nil {
+		return jwt.Claims{}, operr.Error(op, err)
+	}
+	if revoked {
+		return jwt.Claims{}, operr.Error(op, ErrInvalidToken)
+	}
+
+	if claims.APIKey != "" {
+		key, err := a.apiKeyStorage.APIKey(c, claims.APIKey)
+		if err != nil {
+			if errors.Is(err, storage.ErrAPIKeyNotFound) {
+				return jwt.Claims{}, operr.Error(op, ErrInvalidToken)
+			}
+
+			return jwt.Claims{}, operr.Error(op, err)
+		}
+
+		// Токены отозванного ключа перестают приниматься сразу,
+		// не дожидаясь истечения срока.
+		if !key.Active(time.Now()) || key.UserID != claims.UID {
+			return jwt.Claims{}, operr.Error(op, ErrInvalidToken)
+		}
+	}
+
+	return claims, nil
+}
+
+// verifyUserToken как verifyAccessToken, но токен сервисного аккаунта
+// тоже считается недействительным: нужен токен пользователя.
+func (a *Auth) verifyUserToken(
+	c context.Context,
+	token string,
+) (jwt.Claims, error) {
+	const op = "Auth.verifyUserToken"
+
+	claims, err := a.verifyAccessToken(c, token)
+	if err != nil {
+		return jwt.Claims{}, operr.Error(op, err)
+	}
+
+	if claims.Service() {
+		return jwt.Claims{}, operr.Error(op, ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// verifyAccountToken как verifyUserToken, но отклоняет и токены
+// API-ключей: иначе утекший ключ позволял бы управлять сеансами,
+// ключами и профилем владельца.
+func (a *Auth) verifyAccountToken(
+	c context.Context,
+	token string,
+) (jwt.Claims, error) {
+	const op = "Auth.verifyAccountToken"
+
+	claims, err := a.verifyUserToken(c, token)
+	if err != nil {
+		return jwt.Claims{}, operr.Error(op, err)
+	}
+
+	if claims.APIKey != "" {
+		return jwt.Claims{}, operr.Error(op, ErrAPIKeyTokenNotAllowed)
+	}
+
+	return claims, nil
+}
+
+// logTokenError пишет в лог ошибку проверки access-токена
+// с уровнем, зависящим от ее причины.
+func logTokenError(log *slog.Logger, err error) {
+	if errors.Is(err, ErrInvalidToken) {
+		log.Warn("недействительный access-токен", sl.Err(err))
+
+		return
+	}
+	if errors.Is(err, ErrAPIKeyTokenNotAllowed) {
+		log.Warn("действие по токену API-ключа", sl.Err(err))
+
+		return
+	}
+
+	log.Error("не удалось проверить access-токен", sl.Err(err))
+}
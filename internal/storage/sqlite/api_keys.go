@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"github.com/mattn/go-sqlite3"
+	"time"
+)
+
+const apiKeyColumns = `id, user_id, app_id, name, prefix, secret_hash, scopes,
+	created_at, expires_at, last_used_at, revoked_at`
+
+func (s *Storage) SaveAPIKey(c context.Context, key models.APIKey) (int64, error) {
+	const op = "storage.sqlite.SaveAPIKey"
+
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	res, err := s.db.ExecContext(
+		c,
+		`INSERT INTO api_keys(
+		     user_id, app_id, name, prefix, secret_hash, scopes,
+		     created_at, expires_at
+		 ) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.UserID, key.AppID, key.Name, key.Prefix, key.SecretHash,
+		string(scopes), key.CreatedAt.Unix(), unixOrNull(key.ExpiresAt),
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) &&
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+
+			return 0, operr.Error(op, storage.ErrAPIKeyExists)
+		}
+
+		return 0, operr.Error(op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) APIKey(c context.Context, prefix string) (models.APIKey, error) {
+	const op = "storage.sqlite.APIKey"
+
+	row := s.db.QueryRowContext(
+		c,
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE prefix = ?",
+		prefix,
+	)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, operr.Error(op, storage.ErrAPIKeyNotFound)
+		}
+
+		return models.APIKey{}, operr.Error(op, err)
+	}
+
+	return key, nil
+}
+
+// APIKeys возвращает неотозванные ключи пользователя, новые первыми.
+func (s *Storage) APIKeys(c context.Context, userID int64) ([]models.APIKey, error) {
+	const op = "storage.sqlite.APIKeys"
+
+	rows, err := s.db.QueryContext(
+		c,
+		"SELECT "+apiKeyColumns+` FROM api_keys
+		 WHERE user_id = ? AND revoked_at IS NULL
+		 ORDER BY id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, operr.Error(op, err)
+		}
+
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ keyID пользователя userID.
+//
+// Чужой, уже отозванный или несуществующий ключ
+// дает storage.ErrAPIKeyNotFound.
+func (s *Storage) RevokeAPIKey(c context.Context, userID, keyID int64) error {
+	const op = "storage.sqlite.RevokeAPIKey"
+
+	res, err := s.db.ExecContext(
+		c,
+		`UPDATE api_keys SET revoked_at = ?
+		 WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		time.Now().Unix(), keyID, userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+// TouchAPIKey запоминает время последнего использования ключа.
+func (s *Storage) TouchAPIKey(c context.Context, keyID int64, at time.Time) error {
+	const op = "storage.sqlite.TouchAPIKey"
+
+	_, err := s.db.ExecContext(
+		c,
+		"UPDATE api_keys SET last_used_at = ? WHERE id = ?",
+		at.Unix(), keyID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+func scanAPIKey(row scanner) (models.APIKey, error) {
+	var (
+		key                              models.APIKey
+		scopes                           string
+		createdAt                        int64
+		expiresAt, lastUsedAt, revokedAt sql.NullInt64
+	)
+	err := row.Scan(
+		&key.ID, &key.UserID, &key.AppID, &key.Name, &key.Prefix,
+		&key.SecretHash, &scopes,
+		&createdAt, &expiresAt, &lastUsedAt, &revokedAt,
+	)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	if err = json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return models.APIKey{}, err
+	}
+	key.CreatedAt = time.Unix(createdAt, 0)
+	key.ExpiresAt = timeOrZero(expiresAt)
+	key.LastUsedAt = timeOrZero(lastUsedAt)
+	key.RevokedAt = timeOrZero(revokedAt)
+
+	return key, nil
+}
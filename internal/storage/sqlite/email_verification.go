@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"time"
+)
+
+func (s *Storage) SaveEmailVerificationToken(
+	c context.Context,
+	token models.EmailVerificationToken,
+) error {
+	const op = "storage.sqlite.SaveEmailVerificationToken"
+
+	_, err := s.db.ExecContext(
+		c,
+		`INSERT INTO email_verification_tokens(
+		     token_hash, user_id, email, created_at, expires_at
+		 ) VALUES (?, ?, ?, ?, ?)`,
+		token.Hash, token.UserID, token.Email,
+		token.CreatedAt.Unix(), token.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) EmailVerificationToken(
+	c context.Context,
+	hash []byte,
+) (models.EmailVerificationToken, error) {
+	const op = "storage.sqlite.EmailVerificationToken"
+
+	row := s.db.QueryRowContext(
+		c,
+		`SELECT id, token_hash, user_id, email, created_at, expires_at, used_at
+		 FROM email_verification_tokens WHERE token_hash = ?`,
+		hash,
+	)
+
+	var (
+		token                models.EmailVerificationToken
+		createdAt, expiresAt int64
+		usedAt               sql.NullInt64
+	)
+	err := row.Scan(
+		&token.ID, &token.Hash, &token.UserID, &token.Email,
+		&createdAt, &expiresAt, &usedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.EmailVerificationToken{},
+				operr.Error(op, storage.ErrTokenNotFound)
+		}
+
+		return models.EmailVerificationToken{}, operr.Error(op, err)
+	}
+
+	token.CreatedAt = time.Unix(createdAt, 0)
+	token.ExpiresAt = time.Unix(expiresAt, 0)
+	token.UsedAt = timeOrZero(usedAt)
+
+	return token, nil
+}
+
+// VerifyEmail использует токен подтверждения и помечает email
+// пользователя подтвержденным в одной транзакции.
+//
+// Если токен уже использован, возвращает storage.ErrTokenUsed.
+// Если у пользователя уже другой email, возвращает
+// storage.ErrUserNotFound и токен не расходует.
+func (s *Storage) VerifyEmail(
+	c context.Context,
+	token models.EmailVerificationToken,
+) error {
+	const op = "storage.sqlite.VerifyEmail"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(
+		c,
+		`UPDATE email_verification_tokens SET used_at = ?
+		 WHERE id = ? AND used_at IS NULL`,
+		time.Now().Unix(), token.ID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrTokenUsed)
+	}
+
+	res, err = tx.ExecContext(
+		c,
+		"UPDATE users SET email_verified = TRUE WHERE id = ? AND email = ?",
+		token.UserID, token.Email,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err = res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrUserNotFound)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,340 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"github.com/mattn/go-sqlite3"
+)
+
+// userGroupsQuery отбирает id групп, в которые пользователь входит
+// напрямую или через вложенные группы. Параметр — id пользователя.
+//
+// UNION вместо UNION ALL останавливает обход и на цикле,
+// хотя AddSubgroup циклы не допускает.
+const userGroupsQuery = `
+	WITH RECURSIVE member_of(id) AS (
+	    SELECT group_id FROM group_users WHERE user_id = ?
+	    UNION
+	    SELECT gc.group_id FROM group_children gc
+	        JOIN member_of m ON gc.child_id = m.id)
+	SELECT id FROM member_of`
+
+// SaveGroup сохраняет группу и возвращает ее ID.
+//
+// Если в организации уже есть группа с таким именем,
+// возвращает storage.ErrGroupExists.
+func (s *Storage) SaveGroup(c context.Context, group models.Group) (int64, error) {
+	const op = "storage.sqlite.SaveGroup"
+
+	res, err := s.db.ExecContext(
+		c,
+		"INSERT INTO groups(tenant_id, name) VALUES (?, ?)",
+		group.TenantID, group.Name,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) &&
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+
+			return 0, operr.Error(op, storage.ErrGroupExists)
+		}
+
+		return 0, operr.Error(op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) Group(c context.Context, id int64) (models.Group, error) {
+	const op = "storage.sqlite.Group"
+
+	var group models.Group
+	err := s.db.QueryRowContext(
+		c,
+		"SELECT id, tenant_id, name FROM groups WHERE id = ?",
+		id,
+	).Scan(&group.ID, &group.TenantID, &group.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Group{}, operr.Error(op, storage.ErrGroupNotFound)
+		}
+
+		return models.Group{}, operr.Error(op, err)
+	}
+
+	return group, nil
+}
+
+// Groups возвращает группы организации tenantID по имени.
+func (s *Storage) Groups(c context.Context, tenantID int64) ([]models.Group, error) {
+	const op = "storage.sqlite.Groups"
+
+	groups, err := s.queryGroups(
+		c,
+		"SELECT id, tenant_id, name FROM groups WHERE tenant_id = ? ORDER BY name",
+		tenantID,
+	)
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return groups, nil
+}
+
+// UserGroups возвращает по имени группы, в которые пользователь
+// входит напрямую или через вложенные группы.
+func (s *Storage) UserGroups(c context.Context, userID int64) ([]models.Group, error) {
+	const op = "storage.sqlite.UserGroups"
+
+	groups, err := s.queryGroups(
+		c,
+		`SELECT id, tenant_id, name FROM groups
+		 WHERE id IN (`+userGroupsQuery+`) ORDER BY name`,
+		userID,
+	)
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return groups, nil
+}
+
+// DeleteGroup удаляет группу вместе с ее участниками, вхождениями
+// в другие группы и назначенными ей ролями.
+func (s *Storage) DeleteGroup(c context.Context, id int64) error {
+	const op = "storage.sqlite.DeleteGroup"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(c, "DELETE FROM groups WHERE id = ?", id)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrGroupNotFound)
+	}
+
+	for _, query := range []string{
+		"DELETE FROM group_users WHERE group_id = ?",
+		"DELETE FROM group_children WHERE group_id = ?1 OR child_id = ?1",
+		"DELETE FROM group_roles WHERE group_id = ?",
+	} {
+		if _, err = tx.ExecContext(c, query, id); err != nil {
+			return operr.Error(op, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// AddGroupUser добавляет пользователя в группу.
+// Повторное добавление ничего не меняет.
+func (s *Storage) AddGroupUser(c context.Context, groupID, userID int64) error {
+	const op = "storage.sqlite.AddGroupUser"
+
+	_, err := s.db.ExecContext(
+		c,
+		`INSERT INTO group_users(group_id, user_id) VALUES (?, ?)
+		 ON CONFLICT DO NOTHING`,
+		groupID, userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// RemoveGroupUser удаляет пользователя из группы. Если он в ней
+// не состоит, возвращает storage.ErrGroupMemberNotFound.
+func (s *Storage) RemoveGroupUser(c context.Context, groupID, userID int64) error {
+	const op = "storage.sqlite.RemoveGroupUser"
+
+	err := s.deleteMember(
+		c,
+		"DELETE FROM group_users WHERE group_id = ? AND user_id = ?",
+		groupID, userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// AddSubgroup включает группу childID в группу groupID.
+// Повторное включение ничего не меняет.
+//
+// Если groupID и так входит в childID, напрямую или через вложенные
+// группы, или это одна группа, возвращает storage.ErrGroupCycle.
+func (s *Storage) AddSubgroup(c context.Context, groupID, childID int64) error {
+	const op = "storage.sqlite.AddSubgroup"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var cycle bool
+	err = tx.QueryRowContext(
+		c,
+		`WITH RECURSIVE descendants(id) AS (
+		     SELECT ?
+		     UNION
+		     SELECT gc.child_id FROM group_children gc
+		         JOIN descendants d ON gc.group_id = d.id)
+		 SELECT EXISTS(SELECT 1 FROM descendants WHERE id = ?)`,
+		childID, groupID,
+	).Scan(&cycle)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if cycle {
+		return operr.Error(op, storage.ErrGroupCycle)
+	}
+
+	_, err = tx.ExecContext(
+		c,
+		`INSERT INTO group_children(group_id, child_id) VALUES (?, ?)
+		 ON CONFLICT DO NOTHING`,
+		groupID, childID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// RemoveSubgroup исключает группу childID из группы groupID. Если она
+// в нее не входит, возвращает storage.ErrGroupMemberNotFound.
+func (s *Storage) RemoveSubgroup(c context.Context, groupID, childID int64) error {
+	const op = "storage.sqlite.RemoveSubgroup"
+
+	err := s.deleteMember(
+		c,
+		"DELETE FROM group_children WHERE group_id = ? AND child_id = ?",
+		groupID, childID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// AssignGroupRole назначает группе роль. Повторное назначение
+// ничего не меняет.
+func (s *Storage) AssignGroupRole(c context.Context, groupID, roleID int64) error {
+	const op = "storage.sqlite.AssignGroupRole"
+
+	_, err := s.db.ExecContext(
+		c,
+		`INSERT INTO group_roles(group_id, role_id) VALUES (?, ?)
+		 ON CONFLICT DO NOTHING`,
+		groupID, roleID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// UnassignGroupRole снимает роль с группы. Если роль не была
+// назначена, возвращает storage.ErrRoleNotAssigned.
+func (s *Storage) UnassignGroupRole(c context.Context, groupID, roleID int64) error {
+	const op = "storage.sqlite.UnassignGroupRole"
+
+	res, err := s.db.ExecContext(
+		c,
+		"DELETE FROM group_roles WHERE group_id = ? AND role_id = ?",
+		groupID, roleID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrRoleNotAssigned)
+	}
+
+	return nil
+}
+
+// deleteMember выполняет удаление участника группы и возвращает
+// storage.ErrGroupMemberNotFound, если ничего не удалено.
+func (s *Storage) deleteMember(c context.Context, query string, args ...any) error {
+	res, err := s.db.ExecContext(c, query, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrGroupMemberNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) queryGroups(
+	c context.Context,
+	query string,
+	args ...any,
+) ([]models.Group, error) {
+	rows, err := s.db.QueryContext(c, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.Group{}
+	for rows.Next() {
+		var group models.Group
+		if err = rows.Scan(&group.ID, &group.TenantID, &group.Name); err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, group)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,262 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"time"
+)
+
+const invitationColumns = `id, token_hash, app_id, COALESCE(role_id, 0), email,
+	invited_by, created_at, expires_at, accepted_at,
+	COALESCE(accepted_by, 0), revoked_at`
+
+func (s *Storage) SaveInvitation(
+	c context.Context,
+	inv models.Invitation,
+) (int64, error) {
+	const op = "storage.sqlite.SaveInvitation"
+
+	res, err := s.db.ExecContext(
+		c,
+		`INSERT INTO invitations(token_hash, app_id, role_id, email,
+		                         invited_by, created_at, expires_at)
+		 VALUES (?, ?, NULLIF(?, 0), ?, ?, ?, ?)`,
+		inv.Hash, inv.AppID, inv.RoleID, inv.Email,
+		inv.InvitedBy, inv.CreatedAt.Unix(), inv.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	return id, nil
+}
+
+// Invitation возвращает приглашение по хэшу токена.
+func (s *Storage) Invitation(
+	c context.Context,
+	hash []byte,
+) (models.Invitation, error) {
+	const op = "storage.sqlite.Invitation"
+
+	row := s.db.QueryRowContext(
+		c,
+		"SELECT "+invitationColumns+" FROM invitations WHERE token_hash = ?",
+		hash,
+	)
+
+	inv, err := scanInvitation(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Invitation{}, operr.Error(op, storage.ErrTokenNotFound)
+		}
+
+		return models.Invitation{}, operr.Error(op, err)
+	}
+
+	return inv, nil
+}
+
+// Invitations возвращает приглашения в приложение appID,
+// при appID = 0 — во все приложения, начиная с новых.
+func (s *Storage) Invitations(
+	c context.Context,
+	appID int,
+) ([]models.Invitation, error) {
+	const op = "storage.sqlite.Invitations"
+
+	rows, err := s.db.QueryContext(
+		c,
+		"SELECT "+invitationColumns+` FROM invitations
+		 WHERE ?1 = 0 OR app_id = ?1 ORDER BY id DESC`,
+		appID,
+	)
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+	defer rows.Close()
+
+	invitations := []models.Invitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, operr.Error(op, err)
+		}
+
+		invitations = append(invitations, inv)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return invitations, nil
+}
+
+// RevokeInvitation отзывает приглашение. Если его нет или оно уже
+// принято либо отозвано, возвращает storage.ErrInvitationNotFound.
+func (s *Storage) RevokeInvitation(c context.Context, id int64, at time.Time) error {
+	const op = "storage.sqlite.RevokeInvitation"
+
+	res, err := s.db.ExecContext(
+		c,
+		`UPDATE invitations SET revoked_at = ?
+		 WHERE id = ? AND accepted_at IS NULL AND revoked_at IS NULL`,
+		at.Unix(), id,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrInvitationNotFound)
+	}
+
+	return nil
+}
+
+// AcceptInvitation в одной транзакции отмечает приглашение принятым
+// пользователем userID, назначает ему роль из приглашения и считает
+// подтвержденным email, на который пришло приглашение.
+//
+// Если приглашение уже принято, отозвано или истекло (например,
+// его принял параллельный запрос), возвращает storage.ErrTokenUsed.
+func (s *Storage) AcceptInvitation(
+	c context.Context,
+	id int64,
+	userID int64,
+	at time.Time,
+) error {
+	const op = "storage.sqlite.AcceptInvitation"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err = acceptInvitation(c, tx, id, userID, at); err != nil {
+		return operr.Error(op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// AcceptInvitationNewUser в одной транзакции создает пользователя
+// организации tenantID и принимает им приглашение, как AcceptInvitation.
+// Если принять приглашение не удалось, пользователь не создается.
+//
+// Возвращает ID пользователя, storage.ErrUserExists, если email
+// в организации уже занят, или storage.ErrTokenUsed.
+func (s *Storage) AcceptInvitationNewUser(
+	c context.Context,
+	id int64,
+	tenantID int64,
+	email string,
+	passHash []byte,
+	at time.Time,
+) (int64, error) {
+	const op = "storage.sqlite.AcceptInvitationNewUser"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	userID, err := insertUser(c, tx, tenantID, email, passHash, at)
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	if err = acceptInvitation(c, tx, id, userID, at); err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	return userID, nil
+}
+
+// acceptInvitation принимает приглашение в транзакции tx.
+func acceptInvitation(
+	c context.Context,
+	tx *sql.Tx,
+	id int64,
+	userID int64,
+	at time.Time,
+) error {
+	res, err := tx.ExecContext(
+		c,
+		`UPDATE invitations SET accepted_at = ?, accepted_by = ?
+		 WHERE id = ? AND accepted_at IS NULL AND revoked_at IS NULL
+		   AND expires_at > ?`,
+		at.Unix(), userID, id, at.Unix(),
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrTokenUsed
+	}
+
+	for _, query := range []string{
+		`INSERT INTO user_roles(user_id, role_id)
+		 SELECT ?1, role_id FROM invitations WHERE id = ?2 AND role_id IS NOT NULL
+		 ON CONFLICT DO NOTHING`,
+		`UPDATE users SET email_verified = TRUE
+		 WHERE id = ?1 AND email = (SELECT email FROM invitations WHERE id = ?2)`,
+	} {
+		if _, err = tx.ExecContext(c, query, userID, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanInvitation(row scanner) (models.Invitation, error) {
+	var (
+		inv                  models.Invitation
+		createdAt, expiresAt int64
+		acceptedAt           sql.NullInt64
+		revokedAt            sql.NullInt64
+	)
+	err := row.Scan(
+		&inv.ID, &inv.Hash, &inv.AppID, &inv.RoleID, &inv.Email,
+		&inv.InvitedBy, &createdAt, &expiresAt, &acceptedAt,
+		&inv.AcceptedBy, &revokedAt,
+	)
+	if err != nil {
+		return models.Invitation{}, err
+	}
+
+	inv.CreatedAt = time.Unix(createdAt, 0)
+	inv.ExpiresAt = time.Unix(expiresAt, 0)
+	inv.AcceptedAt = timeOrZero(acceptedAt)
+	inv.RevokedAt = timeOrZero(revokedAt)
+
+	return inv, nil
+}
@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"time"
+)
+
+// LoginThrottle возвращает счетчик неудачных входов.
+// Если неудач не было, возвращает пустой счетчик без ошибки.
+func (s *Storage) LoginThrottle(
+	c context.Context,
+	scope string,
+	subject string,
+) (models.LoginThrottle, error) {
+	const op = "storage.sqlite.LoginThrottle"
+
+	row := s.db.QueryRowContext(
+		c,
+		`SELECT failures, last_failure_at, blocked_until, locked_until
+		 FROM login_throttle WHERE scope = ? AND subject = ?`,
+		scope, subject,
+	)
+
+	throttle := models.LoginThrottle{Scope: scope, Subject: subject}
+
+	var (
+		lastFailureAt             int64
+		blockedUntil, lockedUntil sql.NullInt64
+	)
+	err := row.Scan(
+		&throttle.Failures, &lastFailureAt, &blockedUntil, &lockedUntil,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return throttle, nil
+		}
+
+		return models.LoginThrottle{}, operr.Error(op, err)
+	}
+
+	throttle.LastFailureAt = time.Unix(lastFailureAt, 0)
+	throttle.BlockedUntil = timeOrZero(blockedUntil)
+	throttle.LockedUntil = timeOrZero(lockedUntil)
+
+	return throttle, nil
+}
+
+// RecordLoginFailure атомарно увеличивает счетчик неудачных входов
+// и возвращает новое значение. Если с прошлой неудачи прошло
+// больше resetAfter, счет начинается заново.
+func (s *Storage) RecordLoginFailure(
+	c context.Context,
+	scope string,
+	subject string,
+	at time.Time,
+	resetAfter time.Duration,
+) (int, error) {
+	const op = "storage.sqlite.RecordLoginFailure"
+
+	row := s.db.QueryRowContext(
+		c,
+		`INSERT INTO login_throttle(scope, subject, failures, last_failure_at)
+		 VALUES (?, ?, 1, ?)
+		 ON CONFLICT (scope, subject) DO UPDATE SET
+		     failures = CASE WHEN last_failure_at < ? THEN 1
+		                     ELSE failures + 1 END,
+		     last_failure_at = excluded.last_failure_at
+		 RETURNING failures`,
+		scope, subject, at.Unix(), at.Add(-resetAfter).Unix(),
+	)
+
+	var failures int
+	if err := row.Scan(&failures); err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	return failures, nil
+}
+
+// SetLoginBlock задает окно задержки и блокировку входа.
+// Нулевое время снимает соответствующее ограничение.
+func (s *Storage) SetLoginBlock(
+	c context.Context,
+	scope string,
+	subject string,
+	blockedUntil time.Time,
+	lockedUntil time.Time,
+) error {
+	const op = "storage.sqlite.SetLoginBlock"
+
+	_, err := s.db.ExecContext(
+		c,
+		`UPDATE login_throttle SET blocked_until = ?, locked_until = ?
+		 WHERE scope = ? AND subject = ?`,
+		unixOrNull(blockedUntil), unixOrNull(lockedUntil), scope, subject,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// ResetLoginFailures удаляет счетчик вместе с задержкой и блокировкой.
+func (s *Storage) ResetLoginFailures(
+	c context.Context,
+	scope string,
+	subject string,
+) error {
+	const op = "storage.sqlite.ResetLoginFailures"
+
+	_, err := s.db.ExecContext(
+		c,
+		"DELETE FROM login_throttle WHERE scope = ? AND subject = ?",
+		scope, subject,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
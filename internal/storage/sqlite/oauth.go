@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"time"
+)
+
+func (s *Storage) SaveAuthCode(c context.Context, code models.AuthCode) error {
+	const op = "storage.sqlite.SaveAuthCode"
+
+	_, err := s.db.ExecContext(
+		c,
+		`INSERT INTO authorization_codes(
+		     code_hash, app_id, user_id, redirect_uri,
+		     code_challenge, scope, nonce, auth_time, expires_at
+		 ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		code.Hash, code.AppID, code.UserID, code.RedirectURI,
+		code.CodeChallenge, code.Scope, code.Nonce, code.AuthTime.Unix(),
+		code.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// AuthCode возвращает код авторизации по хэшу, не используя его.
+func (s *Storage) AuthCode(c context.Context, hash []byte) (models.AuthCode, error) {
+	const op = "storage.sqlite.AuthCode"
+
+	code, err := s.authCode(c, hash)
+	if err != nil {
+		return models.AuthCode{}, operr.Error(op, err)
+	}
+
+	return code, nil
+}
+
+// UseAuthCode помечает код использованным и связывает его
+// с семейством токенов familyID, выданных в обмен.
+//
+// Если код уже использован, возвращает его вместе
+// с storage.ErrTokenUsed: по FamilyID можно отозвать выданные токены.
+func (s *Storage) UseAuthCode(
+	c context.Context,
+	hash []byte,
+	familyID string,
+) (models.AuthCode, error) {
+	const op = "storage.sqlite.UseAuthCode"
+
+	// Условное обновление атомарно: из параллельных обменов
+	// одного кода успешным будет только один.
+	res, err := s.db.ExecContext(
+		c,
+		`UPDATE authorization_codes SET used_at = ?, family_id = ?
+		 WHERE code_hash = ? AND used_at IS NULL`,
+		time.Now().Unix(), familyID, hash,
+	)
+	if err != nil {
+		return models.AuthCode{}, operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return models.AuthCode{}, operr.Error(op, err)
+	}
+
+	code, err := s.authCode(c, hash)
+	if err != nil {
+		return models.AuthCode{}, operr.Error(op, err)
+	}
+
+	if n == 0 {
+		return code, operr.Error(op, storage.ErrTokenUsed)
+	}
+
+	return code, nil
+}
+
+// authCode читает код авторизации по хэшу.
+func (s *Storage) authCode(c context.Context, hash []byte) (models.AuthCode, error) {
+	row := s.db.QueryRowContext(
+		c,
+		`SELECT id, code_hash, app_id, user_id, redirect_uri,
+		        code_challenge, scope, nonce, auth_time,
+		        expires_at, used_at, family_id
+		 FROM authorization_codes WHERE code_hash = ?`,
+		hash,
+	)
+
+	var (
+		code      models.AuthCode
+		authTime  int64
+		expiresAt int64
+		usedAt    sql.NullInt64
+		family    sql.NullString
+	)
+	err := row.Scan(
+		&code.ID, &code.Hash, &code.AppID, &code.UserID, &code.RedirectURI,
+		&code.CodeChallenge, &code.Scope, &code.Nonce, &authTime,
+		&expiresAt, &usedAt, &family,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AuthCode{}, storage.ErrTokenNotFound
+		}
+
+		return models.AuthCode{}, err
+	}
+
+	code.AuthTime = time.Unix(authTime, 0)
+	code.ExpiresAt = time.Unix(expiresAt, 0)
+	code.UsedAt = timeOrZero(usedAt)
+	code.FamilyID = family.String
+
+	return code, nil
+}
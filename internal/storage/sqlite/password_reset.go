@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"time"
+)
+
+func (s *Storage) SavePasswordResetToken(
+	c context.Context,
+	token models.PasswordResetToken,
+) error {
+	const op = "storage.sqlite.SavePasswordResetToken"
+
+	_, err := s.db.ExecContext(
+		c,
+		`INSERT INTO password_reset_tokens(token_hash, user_id, created_at, expires_at)
+		 VALUES (?, ?, ?, ?)`,
+		token.Hash, token.UserID, token.CreatedAt.Unix(), token.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) PasswordResetToken(
+	c context.Context,
+	hash []byte,
+) (models.PasswordResetToken, error) {
+	const op = "storage.sqlite.PasswordResetToken"
+
+	row := s.db.QueryRowContext(
+		c,
+		`SELECT id, token_hash, user_id, created_at, expires_at, used_at
+		 FROM password_reset_tokens WHERE token_hash = ?`,
+		hash,
+	)
+
+	var (
+		token                models.PasswordResetToken
+		createdAt, expiresAt int64
+		usedAt               sql.NullInt64
+	)
+	err := row.Scan(
+		&token.ID, &token.Hash, &token.UserID,
+		&createdAt, &expiresAt, &usedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PasswordResetToken{},
+				operr.Error(op, storage.ErrTokenNotFound)
+		}
+
+		return models.PasswordResetToken{}, operr.Error(op, err)
+	}
+
+	token.CreatedAt = time.Unix(createdAt, 0)
+	token.ExpiresAt = time.Unix(expiresAt, 0)
+	token.UsedAt = timeOrZero(usedAt)
+
+	return token, nil
+}
+
+// ResetPassword использует токен сброса tokenID и меняет хэш пароля
+// пользователя в одной транзакции. Остальные неиспользованные токены
+// пользователя при этом тоже гасятся.
+//
+// Если токен уже использован (например, параллельным запросом),
+// возвращает storage.ErrTokenUsed и пароль не меняет.
+func (s *Storage) ResetPassword(
+	c context.Context,
+	tokenID int64,
+	userID int64,
+	passHash []byte,
+) error {
+	const op = "storage.sqlite.ResetPassword"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().Unix()
+
+	res, err := tx.ExecContext(
+		c,
+		`UPDATE password_reset_tokens SET used_at = ?
+		 WHERE id = ? AND user_id = ? AND used_at IS NULL`,
+		now, tokenID, userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrTokenUsed)
+	}
+
+	_, err = tx.ExecContext(
+		c,
+		`UPDATE password_reset_tokens SET used_at = ?
+		 WHERE user_id = ? AND used_at IS NULL`,
+		now, userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	res, err = tx.ExecContext(
+		c,
+		"UPDATE users SET pass_hash = ? WHERE id = ?",
+		passHash, userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err = res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrUserNotFound)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
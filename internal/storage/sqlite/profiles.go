@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"time"
+)
+
+// Profile возвращает профиль пользователя. Если профиль
+// еще не заполнялся, возвращает пустой профиль без ошибки.
+func (s *Storage) Profile(c context.Context, userID int64) (models.Profile, error) {
+	const op = "storage.sqlite.Profile"
+
+	profile := models.Profile{
+		UserID:     userID,
+		Attributes: map[string]string{},
+	}
+
+	var (
+		attributes string
+		updatedAt  int64
+	)
+	err := s.db.QueryRowContext(
+		c,
+		`SELECT display_name, locale, timezone, avatar_url, attributes, updated_at
+		 FROM profiles WHERE user_id = ?`,
+		userID,
+	).Scan(
+		&profile.DisplayName,
+		&profile.Locale,
+		&profile.Timezone,
+		&profile.AvatarURL,
+		&attributes,
+		&updatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return profile, nil
+		}
+
+		return models.Profile{}, operr.Error(op, err)
+	}
+
+	if err = json.Unmarshal([]byte(attributes), &profile.Attributes); err != nil {
+		return models.Profile{}, operr.Error(op, err)
+	}
+
+	profile.UpdatedAt = time.Unix(updatedAt, 0)
+
+	return profile, nil
+}
+
+// SaveProfile сохраняет профиль пользователя целиком.
+func (s *Storage) SaveProfile(c context.Context, profile models.Profile) error {
+	const op = "storage.sqlite.SaveProfile"
+
+	attributes := profile.Attributes
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+
+	attrJSON, err := json.Marshal(attributes)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	_, err = s.db.ExecContext(
+		c,
+		`INSERT INTO profiles(user_id, display_name, locale, timezone,
+		                      avatar_url, attributes, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (user_id) DO UPDATE SET
+		     display_name = excluded.display_name,
+		     locale       = excluded.locale,
+		     timezone     = excluded.timezone,
+		     avatar_url   = excluded.avatar_url,
+		     attributes   = excluded.attributes,
+		     updated_at   = excluded.updated_at`,
+		profile.UserID, profile.DisplayName, profile.Locale, profile.Timezone,
+		profile.AvatarURL, string(attrJSON), profile.UpdatedAt.Unix(),
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
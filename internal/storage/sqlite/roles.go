@@ -0,0 +1,249 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SaveRole сохраняет роль вместе с разрешениями и возвращает ее ID.
+//
+// Если в приложении уже есть роль с таким именем,
+// возвращает storage.ErrRoleExists.
+func (s *Storage) SaveRole(c context.Context, role models.Role) (int64, error) {
+	const op = "storage.sqlite.SaveRole"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(
+		c,
+		"INSERT INTO roles(app_id, name) VALUES (NULLIF(?, 0), ?)",
+		role.AppID, role.Name,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) &&
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+
+			return 0, operr.Error(op, storage.ErrRoleExists)
+		}
+
+		return 0, operr.Error(op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	for _, permission := range role.Permissions {
+		_, err = tx.ExecContext(
+			c,
+			`INSERT INTO role_permissions(role_id, permission) VALUES (?, ?)
+			 ON CONFLICT DO NOTHING`,
+			id, permission,
+		)
+		if err != nil {
+			return 0, operr.Error(op, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) Role(c context.Context, id int64) (models.Role, error) {
+	const op = "storage.sqlite.Role"
+
+	roles, err := s.queryRoles(c, "r.id = ?", id)
+	if err != nil {
+		return models.Role{}, operr.Error(op, err)
+	}
+	if len(roles) == 0 {
+		return models.Role{}, operr.Error(op, storage.ErrRoleNotFound)
+	}
+
+	return roles[0], nil
+}
+
+// Roles возвращает роли приложения appID, при appID = 0 — глобальные.
+func (s *Storage) Roles(c context.Context, appID int) ([]models.Role, error) {
+	const op = "storage.sqlite.Roles"
+
+	roles, err := s.queryRoles(c, "COALESCE(r.app_id, 0) = ?", appID)
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return roles, nil
+}
+
+// UserRoles возвращает роли пользователя, действующие в приложении
+// appID: назначенные в нем и глобальные, в том числе назначенные
+// группам пользователя.
+func (s *Storage) UserRoles(
+	c context.Context,
+	userID int64,
+	appID int,
+) ([]models.Role, error) {
+	const op = "storage.sqlite.UserRoles"
+
+	roles, err := s.queryRoles(
+		c,
+		`(r.id IN (SELECT role_id FROM user_roles WHERE user_id = ?)
+		  OR r.id IN (SELECT role_id FROM group_roles
+		              WHERE group_id IN (`+userGroupsQuery+`)))
+		 AND (r.app_id IS NULL OR r.app_id = ?)`,
+		userID, userID, appID,
+	)
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return roles, nil
+}
+
+// DeleteRole удаляет роль вместе с ее разрешениями и назначениями
+// и убирает ее из приглашений.
+func (s *Storage) DeleteRole(c context.Context, id int64) error {
+	const op = "storage.sqlite.DeleteRole"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(c, "DELETE FROM roles WHERE id = ?", id)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrRoleNotFound)
+	}
+
+	for _, query := range []string{
+		"DELETE FROM role_permissions WHERE role_id = ?",
+		"DELETE FROM user_roles WHERE role_id = ?",
+		"DELETE FROM group_roles WHERE role_id = ?",
+		// Внешние ключи не включены, и ON DELETE SET NULL не срабатывает,
+		// а id ролей переиспользуются: иначе приглашение выдало бы
+		// роль, созданную позже под тем же id.
+		"UPDATE invitations SET role_id = NULL WHERE role_id = ?",
+	} {
+		if _, err = tx.ExecContext(c, query, id); err != nil {
+			return operr.Error(op, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// AssignRole назначает пользователю роль. Повторное назначение
+// ничего не меняет.
+func (s *Storage) AssignRole(c context.Context, userID, roleID int64) error {
+	const op = "storage.sqlite.AssignRole"
+
+	_, err := s.db.ExecContext(
+		c,
+		`INSERT INTO user_roles(user_id, role_id) VALUES (?, ?)
+		 ON CONFLICT DO NOTHING`,
+		userID, roleID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// UnassignRole снимает роль с пользователя. Если роль не была
+// назначена, возвращает storage.ErrRoleNotAssigned.
+func (s *Storage) UnassignRole(c context.Context, userID, roleID int64) error {
+	const op = "storage.sqlite.UnassignRole"
+
+	res, err := s.db.ExecContext(
+		c,
+		"DELETE FROM user_roles WHERE user_id = ? AND role_id = ?",
+		userID, roleID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrRoleNotAssigned)
+	}
+
+	return nil
+}
+
+// queryRoles возвращает роли с разрешениями, отобранные условием where
+// по таблице roles r, упорядоченные по приложению и имени.
+func (s *Storage) queryRoles(
+	c context.Context,
+	where string,
+	args ...any,
+) ([]models.Role, error) {
+	rows, err := s.db.QueryContext(
+		c,
+		`SELECT r.id, COALESCE(r.app_id, 0), r.name, p.permission
+		 FROM roles r LEFT JOIN role_permissions p ON p.role_id = r.id
+		 WHERE `+where+`
+		 ORDER BY COALESCE(r.app_id, 0), r.name, p.permission`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var (
+			role       models.Role
+			permission sql.NullString
+		)
+		err = rows.Scan(&role.ID, &role.AppID, &role.Name, &permission)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(roles) == 0 || roles[len(roles)-1].ID != role.ID {
+			roles = append(roles, role)
+		}
+		if permission.Valid {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, permission.String)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
@@ -0,0 +1,300 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"time"
+)
+
+// SaveTOTP сохраняет новый неподтвержденный секрет пользователя,
+// заменяя прежний неподтвержденный.
+//
+// Если второй фактор уже включен, возвращает storage.ErrTOTPEnabled.
+func (s *Storage) SaveTOTP(c context.Context, userID int64, secret string) error {
+	const op = "storage.sqlite.SaveTOTP"
+
+	res, err := s.db.ExecContext(
+		c,
+		`INSERT INTO user_totp(user_id, secret) VALUES (?, ?)
+		 ON CONFLICT (user_id) DO UPDATE
+		 SET secret = excluded.secret, last_step = 0
+		 WHERE user_totp.confirmed_at IS NULL`,
+		userID, secret,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrTOTPEnabled)
+	}
+
+	return nil
+}
+
+func (s *Storage) TOTP(c context.Context, userID int64) (models.TOTP, error) {
+	const op = "storage.sqlite.TOTP"
+
+	row := s.db.QueryRowContext(
+		c,
+		`SELECT user_id, secret, confirmed_at, last_step
+		 FROM user_totp WHERE user_id = ?`,
+		userID,
+	)
+
+	var (
+		t           models.TOTP
+		confirmedAt sql.NullInt64
+	)
+	err := row.Scan(&t.UserID, &t.Secret, &confirmedAt, &t.LastStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TOTP{}, operr.Error(op, storage.ErrTOTPNotFound)
+		}
+
+		return models.TOTP{}, operr.Error(op, err)
+	}
+
+	t.ConfirmedAt = timeOrZero(confirmedAt)
+
+	return t, nil
+}
+
+// ConfirmTOTP включает второй фактор, запоминает принятый шаг step
+// и заменяет коды восстановления пользователя новыми в одной транзакции.
+//
+// Если второй фактор уже включен или шаг уже был принят,
+// возвращает storage.ErrTOTPEnabled.
+func (s *Storage) ConfirmTOTP(
+	c context.Context,
+	userID int64,
+	step int64,
+	recoveryHashes [][]byte,
+) error {
+	const op = "storage.sqlite.ConfirmTOTP"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(
+		c,
+		`UPDATE user_totp SET confirmed_at = ?, last_step = ?
+		 WHERE user_id = ? AND confirmed_at IS NULL AND last_step < ?`,
+		time.Now().Unix(), step, userID, step,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrTOTPEnabled)
+	}
+
+	_, err = tx.ExecContext(c, "DELETE FROM recovery_codes WHERE user_id = ?", userID)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	for _, hash := range recoveryHashes {
+		_, err = tx.ExecContext(
+			c,
+			"INSERT INTO recovery_codes(user_id, code_hash) VALUES (?, ?)",
+			userID, hash,
+		)
+		if err != nil {
+			return operr.Error(op, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep запоминает принятый шаг step.
+//
+// Условное обновление не дает принять один код дважды:
+// если шаг не новее последнего, возвращает storage.ErrTokenUsed.
+func (s *Storage) UseTOTPStep(c context.Context, userID int64, step int64) error {
+	const op = "storage.sqlite.UseTOTPStep"
+
+	res, err := s.db.ExecContext(
+		c,
+		"UPDATE user_totp SET last_step = ? WHERE user_id = ? AND last_step < ?",
+		step, userID, step,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrTokenUsed)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode помечает код восстановления использованным.
+//
+// Неизвестный или уже использованный код
+// дает storage.ErrTokenNotFound.
+func (s *Storage) UseRecoveryCode(c context.Context, userID int64, hash []byte) error {
+	const op = "storage.sqlite.UseRecoveryCode"
+
+	res, err := s.db.ExecContext(
+		c,
+		`UPDATE recovery_codes SET used_at = ?
+		 WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+		time.Now().Unix(), userID, hash,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrTokenNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveLoginChallenge(
+	c context.Context,
+	challenge models.LoginChallenge,
+) error {
+	const op = "storage.sqlite.SaveLoginChallenge"
+
+	_, err := s.db.ExecContext(
+		c,
+		`INSERT INTO login_challenges(challenge_hash, user_id, app_id, expires_at)
+		 VALUES (?, ?, ?, ?)`,
+		challenge.Hash, challenge.UserID, challenge.AppID,
+		challenge.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) LoginChallenge(
+	c context.Context,
+	hash []byte,
+) (models.LoginChallenge, error) {
+	const op = "storage.sqlite.LoginChallenge"
+
+	row := s.db.QueryRowContext(
+		c,
+		`SELECT id, challenge_hash, user_id, app_id, expires_at, attempts, used_at
+		 FROM login_challenges WHERE challenge_hash = ?`,
+		hash,
+	)
+
+	var (
+		ch        models.LoginChallenge
+		expiresAt int64
+		usedAt    sql.NullInt64
+	)
+	err := row.Scan(
+		&ch.ID, &ch.Hash, &ch.UserID, &ch.AppID,
+		&expiresAt, &ch.Attempts, &usedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.LoginChallenge{}, operr.Error(op, storage.ErrTokenNotFound)
+		}
+
+		return models.LoginChallenge{}, operr.Error(op, err)
+	}
+
+	ch.ExpiresAt = time.Unix(expiresAt, 0)
+	ch.UsedAt = timeOrZero(usedAt)
+
+	return ch, nil
+}
+
+// AttemptLoginChallenge засчитывает попытку ответа на вызов.
+//
+// Попытка засчитывается до проверки кода и атомарно, поэтому
+// параллельные запросы не дают перебрать больше maxAttempts кодов.
+// Для использованного, истекшего или исчерпавшего попытки вызова
+// возвращает storage.ErrTokenUsed.
+func (s *Storage) AttemptLoginChallenge(
+	c context.Context,
+	id int64,
+	maxAttempts int,
+) error {
+	const op = "storage.sqlite.AttemptLoginChallenge"
+
+	res, err := s.db.ExecContext(
+		c,
+		`UPDATE login_challenges SET attempts = attempts + 1
+		 WHERE id = ? AND used_at IS NULL AND attempts < ? AND expires_at > ?`,
+		id, maxAttempts, time.Now().Unix(),
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrTokenUsed)
+	}
+
+	return nil
+}
+
+// UseLoginChallenge помечает вызов использованным.
+// Если он уже использован, возвращает storage.ErrTokenUsed.
+func (s *Storage) UseLoginChallenge(c context.Context, id int64) error {
+	const op = "storage.sqlite.UseLoginChallenge"
+
+	res, err := s.db.ExecContext(
+		c,
+		`UPDATE login_challenges SET used_at = ?
+		 WHERE id = ? AND used_at IS NULL`,
+		time.Now().Unix(), id,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrTokenUsed)
+	}
+
+	return nil
+}
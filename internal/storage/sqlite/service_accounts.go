@@ -0,0 +1,168 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"github.com/mattn/go-sqlite3"
+	"time"
+)
+
+func (s *Storage) SaveServiceAccount(
+	c context.Context,
+	account models.ServiceAccount,
+) (int64, error) {
+	const op = "storage.sqlite.SaveServiceAccount"
+
+	scopes, err := json.Marshal(account.Scopes)
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	res, err := s.db.ExecContext(
+		c,
+		`INSERT INTO service_accounts(
+		     app_id, name, client_id, secret_hash, scopes, created_at
+		 ) VALUES (?, ?, ?, ?, ?, ?)`,
+		account.AppID, account.Name, account.ClientID, account.SecretHash,
+		string(scopes), account.CreatedAt.Unix(),
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) &&
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+
+			return 0, operr.Error(op, storage.ErrServiceAccountExists)
+		}
+
+		return 0, operr.Error(op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) ServiceAccount(
+	c context.Context,
+	clientID string,
+) (models.ServiceAccount, error) {
+	const op = "storage.sqlite.ServiceAccount"
+
+	row := s.db.QueryRowContext(
+		c,
+		`SELECT id, app_id, name, client_id, secret_hash, scopes, created_at
+		 FROM service_accounts WHERE client_id = ?`,
+		clientID,
+	)
+
+	account, err := scanServiceAccount(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ServiceAccount{},
+				operr.Error(op, storage.ErrServiceAccountNotFound)
+		}
+
+		return models.ServiceAccount{}, operr.Error(op, err)
+	}
+
+	return account, nil
+}
+
+// ServiceAccounts возвращает сервисные аккаунты приложения appID,
+// а при appID = 0 — всех приложений.
+func (s *Storage) ServiceAccounts(
+	c context.Context,
+	appID int,
+) ([]models.ServiceAccount, error) {
+	const op = "storage.sqlite.ServiceAccounts"
+
+	rows, err := s.db.QueryContext(
+		c,
+		`SELECT id, app_id, name, client_id, secret_hash, scopes, created_at
+		 FROM service_accounts WHERE ? = 0 OR app_id = ?
+		 ORDER BY id`,
+		appID, appID,
+	)
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+	defer rows.Close()
+
+	var accounts []models.ServiceAccount
+	for rows.Next() {
+		account, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, operr.Error(op, err)
+		}
+
+		accounts = append(accounts, account)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return accounts, nil
+}
+
+func (s *Storage) DeleteServiceAccount(c context.Context, clientID string) error {
+	const op = "storage.sqlite.DeleteServiceAccount"
+
+	res, err := s.db.ExecContext(
+		c,
+		"DELETE FROM service_accounts WHERE client_id = ?",
+		clientID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrServiceAccountNotFound)
+	}
+
+	return nil
+}
+
+// scanner общий интерфейс *sql.Row и *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanServiceAccount(row scanner) (models.ServiceAccount, error) {
+	var (
+		account   models.ServiceAccount
+		scopes    string
+		createdAt int64
+	)
+	err := row.Scan(
+		&account.ID,
+		&account.AppID,
+		&account.Name,
+		&account.ClientID,
+		&account.SecretHash,
+		&scopes,
+		&createdAt,
+	)
+	if err != nil {
+		return models.ServiceAccount{}, err
+	}
+
+	if err = json.Unmarshal([]byte(scopes), &account.Scopes); err != nil {
+		return models.ServiceAccount{}, err
+	}
+	account.CreatedAt = time.Unix(createdAt, 0)
+
+	return account, nil
+}
@@ -0,0 +1,154 @@
+package sqlite
+
+import (
+	"context"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"time"
+)
+
+func (s *Storage) SaveSession(c context.Context, session models.Session) error {
+	const op = "storage.sqlite.SaveSession"
+
+	_, err := s.db.ExecContext(
+		c,
+		`INSERT INTO sessions(id, user_id, app_id, ip, user_agent, device,
+		                      created_at, last_seen_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID, session.UserID, session.AppID,
+		session.Client.IP, session.Client.UserAgent, session.Client.Device,
+		session.CreatedAt.Unix(), session.LastSeenAt.Unix(),
+		session.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// TouchSession отмечает активность в сеансе id
+// и продлевает его до expiresAt.
+func (s *Storage) TouchSession(
+	c context.Context,
+	id string,
+	lastSeenAt time.Time,
+	expiresAt time.Time,
+) error {
+	const op = "storage.sqlite.TouchSession"
+
+	_, err := s.db.ExecContext(
+		c,
+		`UPDATE sessions SET last_seen_at = ?, expires_at = ?
+		 WHERE id = ? AND revoked_at IS NULL`,
+		lastSeenAt.Unix(), expiresAt.Unix(), id,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// Sessions возвращает действующие на момент now сеансы пользователя,
+// начиная с последнего активного.
+func (s *Storage) Sessions(
+	c context.Context,
+	userID int64,
+	now time.Time,
+) ([]models.Session, error) {
+	const op = "storage.sqlite.Sessions"
+
+	rows, err := s.db.QueryContext(
+		c,
+		`SELECT id, user_id, app_id, ip, user_agent, device,
+		        created_at, last_seen_at, expires_at
+		 FROM sessions
+		 WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		 ORDER BY last_seen_at DESC, created_at DESC`,
+		userID, now.Unix(),
+	)
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var (
+			session                          models.Session
+			createdAt, lastSeenAt, expiresAt int64
+		)
+		err = rows.Scan(
+			&session.ID, &session.UserID, &session.AppID,
+			&session.Client.IP, &session.Client.UserAgent, &session.Client.Device,
+			&createdAt, &lastSeenAt, &expiresAt,
+		)
+		if err != nil {
+			return nil, operr.Error(op, err)
+		}
+
+		session.CreatedAt = time.Unix(createdAt, 0)
+		session.LastSeenAt = time.Unix(lastSeenAt, 0)
+		session.ExpiresAt = time.Unix(expiresAt, 0)
+
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession завершает сеанс id пользователя userID
+// и отзывает его refresh-токены.
+//
+// Чужой, уже завершенный или несуществующий сеанс
+// дает storage.ErrSessionNotFound.
+func (s *Storage) RevokeSession(c context.Context, userID int64, id string) error {
+	const op = "storage.sqlite.RevokeSession"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().Unix()
+
+	res, err := tx.ExecContext(
+		c,
+		`UPDATE sessions SET revoked_at = ?
+		 WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		now, id, userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrSessionNotFound)
+	}
+
+	_, err = tx.ExecContext(
+		c,
+		`UPDATE refresh_tokens SET revoked_at = ?
+		 WHERE family_id = ? AND revoked_at IS NULL`,
+		now, id,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
@@ -3,11 +3,13 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
 	"github.com/h1lton/sso-grpc-ntc/internal/storage"
 	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
 	"github.com/mattn/go-sqlite3"
+	"time"
 )
 
 type Storage struct {
@@ -29,53 +31,97 @@ func New(storagePath string) (*Storage, error) {
 
 func (s *Storage) SaveUser(
 	c context.Context,
+	tenantID int64,
 	email string,
 	passHash []byte,
+	createdAt time.Time,
 ) (int64, error) {
 	const op = "storage.sqlite.SaveUser"
 
-	stmt, err := s.db.Prepare(
-		"INSERT INTO users(email, pass_hash) VALUES (?, ?)",
-	)
+	id, err := insertUser(c, s.db, tenantID, email, passHash, createdAt)
 	if err != nil {
 		return 0, operr.Error(op, err)
 	}
 
-	res, err := stmt.ExecContext(c, email, passHash)
+	return id, nil
+}
+
+// insertUser добавляет пользователя через ex, чтобы регистрацию
+// можно было выполнить и внутри транзакции. Если email в организации
+// уже занят, возвращает storage.ErrUserExists.
+func insertUser(
+	c context.Context,
+	ex execer,
+	tenantID int64,
+	email string,
+	passHash []byte,
+	createdAt time.Time,
+) (int64, error) {
+	res, err := ex.ExecContext(
+		c,
+		`INSERT INTO users(tenant_id, email, pass_hash, created_at)
+		 VALUES (?, ?, ?, ?)`,
+		tenantID, email, passHash, createdAt.Unix(),
+	)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) &&
 			sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
 
-			return 0, operr.Error(op, storage.ErrUserExists)
+			return 0, storage.ErrUserExists
 		}
 
-		return 0, operr.Error(op, err)
+		return 0, err
 	}
 
 	// Получаем ID созданной записи
-	id, err := res.LastInsertId()
+	return res.LastInsertId()
+}
+
+const userColumns = "id, tenant_id, email, pass_hash, email_verified, disabled, created_at"
+
+// User возвращает пользователя организации tenantID по email.
+func (s *Storage) User(
+	c context.Context,
+	tenantID int64,
+	email string,
+) (models.User, error) {
+	const op = "storage.sqlite.User"
+
+	stmt, err := s.db.Prepare(
+		"SELECT " + userColumns + " FROM users WHERE tenant_id = ? AND email = ?",
+	)
 	if err != nil {
-		return 0, operr.Error(op, err)
+		return models.User{}, operr.Error(op, err)
 	}
 
-	return id, nil
+	row := stmt.QueryRowContext(c, tenantID, email)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, operr.Error(op, storage.ErrUserNotFound)
+		}
+
+		return models.User{}, operr.Error(op, err)
+	}
+
+	return user, nil
 }
 
-func (s *Storage) User(c context.Context, email string) (models.User, error) {
-	const op = "storage.sqlite.User"
+func (s *Storage) UserByID(c context.Context, userID int64) (models.User, error) {
+	const op = "storage.sqlite.UserByID"
 
 	stmt, err := s.db.Prepare(
-		"SELECT id, email, pass_hash FROM users WHERE email = ?",
+		"SELECT " + userColumns + " FROM users WHERE id = ?",
 	)
 	if err != nil {
 		return models.User{}, operr.Error(op, err)
 	}
 
-	row := stmt.QueryRowContext(c, email)
+	row := stmt.QueryRowContext(c, userID)
 
-	var user models.User
-	err = row.Scan(&user.ID, &user.Email, &user.PassHash)
+	user, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, operr.Error(op, storage.ErrUserNotFound)
@@ -87,17 +133,52 @@ func (s *Storage) User(c context.Context, email string) (models.User, error) {
 	return user, nil
 }
 
+func scanUser(row scanner) (models.User, error) {
+	var (
+		user      models.User
+		createdAt int64
+	)
+	err := row.Scan(
+		&user.ID,
+		&user.TenantID,
+		&user.Email,
+		&user.PassHash,
+		&user.EmailVerified,
+		&user.Disabled,
+		&createdAt,
+	)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	// 0 — пользователь создан до того, как время стало сохраняться.
+	if createdAt != 0 {
+		user.CreatedAt = time.Unix(createdAt, 0)
+	}
+
+	return user, nil
+}
+
+// IsAdmin сообщает, есть ли у пользователя, напрямую или через
+// группы, глобальная роль с разрешением models.PermissionAdmin.
 func (s *Storage) IsAdmin(c context.Context, userID int64) (bool, error) {
 	const op = "storage.sqlite.IsAdmin"
 
-	stmt, err := s.db.Prepare(
-		"SELECT is_admin FROM users WHERE id = ?",
+	stmt, err := s.db.Prepare(`
+		SELECT EXISTS(
+		    SELECT 1 FROM roles r
+		        JOIN role_permissions p ON p.role_id = r.id
+		    WHERE r.app_id IS NULL AND p.permission = ?
+		      AND (r.id IN (SELECT role_id FROM user_roles WHERE user_id = ?)
+		           OR r.id IN (SELECT role_id FROM group_roles
+		                       WHERE group_id IN (` + userGroupsQuery + `))))
+		FROM users WHERE id = ?`,
 	)
 	if err != nil {
 		return false, operr.Error(op, err)
 	}
 
-	row := stmt.QueryRowContext(c, userID)
+	row := stmt.QueryRowContext(c, models.PermissionAdmin, userID, userID, userID)
 
 	var is bool
 	err = row.Scan(&is)
@@ -112,11 +193,98 @@ func (s *Storage) IsAdmin(c context.Context, userID int64) (bool, error) {
 	return is, nil
 }
 
+// UpdatePassword задает пользователю новый хэш пароля.
+// Неиспользованные токены сброса пароля при этом перестают действовать:
+// они выданы для прежнего пароля.
+func (s *Storage) UpdatePassword(
+	c context.Context,
+	userID int64,
+	passHash []byte,
+) error {
+	const op = "storage.sqlite.UpdatePassword"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(
+		c,
+		"UPDATE users SET pass_hash = ? WHERE id = ?",
+		passHash, userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrUserNotFound)
+	}
+
+	_, err = tx.ExecContext(
+		c,
+		`UPDATE password_reset_tokens SET used_at = ?
+		 WHERE user_id = ? AND used_at IS NULL`,
+		time.Now().Unix(), userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// RehashPassword заменяет хэш пароля пользователя на newHash,
+// только если он все еще oldHash: пароль, смененный
+// между проверкой и пересчетом, не перезаписывается.
+//
+// Если хэш уже другой, возвращает storage.ErrUserNotFound.
+func (s *Storage) RehashPassword(
+	c context.Context,
+	userID int64,
+	oldHash []byte,
+	newHash []byte,
+) error {
+	const op = "storage.sqlite.RehashPassword"
+
+	stmt, err := s.db.Prepare(
+		"UPDATE users SET pass_hash = ? WHERE id = ? AND pass_hash = ?",
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	res, err := stmt.ExecContext(c, newHash, userID, oldHash)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
 func (s *Storage) App(c context.Context, appID int32) (models.App, error) {
 	const op = "storage.sqlite.App"
 
 	stmt, err := s.db.Prepare(
-		"SELECT id, name, secret FROM apps WHERE id = ?",
+		`SELECT id, tenant_id, name, secret, redirect_uris, require_verified_email
+		 FROM apps WHERE id = ?`,
 	)
 	if err != nil {
 		return models.App{}, operr.Error(op, err)
@@ -124,8 +292,18 @@ func (s *Storage) App(c context.Context, appID int32) (models.App, error) {
 
 	row := stmt.QueryRowContext(c, appID)
 
-	var app models.App
-	err = row.Scan(&app.ID, &app.Name, &app.Secret)
+	var (
+		app          models.App
+		redirectURIs string
+	)
+	err = row.Scan(
+		&app.ID,
+		&app.TenantID,
+		&app.Name,
+		&app.Secret,
+		&redirectURIs,
+		&app.RequireVerifiedEmail,
+	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.App{}, operr.Error(op, storage.ErrAppNotFound)
@@ -134,5 +312,29 @@ func (s *Storage) App(c context.Context, appID int32) (models.App, error) {
 		return models.App{}, operr.Error(op, err)
 	}
 
+	err = json.Unmarshal([]byte(redirectURIs), &app.RedirectURIs)
+	if err != nil {
+		return models.App{}, operr.Error(op, err)
+	}
+
 	return app, nil
 }
+
+// unixOrNull переводит время в unix-секунды для записи в бд.
+// Нулевое время записывается как NULL.
+func unixOrNull(t time.Time) sql.NullInt64 {
+	if t.IsZero() {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: t.Unix(), Valid: true}
+}
+
+// timeOrZero обратное преобразование к unixOrNull.
+func timeOrZero(v sql.NullInt64) time.Time {
+	if !v.Valid {
+		return time.Time{}
+	}
+
+	return time.Unix(v.Int64, 0)
+}
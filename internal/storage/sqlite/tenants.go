@@ -0,0 +1,157 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"github.com/mattn/go-sqlite3"
+	"time"
+)
+
+// SaveTenant сохраняет организацию и возвращает ее ID.
+//
+// Если организация с таким именем уже есть,
+// возвращает storage.ErrTenantExists.
+func (s *Storage) SaveTenant(
+	c context.Context,
+	name string,
+	createdAt time.Time,
+) (int64, error) {
+	const op = "storage.sqlite.SaveTenant"
+
+	stmt, err := s.db.Prepare(
+		"INSERT INTO tenants(name, created_at) VALUES (?, ?)",
+	)
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	res, err := stmt.ExecContext(c, name, createdAt.Unix())
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) &&
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+
+			return 0, operr.Error(op, storage.ErrTenantExists)
+		}
+
+		return 0, operr.Error(op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) Tenant(c context.Context, id int64) (models.Tenant, error) {
+	const op = "storage.sqlite.Tenant"
+
+	stmt, err := s.db.Prepare(
+		"SELECT id, name, created_at FROM tenants WHERE id = ?",
+	)
+	if err != nil {
+		return models.Tenant{}, operr.Error(op, err)
+	}
+
+	var (
+		tenant    models.Tenant
+		createdAt int64
+	)
+	err = stmt.QueryRowContext(c, id).Scan(&tenant.ID, &tenant.Name, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Tenant{}, operr.Error(op, storage.ErrTenantNotFound)
+		}
+
+		return models.Tenant{}, operr.Error(op, err)
+	}
+
+	tenant.CreatedAt = time.Unix(createdAt, 0)
+
+	return tenant, nil
+}
+
+// Tenants возвращает все организации в порядке создания.
+func (s *Storage) Tenants(c context.Context) ([]models.Tenant, error) {
+	const op = "storage.sqlite.Tenants"
+
+	rows, err := s.db.QueryContext(
+		c,
+		"SELECT id, name, created_at FROM tenants ORDER BY id",
+	)
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+	defer rows.Close()
+
+	tenants := []models.Tenant{}
+	for rows.Next() {
+		var (
+			tenant    models.Tenant
+			createdAt int64
+		)
+		if err = rows.Scan(&tenant.ID, &tenant.Name, &createdAt); err != nil {
+			return nil, operr.Error(op, err)
+		}
+
+		tenant.CreatedAt = time.Unix(createdAt, 0)
+		tenants = append(tenants, tenant)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return tenants, nil
+}
+
+// TenantApps возвращает приложения организации без секретов.
+func (s *Storage) TenantApps(c context.Context, tenantID int64) ([]models.App, error) {
+	const op = "storage.sqlite.TenantApps"
+
+	rows, err := s.db.QueryContext(
+		c,
+		"SELECT id, tenant_id, name FROM apps WHERE tenant_id = ? ORDER BY id",
+		tenantID,
+	)
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+	defer rows.Close()
+
+	apps := []models.App{}
+	for rows.Next() {
+		var app models.App
+		if err = rows.Scan(&app.ID, &app.TenantID, &app.Name); err != nil {
+			return nil, operr.Error(op, err)
+		}
+
+		apps = append(apps, app)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return apps, nil
+}
+
+func (s *Storage) TenantUserCount(c context.Context, tenantID int64) (int, error) {
+	const op = "storage.sqlite.TenantUserCount"
+
+	var n int
+	err := s.db.QueryRowContext(
+		c,
+		"SELECT COUNT(*) FROM users WHERE tenant_id = ?",
+		tenantID,
+	).Scan(&n)
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	return n, nil
+}
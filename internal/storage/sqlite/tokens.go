@@ -0,0 +1,319 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"time"
+)
+
+func (s *Storage) SaveRefreshToken(
+	c context.Context,
+	token models.RefreshToken,
+) error {
+	const op = "storage.sqlite.SaveRefreshToken"
+
+	if err := saveRefreshToken(c, s.db, token); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RefreshToken(
+	c context.Context,
+	hash []byte,
+) (models.RefreshToken, error) {
+	const op = "storage.sqlite.RefreshToken"
+
+	stmt, err := s.db.Prepare(`
+		SELECT id, token_hash, family_id, user_id, app_id,
+		       expires_at, rotated_at, revoked_at
+		FROM refresh_tokens WHERE token_hash = ?`,
+	)
+	if err != nil {
+		return models.RefreshToken{}, operr.Error(op, err)
+	}
+
+	row := stmt.QueryRowContext(c, hash)
+
+	var (
+		token                models.RefreshToken
+		expiresAt            int64
+		rotatedAt, revokedAt sql.NullInt64
+	)
+	err = row.Scan(
+		&token.ID, &token.Hash, &token.FamilyID, &token.UserID, &token.AppID,
+		&expiresAt, &rotatedAt, &revokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, operr.Error(op, storage.ErrTokenNotFound)
+		}
+
+		return models.RefreshToken{}, operr.Error(op, err)
+	}
+
+	token.ExpiresAt = time.Unix(expiresAt, 0)
+	token.RotatedAt = timeOrZero(rotatedAt)
+	token.RevokedAt = timeOrZero(revokedAt)
+
+	return token, nil
+}
+
+// RotateRefreshToken помечает токен oldID обменянным
+// и сохраняет пришедший ему на смену next в одной транзакции.
+//
+// Если токен уже был обменян (например, параллельным запросом),
+// возвращает storage.ErrTokenRotated и ничего не сохраняет.
+func (s *Storage) RotateRefreshToken(
+	c context.Context,
+	oldID int64,
+	next models.RefreshToken,
+) error {
+	const op = "storage.sqlite.RotateRefreshToken"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(
+		c,
+		`UPDATE refresh_tokens SET rotated_at = ?
+		 WHERE id = ? AND rotated_at IS NULL AND revoked_at IS NULL`,
+		time.Now().Unix(), oldID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrTokenRotated)
+	}
+
+	if err = saveRefreshToken(c, tx, next); err != nil {
+		return operr.Error(op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// RevokeTokenFamily отзывает все еще действующие токены семейства
+// и завершает его сеанс.
+func (s *Storage) RevokeTokenFamily(c context.Context, familyID string) error {
+	const op = "storage.sqlite.RevokeTokenFamily"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().Unix()
+
+	_, err = tx.ExecContext(
+		c,
+		`UPDATE refresh_tokens SET revoked_at = ?
+		 WHERE family_id = ? AND revoked_at IS NULL`,
+		now, familyID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	_, err = tx.ExecContext(
+		c,
+		"UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL",
+		now, familyID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// execer общая часть *sql.DB и *sql.Tx.
+type execer interface {
+	ExecContext(c context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func saveRefreshToken(
+	c context.Context,
+	db execer,
+	token models.RefreshToken,
+) error {
+	_, err := db.ExecContext(
+		c,
+		`INSERT INTO refresh_tokens(token_hash, family_id, user_id, app_id, expires_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		token.Hash, token.FamilyID, token.UserID, token.AppID,
+		token.ExpiresAt.Unix(),
+	)
+
+	return err
+}
+
+// RevokeAccessToken вносит access-токен в список отозванных.
+//
+// Запись хранится до истечения срока действия самого токена,
+// заодно удаляются записи об уже истекших токенах.
+func (s *Storage) RevokeAccessToken(
+	c context.Context,
+	jti string,
+	expiresAt time.Time,
+) error {
+	const op = "storage.sqlite.RevokeAccessToken"
+
+	_, err := s.db.ExecContext(
+		c,
+		"DELETE FROM revoked_tokens WHERE expires_at < ?",
+		time.Now().Unix(),
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	_, err = s.db.ExecContext(
+		c,
+		`INSERT INTO revoked_tokens(jti, expires_at) VALUES (?, ?)
+		 ON CONFLICT DO NOTHING`,
+		jti, expiresAt.Unix(),
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// RevokeUserTokens отзывает все токены пользователя:
+// refresh-токены помечаются отозванными, сеансы завершаются,
+// а поколение токенов увеличивается, так что все выпущенные
+// до этого access-токены считаются недействительными.
+func (s *Storage) RevokeUserTokens(
+	c context.Context,
+	userID int64,
+	at time.Time,
+) error {
+	const op = "storage.sqlite.RevokeUserTokens"
+
+	tx, err := s.db.BeginTx(c, nil)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(
+		c,
+		`INSERT INTO user_token_revocations(user_id, revoked_at, generation)
+		 VALUES (?, ?, 1)
+		 ON CONFLICT (user_id) DO UPDATE
+		 SET revoked_at = excluded.revoked_at, generation = generation + 1`,
+		userID, at.Unix(),
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	_, err = tx.ExecContext(
+		c,
+		`UPDATE refresh_tokens SET revoked_at = ?
+		 WHERE user_id = ? AND revoked_at IS NULL`,
+		at.Unix(), userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	_, err = tx.ExecContext(
+		c,
+		`UPDATE sessions SET revoked_at = ?
+		 WHERE user_id = ? AND revoked_at IS NULL`,
+		at.Unix(), userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return operr.Error(op, err)
+	}
+
+	return nil
+}
+
+// TokenGeneration возвращает текущее поколение токенов пользователя:
+// число массовых отзывов его токенов.
+func (s *Storage) TokenGeneration(c context.Context, userID int64) (int64, error) {
+	const op = "storage.sqlite.TokenGeneration"
+
+	stmt, err := s.db.Prepare(
+		"SELECT generation FROM user_token_revocations WHERE user_id = ?",
+	)
+	if err != nil {
+		return 0, operr.Error(op, err)
+	}
+
+	var generation int64
+	err = stmt.QueryRowContext(c, userID).Scan(&generation)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+
+		return 0, operr.Error(op, err)
+	}
+
+	return generation, nil
+}
+
+// AccessTokenRevoked сообщает, отозван ли access-токен:
+// по его jti, завершением его сеанса sid или массовым отзывом
+// токенов пользователя после выпуска токена в поколении generation.
+func (s *Storage) AccessTokenRevoked(
+	c context.Context,
+	jti string,
+	sid string,
+	userID int64,
+	generation int64,
+) (bool, error) {
+	const op = "storage.sqlite.AccessTokenRevoked"
+
+	stmt, err := s.db.Prepare(`
+		SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = ?)
+		    OR EXISTS(SELECT 1 FROM sessions
+		              WHERE id = ? AND revoked_at IS NOT NULL)
+		    OR EXISTS(SELECT 1 FROM user_token_revocations
+		              WHERE user_id = ? AND generation > ?)`,
+	)
+	if err != nil {
+		return false, operr.Error(op, err)
+	}
+
+	var revoked bool
+	err = stmt.QueryRowContext(c, jti, sid, userID, generation).
+		Scan(&revoked)
+	if err != nil {
+		return false, operr.Error(op, err)
+	}
+
+	return revoked, nil
+}
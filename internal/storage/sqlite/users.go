@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	"github.com/h1lton/sso-grpc-ntc/pkg/operr"
+	"strings"
+	"time"
+)
+
+// usersWithRoleQuery пользователи с ролью ?: назначенной напрямую,
+// группе пользователя или группе, в которую она вложена.
+const usersWithRoleQuery = `
+	SELECT user_id FROM user_roles WHERE role_id = ?
+	UNION
+	SELECT user_id FROM group_users WHERE group_id IN (
+	    WITH RECURSIVE g(id) AS (
+	        SELECT group_id FROM group_roles WHERE role_id = ?
+	        UNION
+	        SELECT c.child_id FROM group_children c JOIN g ON c.group_id = g.id
+	    )
+	    SELECT id FROM g
+	)`
+
+// Users возвращает страницу списка пользователей. Пагинация
+// по ключу: следующая страница начинается за q.After
+// в порядке сортировки, а не со смещения.
+func (s *Storage) Users(
+	c context.Context,
+	q models.UserQuery,
+) ([]models.User, error) {
+	const op = "storage.sqlite.Users"
+
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, condArgs ...any) {
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+
+	f := q.Filter
+	if f.TenantID != 0 {
+		add("tenant_id = ?", f.TenantID)
+	}
+	if f.EmailPrefix != "" {
+		add(`email LIKE ? ESCAPE '\'`, escapeLike(f.EmailPrefix)+"%")
+	}
+	if !f.CreatedFrom.IsZero() {
+		add("created_at >= ?", f.CreatedFrom.Unix())
+	}
+	if !f.CreatedTo.IsZero() {
+		add("created_at < ?", f.CreatedTo.Unix())
+	}
+	if f.RoleID != 0 {
+		add("id IN ("+usersWithRoleQuery+")", f.RoleID, f.RoleID)
+	}
+	if f.AppID != 0 {
+		add("id IN (SELECT user_id FROM sessions WHERE app_id = ?)", f.AppID)
+	}
+	if f.Verified != nil {
+		add("email_verified = ?", *f.Verified)
+	}
+	if f.Disabled != nil {
+		add("disabled = ?", *f.Disabled)
+	}
+
+	var key string
+	switch q.Sort {
+	case models.UserSortEmail:
+		// Как в idx_users_email: иначе индекс не подходит для сортировки.
+		key = "email COLLATE NOCASE"
+	case models.UserSortCreatedAt:
+		key = "created_at"
+	default:
+		key = "id"
+	}
+
+	order, cmp := "ASC", ">"
+	if q.Desc {
+		order, cmp = "DESC", "<"
+	}
+
+	if q.After != nil {
+		switch q.Sort {
+		case models.UserSortEmail:
+			add(
+				"(email COLLATE NOCASE, id) "+cmp+" (?, ?)",
+				q.After.Email, q.After.ID,
+			)
+		case models.UserSortCreatedAt:
+			add(
+				"(created_at, id) "+cmp+" (?, ?)",
+				unixOrZero(q.After.CreatedAt), q.After.ID,
+			)
+		default:
+			add("id "+cmp+" ?", q.After.ID)
+		}
+	}
+
+	query := "SELECT " + userColumns + " FROM users"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if key == "id" {
+		query += fmt.Sprintf(" ORDER BY id %s", order)
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, id %s", key, order, order)
+	}
+	query += " LIMIT ?"
+	args = append(args, q.Limit)
+
+	rows, err := s.db.QueryContext(c, query, args...)
+	if err != nil {
+		return nil, operr.Error(op, err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, operr.Error(op, err)
+		}
+
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, operr.Error(op, err)
+	}
+
+	return users, nil
+}
+
+// SetUserDisabled отключает или снова включает учетную запись
+// пользователя userID.
+//
+// Если пользователя нет, возвращает storage.ErrUserNotFound.
+func (s *Storage) SetUserDisabled(
+	c context.Context,
+	userID int64,
+	disabled bool,
+) error {
+	const op = "storage.sqlite.SetUserDisabled"
+
+	res, err := s.db.ExecContext(
+		c,
+		"UPDATE users SET disabled = ? WHERE id = ?",
+		disabled, userID,
+	)
+	if err != nil {
+		return operr.Error(op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return operr.Error(op, err)
+	}
+	if n == 0 {
+		return operr.Error(op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// escapeLike экранирует в s спецсимволы LIKE.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// unixOrZero переводит время в unix-секунды, нулевое время — в 0.
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
@@ -8,4 +8,33 @@ var (
 	ErrUserExists   = errors.New("пользователь уже существует")
 	ErrUserNotFound = errors.New("пользователь не найден")
 	ErrAppNotFound  = errors.New("приложение не найдено")
+
+	ErrServiceAccountExists   = errors.New("сервисный аккаунт уже существует")
+	ErrServiceAccountNotFound = errors.New("сервисный аккаунт не найден")
+
+	ErrAPIKeyExists   = errors.New("API-ключ уже существует")
+	ErrAPIKeyNotFound = errors.New("API-ключ не найден")
+
+	ErrTOTPNotFound = errors.New("второй фактор не настроен")
+	ErrTOTPEnabled  = errors.New("второй фактор уже включен")
+
+	ErrTokenNotFound = errors.New("токен не найден")
+	ErrTokenRotated  = errors.New("токен уже был обменян")
+	ErrTokenUsed     = errors.New("токен уже использован")
+
+	ErrSessionNotFound = errors.New("сеанс не найден")
+
+	ErrRoleExists      = errors.New("роль уже существует")
+	ErrRoleNotFound    = errors.New("роль не найдена")
+	ErrRoleNotAssigned = errors.New("роль не назначена")
+
+	ErrTenantExists   = errors.New("организация уже существует")
+	ErrTenantNotFound = errors.New("организация не найдена")
+
+	ErrGroupExists         = errors.New("группа уже существует")
+	ErrGroupNotFound       = errors.New("группа не найдена")
+	ErrGroupCycle          = errors.New("группа не может входить сама в себя")
+	ErrGroupMemberNotFound = errors.New("участник не состоит в группе")
+
+	ErrInvitationNotFound = errors.New("приглашение не найдено")
 )
@@ -0,0 +1,12 @@
+-- Счетчики неудачных входов по пользователю (scope = 'user')
+-- и по IP-адресу (scope = 'ip').
+CREATE TABLE IF NOT EXISTS login_throttle
+(
+    scope           TEXT    NOT NULL,
+    subject         TEXT    NOT NULL,
+    failures        INTEGER NOT NULL,
+    last_failure_at INTEGER NOT NULL,
+    blocked_until   INTEGER,
+    locked_until    INTEGER,
+    PRIMARY KEY (scope, subject)
+);
@@ -0,0 +1,15 @@
+-- Сеансы пользователей. id совпадает с family_id refresh-токенов сеанса.
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id           TEXT PRIMARY KEY,
+    user_id      INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    app_id       INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    ip           TEXT    NOT NULL,
+    user_agent   TEXT    NOT NULL,
+    device       TEXT    NOT NULL,
+    created_at   INTEGER NOT NULL,
+    last_seen_at INTEGER NOT NULL,
+    expires_at   INTEGER NOT NULL,
+    revoked_at   INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions (user_id);
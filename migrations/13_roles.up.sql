@@ -0,0 +1,34 @@
+-- Роли с разрешениями. Роль с app_id IS NULL глобальная:
+-- действует во всех приложениях.
+CREATE TABLE IF NOT EXISTS roles
+(
+    id     INTEGER PRIMARY KEY,
+    app_id INTEGER REFERENCES apps (id) ON DELETE CASCADE,
+    name   TEXT NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_app_name ON roles (COALESCE(app_id, 0), name);
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role_id    INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission TEXT    NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles
+(
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+CREATE INDEX IF NOT EXISTS idx_user_roles_role ON user_roles (role_id);
+
+-- Флаг is_admin заменяется глобальной ролью admin.
+INSERT INTO roles (app_id, name) VALUES (NULL, 'admin');
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'sso.admin' FROM roles WHERE app_id IS NULL AND name = 'admin';
+INSERT INTO user_roles (user_id, role_id)
+SELECT users.id, roles.id FROM users, roles
+WHERE users.is_admin AND roles.app_id IS NULL AND roles.name = 'admin';
+
+ALTER TABLE users DROP COLUMN is_admin;
@@ -0,0 +1,20 @@
+CREATE TABLE users_old
+(
+    id             INTEGER PRIMARY KEY,
+    email          TEXT    NOT NULL UNIQUE,
+    pass_hash      BLOB    NOT NULL,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO users_old (id, email, pass_hash, email_verified)
+SELECT id, email, pass_hash, email_verified FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
+
+CREATE INDEX IF NOT EXISTS idx_email ON users (email);
+
+DROP INDEX IF EXISTS idx_apps_tenant;
+ALTER TABLE apps DROP COLUMN tenant_id;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,33 @@
+-- Организации. Приложения и пользователи принадлежат организации,
+-- email уникален в ее пределах. Все существующие записи переходят
+-- в организацию по умолчанию.
+CREATE TABLE IF NOT EXISTS tenants
+(
+    id         INTEGER PRIMARY KEY,
+    name       TEXT    NOT NULL UNIQUE,
+    created_at INTEGER NOT NULL
+);
+
+INSERT INTO tenants (id, name, created_at)
+VALUES (1, 'default', CAST(strftime('%s', 'now') AS INTEGER));
+
+ALTER TABLE apps ADD COLUMN tenant_id INTEGER NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_apps_tenant ON apps (tenant_id);
+
+-- Ограничение UNIQUE на email снимается только пересозданием таблицы.
+CREATE TABLE users_new
+(
+    id             INTEGER PRIMARY KEY,
+    tenant_id      INTEGER NOT NULL DEFAULT 1 REFERENCES tenants (id),
+    email          TEXT    NOT NULL,
+    pass_hash      BLOB    NOT NULL,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO users_new (id, email, pass_hash, email_verified)
+SELECT id, email, pass_hash, email_verified FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_email ON users (tenant_id, email);
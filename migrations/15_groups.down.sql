@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS group_roles;
+DROP TABLE IF EXISTS group_children;
+DROP TABLE IF EXISTS group_users;
+DROP TABLE IF EXISTS groups;
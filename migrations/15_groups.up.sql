@@ -0,0 +1,35 @@
+-- Группы пользователей организации. Группа может входить в другие
+-- группы; роли, назначенные группе, получают все ее участники,
+-- в том числе участники вложенных групп.
+CREATE TABLE IF NOT EXISTS groups
+(
+    id        INTEGER PRIMARY KEY,
+    tenant_id INTEGER NOT NULL REFERENCES tenants (id),
+    name      TEXT    NOT NULL,
+    UNIQUE (tenant_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS group_users
+(
+    group_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    user_id  INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_group_users_user ON group_users (user_id);
+
+-- child_id входит в группу group_id.
+CREATE TABLE IF NOT EXISTS group_children
+(
+    group_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    child_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, child_id)
+);
+CREATE INDEX IF NOT EXISTS idx_group_children_child ON group_children (child_id);
+
+CREATE TABLE IF NOT EXISTS group_roles
+(
+    group_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    role_id  INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, role_id)
+);
+CREATE INDEX IF NOT EXISTS idx_group_roles_role ON group_roles (role_id);
@@ -0,0 +1,16 @@
+-- Приглашения в приложение. role_id IS NULL — без роли.
+CREATE TABLE IF NOT EXISTS invitations
+(
+    id          INTEGER PRIMARY KEY,
+    token_hash  BLOB    NOT NULL UNIQUE,
+    app_id      INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    role_id     INTEGER REFERENCES roles (id) ON DELETE SET NULL,
+    email       TEXT    NOT NULL,
+    invited_by  INTEGER NOT NULL,
+    created_at  INTEGER NOT NULL,
+    expires_at  INTEGER NOT NULL,
+    accepted_at INTEGER,
+    accepted_by INTEGER,
+    revoked_at  INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_invitations_app ON invitations (app_id);
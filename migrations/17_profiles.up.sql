@@ -0,0 +1,12 @@
+-- Профили пользователей. attributes — JSON-объект дополнительных
+-- атрибутов, их схема задается в конфиге.
+CREATE TABLE IF NOT EXISTS profiles
+(
+    user_id      INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    display_name TEXT    NOT NULL DEFAULT '',
+    locale       TEXT    NOT NULL DEFAULT '',
+    timezone     TEXT    NOT NULL DEFAULT '',
+    avatar_url   TEXT    NOT NULL DEFAULT '',
+    attributes   TEXT    NOT NULL DEFAULT '{}',
+    updated_at   INTEGER NOT NULL
+);
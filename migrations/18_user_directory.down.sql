@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_sessions_app_user;
+DROP INDEX IF EXISTS idx_users_verified;
+DROP INDEX IF EXISTS idx_users_created;
+DROP INDEX IF EXISTS idx_users_email;
+
+ALTER TABLE users DROP COLUMN created_at;
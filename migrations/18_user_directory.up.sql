@@ -0,0 +1,8 @@
+-- Время регистрации пользователей для каталога. У пользователей,
+-- созданных раньше, оно неизвестно и остается 0.
+ALTER TABLE users ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_users_email ON users (email COLLATE NOCASE);
+CREATE INDEX IF NOT EXISTS idx_users_created ON users (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_users_verified ON users (email_verified);
+CREATE INDEX IF NOT EXISTS idx_sessions_app_user ON sessions (app_id, user_id);
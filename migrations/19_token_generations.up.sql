@@ -0,0 +1,7 @@
+-- Массовый отзыв токенов пользователя сравнивается не со временем
+-- выпуска (iat с точностью до секунды задевал токены, выпущенные
+-- сразу после отзыва), а с поколением: каждый отзыв увеличивает его,
+-- и токен действителен, только если выпущен в текущем поколении.
+-- Уже отзывавшие токены пользователи переходят в поколение 1,
+-- так что их токены, выпущенные до обновления, перестают действовать.
+ALTER TABLE user_token_revocations ADD COLUMN generation INTEGER NOT NULL DEFAULT 1;
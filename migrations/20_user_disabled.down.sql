@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_disabled;
+
+ALTER TABLE users DROP COLUMN disabled;
@@ -0,0 +1,6 @@
+-- Отключение учетной записи администратором: отключенный
+-- пользователь не может войти и обновить токены, пока его
+-- не включат снова.
+ALTER TABLE users ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_users_disabled ON users (disabled);
@@ -0,0 +1,15 @@
+ALTER TABLE apps ADD COLUMN redirect_uris TEXT NOT NULL DEFAULT '[]';
+
+CREATE TABLE IF NOT EXISTS authorization_codes
+(
+    id             INTEGER PRIMARY KEY,
+    code_hash      BLOB    NOT NULL UNIQUE,
+    app_id         INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    user_id        INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    redirect_uri   TEXT    NOT NULL,
+    code_challenge TEXT    NOT NULL,
+    scope          TEXT    NOT NULL DEFAULT '',
+    expires_at     INTEGER NOT NULL,
+    used_at        INTEGER,
+    family_id      TEXT
+);
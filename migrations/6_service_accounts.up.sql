@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS service_accounts
+(
+    id          INTEGER PRIMARY KEY,
+    app_id      INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    name        TEXT    NOT NULL,
+    client_id   TEXT    NOT NULL UNIQUE,
+    secret_hash BLOB    NOT NULL,
+    scopes      TEXT    NOT NULL DEFAULT '[]',
+    created_at  INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_service_accounts_app ON service_accounts (app_id);
@@ -0,0 +1,32 @@
+package opaque
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// size количество случайных байт в токене.
+const size = 32
+
+// New генерирует случайный непрозрачный токен,
+// закодированный в base64 без паддинга (безопасен для URL).
+func New() (string, error) {
+	b := make([]byte, size)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash возвращает SHA-256 хэш токена.
+//
+// В хранилище попадает только хэш,
+// чтобы утечка базы не давала рабочих токенов.
+func Hash(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+
+	return sum[:]
+}
@@ -0,0 +1,229 @@
+package tests
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKey_HappyPath(t *testing.T) {
+	c, st := suite.New(t)
+
+	respLogin := registerLogin(c, st)
+	ctx := suite.WithAccessToken(c, respLogin.GetToken())
+
+	expiresAt := time.Now().Add(24 * time.Hour).Unix()
+
+	created, err := st.AuthClient.CreateAPIKey(ctx, &ssov1.CreateAPIKeyRequest{
+		Name:      "cli",
+		Scopes:    []string{"repo:read", "repo:write"},
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+
+	info := created.GetApiKey()
+	assert.True(t, strings.HasPrefix(created.GetKey(), "sso_"+info.GetPrefix()+"_"))
+	assert.Equal(t, "cli", info.GetName())
+	assert.Equal(t, expiresAt, info.GetExpiresAt())
+	assert.Empty(t, info.GetLastUsedAt())
+
+	exchanged, err := st.AuthClient.ExchangeAPIKey(c, &ssov1.ExchangeAPIKeyRequest{
+		ApiKey: created.GetKey(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "repo:read repo:write", exchanged.GetScope())
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(exchanged.GetToken(), claims, jwksKeyFunc(c, st))
+	require.NoError(t, err)
+	assert.Equal(t, "repo:read repo:write", claims["scope"])
+	assert.Equal(t, info.GetPrefix(), claims["api_key"])
+
+	introspected, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: exchanged.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.True(t, introspected.GetActive())
+	assert.Equal(t, "repo:read repo:write", introspected.GetScope())
+	assert.Empty(t, introspected.GetRoles())
+	assert.Empty(t, introspected.GetPermissions())
+
+	list, err := st.AuthClient.ListAPIKeys(ctx, &ssov1.ListAPIKeysRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GetApiKeys(), 1)
+	assert.Equal(t, info.GetId(), list.GetApiKeys()[0].GetId())
+	assert.Positive(t, list.GetApiKeys()[0].GetLastUsedAt())
+}
+
+func TestAPIKey_Revoke(t *testing.T) {
+	c, st := suite.New(t)
+
+	respLogin := registerLogin(c, st)
+	ctx := suite.WithAccessToken(c, respLogin.GetToken())
+
+	created := createAPIKey(ctx, st)
+
+	exchanged, err := st.AuthClient.ExchangeAPIKey(c, &ssov1.ExchangeAPIKeyRequest{
+		ApiKey: created.GetKey(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.RevokeAPIKey(ctx, &ssov1.RevokeAPIKeyRequest{
+		Id: created.GetApiKey().GetId(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ExchangeAPIKey(c, &ssov1.ExchangeAPIKeyRequest{
+		ApiKey: created.GetKey(),
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	introspected, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: exchanged.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.False(t, introspected.GetActive())
+
+	list, err := st.AuthClient.ListAPIKeys(ctx, &ssov1.ListAPIKeysRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, list.GetApiKeys())
+}
+
+func TestAPIKey_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	respLogin := registerLogin(c, st)
+	ctx := suite.WithAccessToken(c, respLogin.GetToken())
+
+	created := createAPIKey(ctx, st)
+
+	t.Run("Ключ по токену API-ключа", func(t *testing.T) {
+		exchanged, err := st.AuthClient.ExchangeAPIKey(
+			c,
+			&ssov1.ExchangeAPIKeyRequest{ApiKey: created.GetKey()},
+		)
+		require.NoError(t, err)
+
+		_, err = st.AuthClient.CreateAPIKey(
+			suite.WithAccessToken(c, exchanged.GetToken()),
+			&ssov1.CreateAPIKeyRequest{Name: "nested"},
+		)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Учетная запись по токену API-ключа", func(t *testing.T) {
+		exchanged, err := st.AuthClient.ExchangeAPIKey(
+			c,
+			&ssov1.ExchangeAPIKeyRequest{ApiKey: created.GetKey()},
+		)
+		require.NoError(t, err)
+
+		keyCtx := suite.WithAccessToken(c, exchanged.GetToken())
+
+		calls := map[string]func() error{
+			"LogoutAll": func() error {
+				_, err := st.AuthClient.LogoutAll(keyCtx, &ssov1.LogoutAllRequest{})
+
+				return err
+			},
+			"ListSessions": func() error {
+				_, err := st.AuthClient.ListSessions(keyCtx, &ssov1.ListSessionsRequest{})
+
+				return err
+			},
+			"RevokeSession": func() error {
+				_, err := st.AuthClient.RevokeSession(keyCtx, &ssov1.RevokeSessionRequest{
+					Id: "session",
+				})
+
+				return err
+			},
+			"UpdateProfile": func() error {
+				_, err := st.AuthClient.UpdateProfile(keyCtx, &ssov1.UpdateProfileRequest{})
+
+				return err
+			},
+			"ListAPIKeys": func() error {
+				_, err := st.AuthClient.ListAPIKeys(keyCtx, &ssov1.ListAPIKeysRequest{})
+
+				return err
+			},
+			"RevokeAPIKey": func() error {
+				_, err := st.AuthClient.RevokeAPIKey(keyCtx, &ssov1.RevokeAPIKeyRequest{
+					Id: created.GetApiKey().GetId(),
+				})
+
+				return err
+			},
+		}
+		for name, call := range calls {
+			assert.Equal(t, codes.PermissionDenied, status.Code(call()), name)
+		}
+
+		list, err := st.AuthClient.ListAPIKeys(ctx, &ssov1.ListAPIKeysRequest{})
+		require.NoError(t, err)
+		assert.Len(t, list.GetApiKeys(), 1)
+	})
+
+	t.Run("Неверный секрет", func(t *testing.T) {
+		prefix := created.GetApiKey().GetPrefix()
+
+		_, err := st.AuthClient.ExchangeAPIKey(c, &ssov1.ExchangeAPIKeyRequest{
+			ApiKey: "sso_" + prefix + "_wrong",
+		})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Неверный формат", func(t *testing.T) {
+		_, err := st.AuthClient.ExchangeAPIKey(c, &ssov1.ExchangeAPIKeyRequest{
+			ApiKey: "not-a-key",
+		})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Истекший срок", func(t *testing.T) {
+		_, err := st.AuthClient.CreateAPIKey(ctx, &ssov1.CreateAPIKeyRequest{
+			Name:      "expired",
+			ExpiresAt: time.Now().Add(-time.Hour).Unix(),
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Чужой ключ", func(t *testing.T) {
+		other := suite.WithAccessToken(c, registerLogin(c, st).GetToken())
+
+		_, err := st.AuthClient.RevokeAPIKey(other, &ssov1.RevokeAPIKeyRequest{
+			Id: created.GetApiKey().GetId(),
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Без access-токена", func(t *testing.T) {
+		_, err := st.AuthClient.ListAPIKeys(c, &ssov1.ListAPIKeysRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+// createAPIKey создает бессрочный API-ключ пользователю токена из ctx.
+func createAPIKey(
+	ctx context.Context,
+	st *suite.Suite,
+) *ssov1.CreateAPIKeyResponse {
+	st.Helper()
+
+	created, err := st.AuthClient.CreateAPIKey(ctx, &ssov1.CreateAPIKeyRequest{
+		Name:   "test",
+		Scopes: []string{"repo:read"},
+	})
+	require.NoError(st, err)
+
+	return created
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestIntrospect_ActiveToken(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	respLogin := login(c, st, email, password)
+
+	resp, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: respLogin.GetToken(),
+	})
+	require.NoError(t, err)
+
+	assert.True(t, resp.GetActive())
+	assert.Equal(t, "Bearer", resp.GetTokenType())
+	assert.Equal(t, email, resp.GetEmail())
+	assert.Equal(t, int32(appID), resp.GetAppId())
+	assert.NotEmpty(t, resp.GetUid())
+	assert.NotEmpty(t, resp.GetJti())
+	assert.Empty(t, resp.GetRoles())
+	assert.Greater(t, resp.GetExp(), resp.GetIat())
+}
+
+func TestIntrospect_InactiveToken(t *testing.T) {
+	c, st := suite.New(t)
+
+	respLogin := registerLogin(c, st)
+
+	_, err := st.AuthClient.Logout(
+		suite.WithAccessToken(c, respLogin.GetToken()),
+		&ssov1.LogoutRequest{},
+	)
+	require.NoError(t, err)
+
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid":    1,
+		"app_id": appID,
+		"exp":    jwt.NewNumericDate(time.Now().Add(st.Cfg.TokenTTL)),
+	}).SignedString([]byte("wrong-secret"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "Отозванный токен", token: respLogin.GetToken()},
+		{name: "Токен с чужой подписью", token: forged},
+		{name: "Не JWT", token: "garbage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+				Token: tt.token,
+			})
+			require.NoError(t, err)
+			assert.False(t, resp.GetActive())
+			assert.Empty(t, resp.GetUid())
+		})
+	}
+}
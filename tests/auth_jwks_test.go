@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"testing"
+)
+
+func TestJWKS_HTTPMatchesRPC(t *testing.T) {
+	c, st := suite.New(t)
+
+	respRPC, err := st.AuthClient.JWKS(c, &ssov1.JWKSRequest{})
+	require.NoError(t, err)
+	require.NotEmpty(t, respRPC.GetKeys())
+
+	req, err := http.NewRequestWithContext(
+		c,
+		http.MethodGet,
+		st.HTTPURL("/.well-known/jwks.json"),
+		nil,
+	)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var doc struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Alg string `json:"alg"`
+			N   string `json:"n"`
+		} `json:"keys"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	require.Len(t, doc.Keys, len(respRPC.GetKeys()))
+
+	for i, k := range respRPC.GetKeys() {
+		assert.Equal(t, k.GetKid(), doc.Keys[i].Kid)
+		assert.Equal(t, k.GetKty(), doc.Keys[i].Kty)
+		assert.Equal(t, k.GetAlg(), doc.Keys[i].Alg)
+		assert.Equal(t, k.GetN(), doc.Keys[i].N)
+	}
+}
+
+func TestJWKS_TokenHasKid(t *testing.T) {
+	c, st := suite.New(t)
+
+	respLogin := registerLogin(c, st)
+
+	token, err := jwt.Parse(respLogin.GetToken(), jwksKeyFunc(c, st))
+	require.NoError(t, err)
+	assert.NotEmpty(t, token.Header["kid"])
+}
+
+// jwksKeyFunc возвращает jwt.Keyfunc, который ищет ключ проверки
+// по kid среди ключей, опубликованных сервисом.
+//
+// Поддерживаются только RSA-ключи, которыми подписывает локальный конфиг.
+func jwksKeyFunc(c context.Context, st *suite.Suite) jwt.Keyfunc {
+	st.Helper()
+
+	resp, err := st.AuthClient.JWKS(c, &ssov1.JWKSRequest{})
+	require.NoError(st, err)
+
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		for _, k := range resp.GetKeys() {
+			if k.GetKid() != kid {
+				continue
+			}
+
+			if k.GetKty() != "RSA" {
+				return nil, fmt.Errorf("неподдерживаемый тип ключа %q", k.GetKty())
+			}
+
+			n, err := base64.RawURLEncoding.DecodeString(k.GetN())
+			if err != nil {
+				return nil, err
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.GetE())
+			if err != nil {
+				return nil, err
+			}
+
+			return &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}, nil
+		}
+
+		return nil, fmt.Errorf("ключ %q не найден", kid)
+	}
+}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestLogout_HappyPath(t *testing.T) {
+	c, st := suite.New(t)
+
+	respLogin := registerLogin(c, st)
+	authCtx := suite.WithAccessToken(c, respLogin.GetToken())
+
+	_, err := st.AuthClient.Logout(authCtx, &ssov1.LogoutRequest{
+		RefreshToken: respLogin.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+
+	// access-токен отозван
+	_, err = st.AuthClient.Logout(authCtx, &ssov1.LogoutRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// refresh-токен тоже
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: respLogin.GetRefreshToken(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestLogout_WithoutRefreshToken(t *testing.T) {
+	c, st := suite.New(t)
+
+	respLogin := registerLogin(c, st)
+
+	_, err := st.AuthClient.Logout(
+		suite.WithAccessToken(c, respLogin.GetToken()),
+		&ssov1.LogoutRequest{},
+	)
+	require.NoError(t, err)
+
+	// Сеанс завершен целиком, а не только access-токен.
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: respLogin.GetRefreshToken(),
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestLogoutAll_HappyPath(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	first := login(c, st, email, password)
+	second := login(c, st, email, password)
+
+	_, err := st.AuthClient.LogoutAll(
+		suite.WithAccessToken(c, first.GetToken()),
+		&ssov1.LogoutAllRequest{},
+	)
+	require.NoError(t, err)
+
+	// второй сеанс тоже завершен
+	_, err = st.AuthClient.Logout(
+		suite.WithAccessToken(c, second.GetToken()),
+		&ssov1.LogoutRequest{},
+	)
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: second.GetRefreshToken(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestLogoutAll_LoginRightAfter(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+
+	_, err := st.AuthClient.LogoutAll(
+		suite.WithAccessToken(c, login(c, st, email, password).GetToken()),
+		&ssov1.LogoutAllRequest{},
+	)
+	require.NoError(t, err)
+
+	// Вход в ту же секунду, что и отзыв, дает действующий токен.
+	info, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: login(c, st, email, password).GetToken(),
+	})
+	require.NoError(t, err)
+	assert.True(t, info.GetActive())
+}
+
+func TestLogout_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr string
+	}{
+		{
+			name:        "Выход без токена",
+			token:       "",
+			expectedErr: "access-токен не указан",
+		},
+		{
+			name:        "Выход с поддельным токеном",
+			token:       "not.a.jwt",
+			expectedErr: "Недействительный access-токен",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := c
+			if tt.token != "" {
+				ctx = suite.WithAccessToken(c, tt.token)
+			}
+
+			_, err := st.AuthClient.Logout(ctx, &ssov1.LogoutRequest{})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestRefresh_HappyPath(t *testing.T) {
+	c, st := suite.New(t)
+
+	respLogin := registerLogin(c, st)
+	require.NotEmpty(t, respLogin.GetRefreshToken())
+
+	respRefresh, err := st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: respLogin.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respRefresh.GetToken())
+	assert.NotEmpty(t, respRefresh.GetRefreshToken())
+	assert.NotEqual(t, respLogin.GetRefreshToken(), respRefresh.GetRefreshToken())
+
+	// новый токен тоже можно обменять
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: respRefresh.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+}
+
+func TestRefresh_ReuseRevokesFamily(t *testing.T) {
+	c, st := suite.New(t)
+
+	respLogin := registerLogin(c, st)
+
+	respRefresh, err := st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: respLogin.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+
+	// повторное предъявление обменянного токена
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: respLogin.GetRefreshToken(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// после этого отозвано все семейство, включая последний токен
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: respRefresh.GetRefreshToken(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestRefresh_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	tests := []struct {
+		name         string
+		refreshToken string
+		expectedErr  string
+	}{
+		{
+			name:         "Обновление без токена",
+			refreshToken: "",
+			expectedErr:  "refresh-токен не указан",
+		},
+		{
+			name:         "Обновление с несуществующим токеном",
+			refreshToken: gofakeit.UUID(),
+			expectedErr:  "Недействительный refresh-токен",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+				RefreshToken: tt.refreshToken,
+			})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
+
+// registerLogin регистрирует нового пользователя и входит под ним.
+func registerLogin(
+	c context.Context,
+	st *suite.Suite,
+) *ssov1.LoginResponse {
+	st.Helper()
+
+	email, password := registerUser(c, st)
+
+	return login(c, st, email, password)
+}
+
+// registerUser регистрирует нового пользователя
+// и возвращает его email и пароль.
+func registerUser(
+	c context.Context,
+	st *suite.Suite,
+) (email string, password string) {
+	st.Helper()
+
+	email = gofakeit.Email()
+	password = randomPassword()
+
+	_, err := st.AuthClient.Register(c, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(st, err)
+
+	return email, password
+}
+
+func login(
+	c context.Context,
+	st *suite.Suite,
+	email string,
+	password string,
+) *ssov1.LoginResponse {
+	st.Helper()
+
+	respLogin, err := st.AuthClient.Login(c, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(st, err)
+
+	return respLogin
+}
@@ -13,7 +13,6 @@ import (
 
 const (
 	appID         = 1
-	appSecret     = "test-secret"
 	pssDefaultLen = 10
 	emptyAppID    = 0
 )
@@ -43,11 +42,7 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 	token := respLogin.GetToken()
 	require.NotEmpty(t, token)
 
-	tokenParsed, err := jwt.Parse(
-		token, func(token *jwt.Token) (interface{}, error) {
-			return []byte(appSecret), nil
-		},
-	)
+	tokenParsed, err := jwt.Parse(token, jwksKeyFunc(c, st))
 	require.NoError(t, err)
 
 	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
@@ -70,7 +65,7 @@ func TestRegisterLogin_DuplicatedRegistration(t *testing.T) {
 	c, st := suite.New(t)
 
 	email := gofakeit.Email()
-	password := gofakeit.Email()
+	password := randomPassword()
 
 	respReg, err := st.AuthClient.Register(c, &ssov1.RegisterRequest{
 		Email:    email,
@@ -196,6 +191,9 @@ func TestLogin_FailCases(t *testing.T) {
 	}
 }
 
+// randomPassword возвращает случайный пароль, проходящий
+// политику паролей локального конфига: gofakeit не гарантирует
+// все классы символов, поэтому они добавляются явно.
 func randomPassword() string {
 	return gofakeit.Password(
 		true,
@@ -204,5 +202,5 @@ func randomPassword() string {
 		true,
 		false,
 		pssDefaultLen,
-	)
+	) + "aA1"
 }
@@ -0,0 +1,191 @@
+package tests
+
+import (
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestChangePassword_HappyPath(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	respLogin := login(c, st, email, password)
+	other := login(c, st, email, password)
+
+	newPassword := randomPassword()
+
+	resp, err := st.AuthClient.ChangePassword(
+		suite.WithAccessToken(c, respLogin.GetToken()),
+		&ssov1.ChangePasswordRequest{
+			OldPassword: password,
+			NewPassword: newPassword,
+		},
+	)
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetToken())
+
+	_, err = st.AuthClient.Login(c, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.Error(t, err)
+
+	login(c, st, email, newPassword)
+
+	// Без revoke_other_sessions остальные сеансы продолжаются.
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: other.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+}
+
+func TestChangePassword_RevokeOtherSessions(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	respLogin := login(c, st, email, password)
+	other := login(c, st, email, password)
+
+	resp, err := st.AuthClient.ChangePassword(
+		suite.WithAccessToken(c, respLogin.GetToken()),
+		&ssov1.ChangePasswordRequest{
+			OldPassword:         password,
+			NewPassword:         randomPassword(),
+			RevokeOtherSessions: true,
+		},
+	)
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.GetToken())
+	require.NotEmpty(t, resp.GetRefreshToken())
+
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: other.GetRefreshToken(),
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Текущий сеанс продолжается с новыми токенами.
+	info, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: resp.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.True(t, info.GetActive())
+
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: resp.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+}
+
+func TestChangePassword_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	authCtx := suite.WithAccessToken(c, login(c, st, email, password).GetToken())
+
+	tests := []struct {
+		name         string
+		oldPassword  string
+		newPassword  string
+		expectedCode codes.Code
+		expectedErr  string
+	}{
+		{
+			name:         "Без текущего пароля",
+			oldPassword:  "",
+			newPassword:  randomPassword(),
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "текущий пароль не указан",
+		},
+		{
+			name:         "Без нового пароля",
+			oldPassword:  password,
+			newPassword:  "",
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "новый пароль не указан",
+		},
+		{
+			name:         "Неверный текущий пароль",
+			oldPassword:  randomPassword(),
+			newPassword:  randomPassword(),
+			expectedCode: codes.InvalidArgument,
+			expectedErr:  "Неправильный текущий пароль",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.ChangePassword(
+				authCtx,
+				&ssov1.ChangePasswordRequest{
+					OldPassword: tt.oldPassword,
+					NewPassword: tt.newPassword,
+				},
+			)
+			require.Error(t, err)
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			require.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+
+	t.Run("Без access-токена", func(t *testing.T) {
+		_, err := st.AuthClient.ChangePassword(c, &ssov1.ChangePasswordRequest{
+			OldPassword: password,
+			NewPassword: randomPassword(),
+		})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("По токену API-ключа", func(t *testing.T) {
+		key := createAPIKey(authCtx, st).GetKey()
+
+		exchanged, err := st.AuthClient.ExchangeAPIKey(c, &ssov1.ExchangeAPIKeyRequest{
+			ApiKey: key,
+		})
+		require.NoError(t, err)
+
+		_, err = st.AuthClient.ChangePassword(
+			suite.WithAccessToken(c, exchanged.GetToken()),
+			&ssov1.ChangePasswordRequest{
+				OldPassword: password,
+				NewPassword: randomPassword(),
+			},
+		)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestChangePassword_WrongOldPasswordLockout(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	authCtx := suite.WithAccessToken(c, login(c, st, email, password).GetToken())
+
+	for range st.Cfg.Lockout.UserDelayAfter {
+		_, err := st.AuthClient.ChangePassword(authCtx, &ssov1.ChangePasswordRequest{
+			OldPassword: randomPassword(),
+			NewPassword: randomPassword(),
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	// Подбор текущего пароля ограничен так же, как вход.
+	_, err := st.AuthClient.ChangePassword(authCtx, &ssov1.ChangePasswordRequest{
+		OldPassword: password,
+		NewPassword: randomPassword(),
+	})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Positive(t, retryDelay(st, err))
+
+	_, err = st.AuthClient.Login(c, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const (
+	serviceClientID     = "svc-test"
+	serviceClientSecret = "test-service-secret"
+)
+
+func TestClientCredentials_HappyPath(t *testing.T) {
+	c, st := suite.New(t)
+
+	resp, err := st.AuthClient.ClientCredentials(c, &ssov1.ClientCredentialsRequest{
+		ClientId:     serviceClientID,
+		ClientSecret: serviceClientSecret,
+		Scope:        "orders:read",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "orders:read", resp.GetScope())
+	assert.Positive(t, resp.GetExpiresIn())
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(resp.GetToken(), claims, jwksKeyFunc(c, st))
+	require.NoError(t, err)
+	assert.Equal(t, "service:"+serviceClientID, claims["sub"])
+	assert.Equal(t, "orders:read", claims["scope"])
+
+	info, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: resp.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.True(t, info.GetActive())
+	assert.Equal(t, "service:"+serviceClientID, info.GetSub())
+	assert.Equal(t, serviceClientID, info.GetClientId())
+	assert.Equal(t, "orders:read", info.GetScope())
+	assert.Equal(t, int32(appID), info.GetAppId())
+	assert.Empty(t, info.GetUid())
+}
+
+func TestClientCredentials_AllScopesByDefault(t *testing.T) {
+	c, st := suite.New(t)
+
+	resp, err := st.AuthClient.ClientCredentials(c, &ssov1.ClientCredentialsRequest{
+		ClientId:     serviceClientID,
+		ClientSecret: serviceClientSecret,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "orders:read orders:write", resp.GetScope())
+}
+
+func TestClientCredentials_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	tests := []struct {
+		name     string
+		clientID string
+		secret   string
+		scope    string
+		code     codes.Code
+	}{
+		{
+			name:     "Без client id",
+			clientID: "",
+			secret:   serviceClientSecret,
+			code:     codes.InvalidArgument,
+		},
+		{
+			name:     "Неверный секрет",
+			clientID: serviceClientID,
+			secret:   "wrong-secret",
+			code:     codes.Unauthenticated,
+		},
+		{
+			name:     "Неизвестный аккаунт",
+			clientID: "svc-unknown",
+			secret:   serviceClientSecret,
+			code:     codes.Unauthenticated,
+		},
+		{
+			name:     "Неразрешенная область",
+			clientID: serviceClientID,
+			secret:   serviceClientSecret,
+			scope:    "orders:read admin",
+			code:     codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.ClientCredentials(
+				c,
+				&ssov1.ClientCredentialsRequest{
+					ClientId:     tt.clientID,
+					ClientSecret: tt.secret,
+					Scope:        tt.scope,
+				},
+			)
+			require.Error(t, err)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestClientCredentials_HTTP(t *testing.T) {
+	c, st := suite.New(t)
+
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+		"scope":      {"orders:write"},
+	}
+
+	body, status := clientCredentials(c, st, form, serviceClientSecret)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.Equal(t, "orders:write", body["scope"])
+	assert.NotEmpty(t, body["access_token"])
+	assert.NotContains(t, body, "refresh_token")
+
+	body, status = clientCredentials(c, st, form, "wrong-secret")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "invalid_client", body["error"])
+}
+
+// clientCredentials запрашивает токен сервисного аккаунта на /token,
+// передавая учетные данные через HTTP Basic.
+func clientCredentials(
+	c context.Context,
+	st *suite.Suite,
+	form url.Values,
+	secret string,
+) (map[string]any, int) {
+	st.Helper()
+
+	req, err := http.NewRequestWithContext(
+		c,
+		http.MethodPost,
+		st.HTTPURL("/token"),
+		strings.NewReader(form.Encode()),
+	)
+	require.NoError(st, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(serviceClientID, secret)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(st, err)
+	defer resp.Body.Close()
+
+	var body map[string]any
+	require.NoError(st, json.NewDecoder(resp.Body).Decode(&body))
+
+	return body, resp.StatusCode
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+// verifiedAppID приложение, которое пускает только
+// пользователей с подтвержденным email.
+const verifiedAppID = 2
+
+func TestEmailVerification_HappyPath(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+
+	// Без подтверждения в приложение с требованием не пускают.
+	_, err := st.AuthClient.Login(c, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    verifiedAppID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// А в обычное пускают, но с email_verified = false.
+	respLogin := login(c, st, email, password)
+	assert.False(t, emailVerifiedClaim(st, respLogin.GetToken()))
+
+	token := outboxToken(st, email)
+
+	_, err = st.AuthClient.VerifyEmail(c, &ssov1.VerifyEmailRequest{
+		Token: token,
+	})
+	require.NoError(t, err)
+
+	respLogin, err = st.AuthClient.Login(c, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    verifiedAppID,
+	})
+	require.NoError(t, err)
+	assert.True(t, emailVerifiedClaim(st, respLogin.GetToken()))
+
+	info, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: respLogin.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.True(t, info.GetEmailVerified())
+
+	// Токен одноразовый.
+	_, err = st.AuthClient.VerifyEmail(c, &ssov1.VerifyEmailRequest{
+		Token: token,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestEmailVerification_Resend(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, _ := registerUser(c, st)
+	require.Len(t, st.Outbox(email), 1)
+
+	_, err := st.AuthClient.ResendVerification(c, &ssov1.ResendVerificationRequest{
+		Email: email,
+	})
+	require.NoError(t, err)
+	require.Len(t, st.Outbox(email), 2)
+
+	_, err = st.AuthClient.VerifyEmail(c, &ssov1.VerifyEmailRequest{
+		Token: outboxToken(st, email),
+	})
+	require.NoError(t, err)
+
+	// Для подтвержденного и неизвестного email писем нет,
+	// но ответ тот же.
+	_, err = st.AuthClient.ResendVerification(c, &ssov1.ResendVerificationRequest{
+		Email: email,
+	})
+	require.NoError(t, err)
+	assert.Len(t, st.Outbox(email), 2)
+
+	unknown := gofakeit.Email()
+	_, err = st.AuthClient.ResendVerification(c, &ssov1.ResendVerificationRequest{
+		Email: unknown,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, st.Outbox(unknown))
+}
+
+func TestEmailVerification_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr string
+	}{
+		{
+			name:        "Без токена",
+			token:       "",
+			expectedErr: "токен не указан",
+		},
+		{
+			name:        "Неизвестный токен",
+			token:       "token",
+			expectedErr: "недействительна",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.VerifyEmail(c, &ssov1.VerifyEmailRequest{
+				Token: tt.token,
+			})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
+
+func emailVerifiedClaim(st *suite.Suite, token string) bool {
+	st.Helper()
+
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(st, err)
+
+	verified, ok := claims["email_verified"].(bool)
+	require.True(st, ok)
+
+	return verified
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/h1lton/sso-grpc-ntc/internal/notifier"
+	"github.com/h1lton/sso-grpc-ntc/internal/passwordhash"
+	"github.com/h1lton/sso-grpc-ntc/internal/services/auth"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage/sqlite"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLogin_UnknownEmailTiming(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, _ := registerUser(c, st)
+
+	// Меньше порога задержки, чтобы все попытки доходили до пароля.
+	attempts := st.Cfg.Lockout.UserDelayAfter - 1
+	require.Positive(t, attempts)
+
+	fastest := func(email string) time.Duration {
+		var best time.Duration
+		for i := range attempts {
+			start := time.Now()
+			_, err := st.AuthClient.Login(c, &ssov1.LoginRequest{
+				Email:    email,
+				Password: randomPassword(),
+				AppId:    appID,
+			})
+			elapsed := time.Since(start)
+
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+			if i == 0 || elapsed < best {
+				best = elapsed
+			}
+		}
+
+		return best
+	}
+
+	wrongPassword := fastest(email)
+	unknownEmail := fastest(gofakeit.Email())
+
+	// Без сверки с заглушкой ответ для неизвестного email
+	// приходил бы на время хэширования пароля быстрее.
+	assert.Greater(t, unknownEmail, wrongPassword/2)
+}
+
+func TestLogin_UnknownEmailLockout(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, _ := registerUser(c, st)
+
+	// Ответы на неудачные входы с зарегистрированным и неизвестным
+	// email совпадают и после порога задержки.
+	codesFor := func(email string) []codes.Code {
+		var got []codes.Code
+		for range st.Cfg.Lockout.UserDelayAfter + 1 {
+			_, err := st.AuthClient.Login(c, &ssov1.LoginRequest{
+				Email:    email,
+				Password: randomPassword(),
+				AppId:    appID,
+			})
+			got = append(got, status.Code(err))
+		}
+
+		return got
+	}
+
+	known := codesFor(email)
+	assert.Equal(t, codes.ResourceExhausted, known[len(known)-1])
+	assert.Equal(t, known, codesFor(gofakeit.Email()))
+}
+
+func TestRegister_EnumerationSafe(t *testing.T) {
+	t.Parallel()
+
+	c := context.Background()
+	mail := &mailbox{}
+	a := newEnumerationSafeAuth(t, mail)
+
+	email, password := gofakeit.Email(), randomPassword()
+
+	// Ни новая регистрация, ни повторная не раскрывают user_id
+	// и не отличаются по ответу.
+	id, err := a.Register(c, email, password, 0)
+	require.NoError(t, err)
+	assert.Zero(t, id)
+
+	id, err = a.Register(c, email, randomPassword(), 0)
+	require.NoError(t, err)
+	assert.Zero(t, id)
+
+	// О повторной попытке узнает только владелец адреса.
+	subjects := mail.subjects(email)
+	require.Len(t, subjects, 2)
+	assert.Equal(t, "Попытка регистрации", subjects[1])
+}
+
+// newEnumerationSafeAuth создает сервис с enumeration_safe регистрацией
+// поверх отдельной базы, чтобы не менять конфиг общего сервера.
+func newEnumerationSafeAuth(t *testing.T, mail auth.Notifier) *auth.Auth {
+	t.Helper()
+
+	storagePath := filepath.Join(t.TempDir(), "sso.db")
+
+	m, err := migrate.New(
+		"file://../migrations",
+		fmt.Sprintf("sqlite3://%s?x-migrations-table=migrations", storagePath),
+	)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+
+	srcErr, dbErr := m.Close()
+	require.NoError(t, srcErr)
+	require.NoError(t, dbErr)
+
+	storage, err := sqlite.New(storagePath)
+	require.NoError(t, err)
+
+	hasher, err := passwordhash.New(passwordhash.Config{
+		Algorithm:  passwordhash.Bcrypt,
+		BcryptCost: 4,
+	})
+	require.NoError(t, err)
+
+	return auth.New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		storage,
+		mail,
+		hasher,
+		nil,
+		auth.Config{
+			EmailVerificationTTL:        time.Hour,
+			EnumerationSafeRegistration: true,
+		},
+	)
+}
+
+// mailbox запоминает отправленные письма.
+type mailbox struct {
+	mu       sync.Mutex
+	messages []notifier.Message
+}
+
+func (m *mailbox) Send(_ context.Context, msg notifier.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, msg)
+
+	return nil
+}
+
+// subjects возвращает темы писем на адрес to в порядке отправки.
+func (m *mailbox) subjects(to string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var subjects []string
+	for _, msg := range m.messages {
+		if msg.To == to {
+			subjects = append(subjects, msg.Subject)
+		}
+	}
+
+	return subjects
+}
@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"context"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestGroups_NestedRoles(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+
+	respRole, err := st.AuthClient.CreateRole(adminCtx, &ssov1.CreateRoleRequest{
+		AppId:       appID,
+		Name:        "reporter-" + gofakeit.UUID(),
+		Permissions: []string{"reports:read"},
+	})
+	require.NoError(t, err)
+	role := respRole.GetRole()
+
+	outer := createGroup(adminCtx, st, 0)
+	inner := createGroup(adminCtx, st, 0)
+
+	email, password := registerUser(c, st)
+	uid := introspectUserID(c, st, login(c, st, email, password).GetToken())
+
+	_, err = st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+		GroupId:      outer.GetId(),
+		ChildGroupId: inner.GetId(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+		GroupId: inner.GetId(),
+		UserId:  uid,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.AssignGroupRole(adminCtx, &ssov1.AssignGroupRoleRequest{
+		GroupId: outer.GetId(),
+		RoleId:  role.GetId(),
+	})
+	require.NoError(t, err)
+
+	// Роль внешней группы действует через вложенную.
+	assert.True(t, checkPermission(c, st, uid, appID, "reports:read"))
+	assert.False(t, checkPermission(c, st, uid, verifiedAppID, "reports:read"))
+
+	token := login(c, st, email, password).GetToken()
+
+	claims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+	assert.Equal(t, []any{role.GetName()}, claims["roles"])
+	assert.ElementsMatch(t, []any{outer.GetName(), inner.GetName()}, claims["groups"])
+
+	info, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{Token: token})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{outer.GetName(), inner.GetName()}, info.GetGroups())
+
+	respList, err := st.AuthClient.ListGroups(adminCtx, &ssov1.ListGroupsRequest{})
+	require.NoError(t, err)
+
+	var names []string
+	for _, g := range respList.GetGroups() {
+		names = append(names, g.GetName())
+	}
+	assert.Contains(t, names, outer.GetName())
+	assert.Contains(t, names, inner.GetName())
+
+	_, err = st.AuthClient.RemoveGroupMember(adminCtx, &ssov1.RemoveGroupMemberRequest{
+		GroupId:      outer.GetId(),
+		ChildGroupId: inner.GetId(),
+	})
+	require.NoError(t, err)
+
+	assert.False(t, checkPermission(c, st, uid, appID, "reports:read"))
+
+	_, err = st.AuthClient.DeleteGroup(adminCtx, &ssov1.DeleteGroupRequest{
+		Id: inner.GetId(),
+	})
+	require.NoError(t, err)
+
+	info, err = st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{Token: token})
+	require.NoError(t, err)
+	assert.Empty(t, info.GetGroups())
+}
+
+func TestGroups_Cycle(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+
+	top := createGroup(adminCtx, st, 0)
+	middle := createGroup(adminCtx, st, 0)
+	bottom := createGroup(adminCtx, st, 0)
+
+	for _, edge := range [][2]*ssov1.Group{{top, middle}, {middle, bottom}} {
+		_, err := st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+			GroupId:      edge[0].GetId(),
+			ChildGroupId: edge[1].GetId(),
+		})
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name   string
+		parent *ssov1.Group
+		child  *ssov1.Group
+	}{
+		{name: "В саму себя", parent: top, child: top},
+		{name: "Прямой цикл", parent: middle, child: top},
+		{name: "Через вложенную группу", parent: bottom, child: top},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+				GroupId:      tt.parent.GetId(),
+				ChildGroupId: tt.child.GetId(),
+			})
+			assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		})
+	}
+}
+
+func TestGroups_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+	userCtx := suite.WithAccessToken(c, registerLogin(c, st).GetToken())
+
+	group := createGroup(adminCtx, st, 0)
+
+	t.Run("Не администратор", func(t *testing.T) {
+		_, err := st.AuthClient.CreateGroup(userCtx, &ssov1.CreateGroupRequest{
+			Name: "group-" + gofakeit.UUID(),
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Повторное имя", func(t *testing.T) {
+		_, err := st.AuthClient.CreateGroup(adminCtx, &ssov1.CreateGroupRequest{
+			Name: group.GetName(),
+		})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("Пользователь и группа одновременно", func(t *testing.T) {
+		_, err := st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+			GroupId:      group.GetId(),
+			UserId:       1,
+			ChildGroupId: group.GetId(),
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Пользователь другой организации", func(t *testing.T) {
+		resp, err := st.AuthClient.Register(c, &ssov1.RegisterRequest{
+			Email:    gofakeit.Email(),
+			Password: randomPassword(),
+			AppId:    tenantAppID,
+		})
+		require.NoError(t, err)
+
+		_, err = st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+			GroupId: group.GetId(),
+			UserId:  resp.GetUserId(),
+		})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Группа другой организации", func(t *testing.T) {
+		other := createGroup(adminCtx, st, tenantID)
+
+		_, err := st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+			GroupId:      group.GetId(),
+			ChildGroupId: other.GetId(),
+		})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Удаление не участника", func(t *testing.T) {
+		_, err := st.AuthClient.RemoveGroupMember(adminCtx, &ssov1.RemoveGroupMemberRequest{
+			GroupId: group.GetId(),
+			UserId:  1 << 40,
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Несуществующая группа", func(t *testing.T) {
+		_, err := st.AuthClient.DeleteGroup(adminCtx, &ssov1.DeleteGroupRequest{
+			Id: 1 << 40,
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+// createGroup создает группу со случайным именем
+// в организации tenantID (0 — по умолчанию).
+func createGroup(
+	adminCtx context.Context,
+	st *suite.Suite,
+	tenantID int64,
+) *ssov1.Group {
+	st.Helper()
+
+	resp, err := st.AuthClient.CreateGroup(adminCtx, &ssov1.CreateGroupRequest{
+		TenantId: tenantID,
+		Name:     "group-" + gofakeit.UUID(),
+	})
+	require.NoError(st, err)
+
+	return resp.GetGroup()
+}
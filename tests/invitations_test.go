@@ -0,0 +1,333 @@
+package tests
+
+import (
+	"context"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"testing"
+)
+
+func TestInvitations_NewUser(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+	role := createRole(adminCtx, st, "invites:read")
+
+	email := gofakeit.Email()
+	inv := createInvitation(adminCtx, st, email, role.GetId())
+	assert.Equal(t, email, inv.GetEmail())
+	assert.Equal(t, int32(appID), inv.GetAppId())
+	assert.Greater(t, inv.GetExpiresAt(), inv.GetCreatedAt())
+
+	token := outboxToken(st, email)
+	password := randomPassword()
+
+	resp, err := st.AuthClient.AcceptInvitation(c, &ssov1.AcceptInvitationRequest{
+		Token:    token,
+		Password: password,
+	})
+	require.NoError(t, err)
+	uid := resp.GetUserId()
+	assert.NotEmpty(t, uid)
+
+	// Адрес подтвержден самим приглашением.
+	loginToken := login(c, st, email, password).GetToken()
+	assert.Equal(t, uid, introspectUserID(c, st, loginToken))
+	assert.True(t, emailVerifiedClaim(st, loginToken))
+	assert.True(t, checkPermission(c, st, uid, appID, "invites:read"))
+
+	// Приглашение одноразовое.
+	_, err = st.AuthClient.AcceptInvitation(c, &ssov1.AcceptInvitationRequest{
+		Token:    token,
+		Password: password,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	respList, err := st.AuthClient.ListInvitations(adminCtx, &ssov1.ListInvitationsRequest{
+		AppId: appID,
+	})
+	require.NoError(t, err)
+
+	var accepted *ssov1.Invitation
+	for _, i := range respList.GetInvitations() {
+		if i.GetId() == inv.GetId() {
+			accepted = i
+		}
+	}
+	require.NotNil(t, accepted)
+	assert.Equal(t, uid, accepted.GetAcceptedBy())
+	assert.NotEmpty(t, accepted.GetAcceptedAt())
+}
+
+// Новый пользователь по приглашению создается так же, как в Register:
+// пароль проверяется той же политикой, а отклоненный пароль
+// не расходует приглашение.
+func TestInvitations_NewUserPasswordPolicy(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+
+	email := gofakeit.Email()
+	createInvitation(adminCtx, st, email, 0)
+	token := outboxToken(st, email)
+
+	_, err := st.AuthClient.AcceptInvitation(c, &ssov1.AcceptInvitationRequest{
+		Token:    token,
+		Password: "onlylowercaseletters",
+	})
+	require.Error(t, err)
+
+	field, rules := policyViolations(st, err)
+	assert.Equal(t, "password", field)
+	assert.Equal(t, []string{"upper", "digit"}, rules)
+
+	password := randomPassword()
+	resp, err := st.AuthClient.AcceptInvitation(c, &ssov1.AcceptInvitationRequest{
+		Token:    token,
+		Password: password,
+	})
+	require.NoError(t, err)
+	loginToken := login(c, st, email, password).GetToken()
+	assert.Equal(t, resp.GetUserId(), introspectUserID(c, st, loginToken))
+}
+
+func TestInvitations_ExistingUser(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+	role := createRole(adminCtx, st, "invites:write")
+
+	email, password := registerUser(c, st)
+	uid := introspectUserID(c, st, login(c, st, email, password).GetToken())
+
+	createInvitation(adminCtx, st, email, role.GetId())
+	token := outboxToken(st, email)
+
+	_, err := st.AuthClient.AcceptInvitation(c, &ssov1.AcceptInvitationRequest{
+		Token:    token,
+		Password: randomPassword(),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.False(t, checkPermission(c, st, uid, appID, "invites:write"))
+
+	resp, err := st.AuthClient.AcceptInvitation(c, &ssov1.AcceptInvitationRequest{
+		Token:    token,
+		Password: password,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uid, resp.GetUserId())
+	assert.True(t, checkPermission(c, st, uid, appID, "invites:write"))
+}
+
+func TestInvitations_Revoke(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+
+	email := gofakeit.Email()
+	inv := createInvitation(adminCtx, st, email, 0)
+	token := outboxToken(st, email)
+
+	_, err := st.AuthClient.RevokeInvitation(adminCtx, &ssov1.RevokeInvitationRequest{
+		Id: inv.GetId(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.AcceptInvitation(c, &ssov1.AcceptInvitationRequest{
+		Token:    token,
+		Password: randomPassword(),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Отозванное приглашение повторно не отзывается.
+	_, err = st.AuthClient.RevokeInvitation(adminCtx, &ssov1.RevokeInvitationRequest{
+		Id: inv.GetId(),
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestInvitations_ConcurrentAccept(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+
+	email := gofakeit.Email()
+	createInvitation(adminCtx, st, email, 0)
+	token := outboxToken(st, email)
+
+	const n = 5
+
+	passwords := make([]string, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := range n {
+		passwords[i] = randomPassword()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, errs[i] = st.AuthClient.AcceptInvitation(c, &ssov1.AcceptInvitationRequest{
+				Token:    token,
+				Password: passwords[i],
+			})
+		}()
+	}
+	wg.Wait()
+
+	// Принимает ровно один запрос, и учетная запись остается
+	// только с его паролем.
+	var accepted []string
+	for i, err := range errs {
+		if err == nil {
+			accepted = append(accepted, passwords[i])
+
+			continue
+		}
+
+		assert.Contains(t, []codes.Code{
+			codes.InvalidArgument,
+			codes.AlreadyExists,
+		}, status.Code(err))
+	}
+	require.Len(t, accepted, 1)
+
+	login(c, st, email, accepted[0])
+}
+
+func TestInvitations_RoleDeleted(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+	role := createRole(adminCtx, st, "invites:deleted")
+
+	email := gofakeit.Email()
+	inv := createInvitation(adminCtx, st, email, role.GetId())
+
+	_, err := st.AuthClient.DeleteRole(adminCtx, &ssov1.DeleteRoleRequest{
+		Id: role.GetId(),
+	})
+	require.NoError(t, err)
+
+	// Приглашение больше не ссылается на удаленную роль: ее id
+	// может достаться новой роли.
+	respList, err := st.AuthClient.ListInvitations(adminCtx, &ssov1.ListInvitationsRequest{
+		AppId: appID,
+	})
+	require.NoError(t, err)
+
+	var found bool
+	for _, i := range respList.GetInvitations() {
+		if i.GetId() == inv.GetId() {
+			found = true
+			assert.Empty(t, i.GetRoleId())
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestInvitations_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+	userCtx := suite.WithAccessToken(c, registerLogin(c, st).GetToken())
+
+	t.Run("Не администратор", func(t *testing.T) {
+		_, err := st.AuthClient.CreateInvitation(userCtx, &ssov1.CreateInvitationRequest{
+			Email: gofakeit.Email(),
+			AppId: appID,
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Без приложения", func(t *testing.T) {
+		_, err := st.AuthClient.CreateInvitation(adminCtx, &ssov1.CreateInvitationRequest{
+			Email: gofakeit.Email(),
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Роль другого приложения", func(t *testing.T) {
+		respRole, err := st.AuthClient.CreateRole(adminCtx, &ssov1.CreateRoleRequest{
+			AppId: verifiedAppID,
+			Name:  "role-" + gofakeit.UUID(),
+		})
+		require.NoError(t, err)
+
+		_, err = st.AuthClient.CreateInvitation(adminCtx, &ssov1.CreateInvitationRequest{
+			Email:  gofakeit.Email(),
+			RoleId: respRole.GetRole().GetId(),
+			AppId:  appID,
+		})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Несуществующая роль", func(t *testing.T) {
+		_, err := st.AuthClient.CreateInvitation(adminCtx, &ssov1.CreateInvitationRequest{
+			Email:  gofakeit.Email(),
+			RoleId: 1 << 40,
+			AppId:  appID,
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Неизвестный токен", func(t *testing.T) {
+		_, err := st.AuthClient.AcceptInvitation(c, &ssov1.AcceptInvitationRequest{
+			Token:    gofakeit.UUID(),
+			Password: randomPassword(),
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Несуществующее приглашение", func(t *testing.T) {
+		_, err := st.AuthClient.RevokeInvitation(adminCtx, &ssov1.RevokeInvitationRequest{
+			Id: 1 << 40,
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+// createInvitation приглашает email в тестовое приложение
+// с ролью roleID (0 — без роли).
+func createInvitation(
+	adminCtx context.Context,
+	st *suite.Suite,
+	email string,
+	roleID int64,
+) *ssov1.Invitation {
+	st.Helper()
+
+	resp, err := st.AuthClient.CreateInvitation(adminCtx, &ssov1.CreateInvitationRequest{
+		Email:  email,
+		RoleId: roleID,
+		AppId:  appID,
+	})
+	require.NoError(st, err)
+
+	return resp.GetInvitation()
+}
+
+// createRole создает роль тестового приложения с разрешением permission.
+func createRole(
+	adminCtx context.Context,
+	st *suite.Suite,
+	permission string,
+) *ssov1.Role {
+	st.Helper()
+
+	resp, err := st.AuthClient.CreateRole(adminCtx, &ssov1.CreateRoleRequest{
+		AppId:       appID,
+		Name:        "role-" + gofakeit.UUID(),
+		Permissions: []string{permission},
+	})
+	require.NoError(st, err)
+
+	return resp.GetRole()
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	ssojwt "github.com/h1lton/sso-grpc-ntc/internal/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyRing_RotateAppKey(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	now := time.Now()
+	app := models.App{ID: 5, Secret: "secret"}
+
+	var m ssojwt.Manifest
+
+	// В пустой связке подписывать нечем, поэтому ключ сразу активен.
+	defaultKey, err := m.Generate(path, ssojwt.AlgES256, 0, now)
+	require.NoError(t, err)
+	assert.Equal(t, ssojwt.StateActive, defaultKey.State)
+
+	appKey, err := m.Generate(path, ssojwt.AlgES256, app.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, ssojwt.StateNext, appKey.State)
+
+	// Пока ключ приложения следующий, токены подписывает ключ по умолчанию.
+	keys, err := m.KeySet(path, now)
+	require.NoError(t, err)
+
+	oldToken := newKeyRingToken(t, app, keys)
+	assert.Equal(t, defaultKey.ID, tokenKid(t, oldToken))
+
+	require.NoError(t, m.Promote(app.ID, now, time.Hour))
+
+	keys, err = m.KeySet(path, now)
+	require.NoError(t, err)
+
+	newToken := newKeyRingToken(t, app, keys)
+	assert.Equal(t, appKey.ID, tokenKid(t, newToken))
+
+	for _, token := range []string{oldToken, newToken} {
+		_, err = ssojwt.Parse(token, keys, func(int) (string, error) {
+			return app.Secret, nil
+		})
+		assert.NoError(t, err)
+	}
+}
+
+func TestKeyRing_FirstAppKeyActive(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	now := time.Now()
+	app := models.App{ID: 5, Secret: "secret"}
+
+	var m ssojwt.Manifest
+
+	// Без ключа по умолчанию первый ключ приложения сразу активен.
+	key, err := m.Generate(path, ssojwt.AlgEdDSA, app.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, ssojwt.StateActive, key.State)
+
+	keys, err := m.KeySet(path, now)
+	require.NoError(t, err)
+
+	assert.Equal(t, key.ID, tokenKid(t, newKeyRingToken(t, app, keys)))
+}
+
+// newKeyRingToken выпускает access-токен приложения app ключами keys.
+func newKeyRingToken(t *testing.T, app models.App, keys *ssojwt.KeySet) string {
+	t.Helper()
+
+	token, err := ssojwt.NewToken(
+		models.User{ID: 1, Email: "user@example.com"},
+		app,
+		"",
+		0,
+		models.Access{},
+		time.Hour,
+		keys,
+		"",
+	)
+	require.NoError(t, err)
+
+	return token
+}
+
+// tokenKid возвращает kid из заголовка токена, не проверяя подпись.
+func tokenKid(t *testing.T, token string) string {
+	t.Helper()
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	require.NoError(t, err)
+
+	kid, _ := parsed.Header["kid"].(string)
+
+	return kid
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"context"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/pkg/totp"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Администратор из тестовых миграций.
+const (
+	adminEmail      = "admin@example.com"
+	adminPassword   = "AdminPassw0rd"
+	adminTOTPSecret = "JBSWY3DPEHPK3PXP"
+)
+
+func TestLockout_BackoffThenLock(t *testing.T) {
+	c, st := suite.New(t)
+
+	email := gofakeit.Email()
+	password := randomPassword()
+
+	respReg, err := st.AuthClient.Register(c, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	loginWith := func(password string) error {
+		_, err := st.AuthClient.Login(c, &ssov1.LoginRequest{
+			Email:    email,
+			Password: password,
+			AppId:    appID,
+		})
+
+		return err
+	}
+
+	// Первые неудачи — просто неверный пароль.
+	for range st.Cfg.Lockout.UserDelayAfter {
+		err = loginWith(randomPassword())
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	// Дальше вход откладывается, даже с верным паролем.
+	err = loginWith(password)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	delay := retryDelay(st, err)
+	assert.Positive(t, delay)
+
+	time.Sleep(delay)
+
+	// Еще одна неудача блокирует вход.
+	err = loginWith(randomPassword())
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	err = loginWith(password)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Greater(t, retryDelay(st, err), st.Cfg.Lockout.MaxDelay)
+
+	_, err = st.AuthClient.UnlockAccount(
+		suite.WithAccessToken(c, adminToken(c, st)),
+		&ssov1.UnlockAccountRequest{UserId: respReg.GetUserId()},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, loginWith(password))
+}
+
+func TestUnlockAccount_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	respLogin := registerLogin(c, st)
+
+	t.Run("Не администратор", func(t *testing.T) {
+		_, err := st.AuthClient.UnlockAccount(
+			suite.WithAccessToken(c, respLogin.GetToken()),
+			&ssov1.UnlockAccountRequest{UserId: 1},
+		)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Без пользователя и адреса", func(t *testing.T) {
+		_, err := st.AuthClient.UnlockAccount(
+			suite.WithAccessToken(c, adminToken(c, st)),
+			&ssov1.UnlockAccountRequest{},
+		)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "не указан user id или ip")
+	})
+}
+
+func retryDelay(st *suite.Suite, err error) time.Duration {
+	st.Helper()
+
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			return info.GetRetryDelay().AsDuration()
+		}
+	}
+
+	st.Fatalf("в ошибке нет RetryInfo: %v", err)
+
+	return 0
+}
+
+var admin struct {
+	sync.Mutex
+	token string
+}
+
+// adminToken возвращает access-токен администратора из тестовых миграций.
+//
+// Код TOTP нельзя использовать дважды, поэтому токен выпускается
+// один раз на весь запуск тестов.
+func adminToken(c context.Context, st *suite.Suite) string {
+	st.Helper()
+
+	admin.Lock()
+	defer admin.Unlock()
+
+	if admin.token != "" {
+		return admin.token
+	}
+
+	respLogin := login(c, st, adminEmail, adminPassword)
+	require.NotEmpty(st, respLogin.GetSecondFactorChallenge())
+
+	// Код текущего шага мог уже войти в предыдущем запуске,
+	// тогда подходит код следующего.
+	step := totp.Step(time.Now())
+	for _, s := range []int64{step, step + 1} {
+		resp, err := st.AuthClient.VerifySecondFactor(
+			c,
+			&ssov1.VerifySecondFactorRequest{
+				Challenge: respLogin.GetSecondFactorChallenge(),
+				Code:      totpCode(st.T, adminTOTPSecret, s),
+			},
+		)
+		if err == nil {
+			admin.token = resp.GetToken()
+
+			return admin.token
+		}
+	}
+
+	st.Fatalf("не удалось войти администратором")
+
+	return ""
+}
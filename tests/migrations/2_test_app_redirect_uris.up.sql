@@ -0,0 +1,3 @@
+UPDATE apps
+SET redirect_uris = '["http://localhost:3000/callback"]'
+WHERE id = 1;
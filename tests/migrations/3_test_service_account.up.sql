@@ -0,0 +1,6 @@
+-- Секрет: test-service-secret.
+INSERT INTO service_accounts (app_id, name, client_id, secret_hash, scopes, created_at)
+VALUES (1, 'test', 'svc-test',
+        X'c70f0cd6fb784e1acaa41f7630a7569441074bf63957ad25bfa76408d53dcad5',
+        '["orders:read","orders:write"]', 0)
+ON CONFLICT DO NOTHING;
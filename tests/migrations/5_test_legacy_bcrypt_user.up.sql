@@ -0,0 +1,6 @@
+-- Пароль: LegacyPassw0rd, bcrypt со стоимостью 4 — слабее конфига.
+-- Хэш хранится как BLOB, как его записывает сервис.
+INSERT INTO users (email, pass_hash)
+VALUES ('legacy-bcrypt@example.com',
+        CAST('$2a$04$nAJ7XfNDczo/mfJcOFJdC.IdeVEk9raeuigA4SzSEyVfX2iUxPHm.' AS BLOB))
+ON CONFLICT DO NOTHING;
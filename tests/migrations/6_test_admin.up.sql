@@ -0,0 +1,18 @@
+-- Администратор с включенным вторым фактором.
+-- Пароль: AdminPassw0rd, секрет TOTP: JBSWY3DPEHPK3PXP.
+INSERT INTO users (email, pass_hash, email_verified)
+VALUES ('admin@example.com',
+        CAST('$2a$04$RHlU3LMAVVB9pjFfMZ0yLu/eDBR2yatvq5cabvg3/xyZl4Y0di22i' AS BLOB),
+        TRUE)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO user_roles (user_id, role_id)
+SELECT users.id, roles.id FROM users, roles
+WHERE users.email = 'admin@example.com'
+  AND roles.app_id IS NULL AND roles.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO user_totp (user_id, secret, confirmed_at)
+SELECT id, 'JBSWY3DPEHPK3PXP', 1
+FROM users WHERE email = 'admin@example.com'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,8 @@
+-- Вторая организация со своим приложением.
+INSERT INTO tenants (id, name, created_at)
+VALUES (2, 'test-tenant', 0)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO apps (id, name, secret, tenant_id)
+VALUES (3, 'test-tenant-app', 'test-tenant-secret', 2)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- Приложение, бюджет вызовов которого исчерпывает тест лимитов.
+INSERT INTO apps (id, name, secret)
+VALUES (4, 'test-rate-limited', 'test-rate-limited-secret')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,338 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const redirectURI = "http://localhost:3000/callback"
+
+// noRedirectClient не следует перенаправлениям,
+// чтобы тест мог прочитать code из Location.
+var noRedirectClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func TestOAuth_AuthorizationCode_HappyPath(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	verifier := gofakeit.LetterN(64)
+
+	resp := get(c, st, "/authorize?"+authorizeQuery(verifier).Encode())
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	code := authorize(c, st, email, password, verifier)
+
+	tokens, status := exchangeCode(c, st, code, verifier)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Bearer", tokens["token_type"])
+	assert.NotEmpty(t, tokens["access_token"])
+	assert.NotEmpty(t, tokens["refresh_token"])
+
+	info, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: tokens["access_token"].(string),
+	})
+	require.NoError(t, err)
+	assert.True(t, info.GetActive())
+	assert.Equal(t, email, info.GetEmail())
+}
+
+func TestOAuth_CodeReuseRevokesTokens(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	verifier := gofakeit.LetterN(64)
+
+	code := authorize(c, st, email, password, verifier)
+
+	tokens, status := exchangeCode(c, st, code, verifier)
+	require.Equal(t, http.StatusOK, status)
+
+	body, status := exchangeCode(c, st, code, verifier)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	_, err := st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: tokens["refresh_token"].(string),
+	})
+	require.Error(t, err)
+}
+
+func TestOAuth_RefreshBoundToClient(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	verifier := gofakeit.LetterN(64)
+
+	code := authorize(c, st, email, password, verifier)
+
+	tokens, status := exchangeCode(c, st, code, verifier)
+	require.Equal(t, http.StatusOK, status)
+
+	refresh := tokens["refresh_token"].(string)
+
+	body, status := refreshOAuthToken(c, st, strconv.Itoa(verifiedAppID), refresh)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	body, status = refreshOAuthToken(c, st, "", refresh)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	// Чужой клиент не расходует токен.
+	body, status = refreshOAuthToken(c, st, strconv.Itoa(appID), refresh)
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, body["access_token"])
+	assert.NotEqual(t, refresh, body["refresh_token"])
+}
+
+func TestOAuth_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+
+	t.Run("Незарегистрированный redirect_uri", func(t *testing.T) {
+		q := authorizeQuery(gofakeit.LetterN(64))
+		q.Set("redirect_uri", "http://evil.example/callback")
+
+		resp := get(c, st, "/authorize?"+q.Encode())
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Location"))
+	})
+
+	t.Run("Без PKCE", func(t *testing.T) {
+		q := authorizeQuery(gofakeit.LetterN(64))
+		q.Del("code_challenge")
+
+		resp := get(c, st, "/authorize?"+q.Encode())
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		location, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_request", location.Query().Get("error"))
+	})
+
+	t.Run("Форма входа без CSRF-токена", func(t *testing.T) {
+		form := authorizeQuery(gofakeit.LetterN(64))
+		form.Set("email", email)
+		form.Set("password", password)
+
+		resp := postForm(c, st, "/authorize", form)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		// Токен без cookie, как при отправке формы с чужого сайта.
+		form.Set("csrf_token", gofakeit.LetterN(43))
+
+		resp = postForm(c, st, "/authorize", form)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Неверный code_verifier", func(t *testing.T) {
+		verifier := gofakeit.LetterN(64)
+		code := authorize(c, st, email, password, verifier)
+
+		body, status := exchangeCode(c, st, code, gofakeit.LetterN(64))
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "invalid_grant", body["error"])
+
+		// Неудачный обмен не расходует код.
+		_, status = exchangeCode(c, st, code, verifier)
+		assert.Equal(t, http.StatusOK, status)
+	})
+}
+
+func authorizeQuery(verifier string) url.Values {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {strconv.Itoa(appID)},
+		"redirect_uri":          {redirectURI},
+		"state":                 {"xyz"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+// authorize входит через форму /authorize и возвращает код авторизации.
+func authorize(
+	c context.Context,
+	st *suite.Suite,
+	email string,
+	password string,
+	verifier string,
+) string {
+	st.Helper()
+
+	return authorizeWith(c, st, email, password, authorizeQuery(verifier))
+}
+
+// authorizeWith как authorize, но с произвольными параметрами запроса.
+func authorizeWith(
+	c context.Context,
+	st *suite.Suite,
+	email string,
+	password string,
+	form url.Values,
+) string {
+	st.Helper()
+
+	form.Set("email", email)
+	form.Set("password", password)
+
+	resp := postAuthorize(c, st, form)
+	defer resp.Body.Close()
+
+	require.Equal(st, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(st, err)
+	require.True(st, strings.HasPrefix(location.String(), redirectURI))
+	require.Equal(st, "xyz", location.Query().Get("state"))
+	require.NotEmpty(st, location.Query().Get("code"))
+
+	return location.Query().Get("code")
+}
+
+func exchangeCode(
+	c context.Context,
+	st *suite.Suite,
+	code string,
+	verifier string,
+) (map[string]any, int) {
+	st.Helper()
+
+	resp := postForm(c, st, "/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {strconv.Itoa(appID)},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	defer resp.Body.Close()
+
+	var body map[string]any
+	require.NoError(st, json.NewDecoder(resp.Body).Decode(&body))
+
+	return body, resp.StatusCode
+}
+
+func refreshOAuthToken(
+	c context.Context,
+	st *suite.Suite,
+	clientID string,
+	refreshToken string,
+) (map[string]any, int) {
+	st.Helper()
+
+	resp := postForm(c, st, "/token", url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {clientID},
+		"refresh_token": {refreshToken},
+	})
+	defer resp.Body.Close()
+
+	var body map[string]any
+	require.NoError(st, json.NewDecoder(resp.Body).Decode(&body))
+
+	return body, resp.StatusCode
+}
+
+// csrfInput поле CSRF-токена на странице входа.
+var csrfInput = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// postAuthorize отправляет форму входа так же, как браузер:
+// открывает страницу входа и отправляет form вместе с выданными
+// на ней CSRF-токеном и cookie.
+func postAuthorize(c context.Context, st *suite.Suite, form url.Values) *http.Response {
+	st.Helper()
+
+	page := get(c, st, "/authorize?"+oauthParams(form).Encode())
+	defer page.Body.Close()
+	require.Equal(st, http.StatusOK, page.StatusCode)
+
+	body, err := io.ReadAll(page.Body)
+	require.NoError(st, err)
+
+	match := csrfInput.FindSubmatch(body)
+	require.NotNil(st, match, "на странице входа нет CSRF-токена")
+
+	form = maps.Clone(form)
+	form.Set("csrf_token", string(match[1]))
+
+	return postForm(c, st, "/authorize", form, page.Cookies()...)
+}
+
+// oauthParams параметры запроса авторизации из формы входа,
+// без учетных данных.
+func oauthParams(form url.Values) url.Values {
+	params := maps.Clone(form)
+	for _, name := range []string{"email", "password", "otp"} {
+		params.Del(name)
+	}
+
+	return params
+}
+
+func get(c context.Context, st *suite.Suite, path string) *http.Response {
+	st.Helper()
+
+	req, err := http.NewRequestWithContext(c, http.MethodGet, st.HTTPURL(path), nil)
+	require.NoError(st, err)
+
+	resp, err := noRedirectClient.Do(req)
+	require.NoError(st, err)
+
+	return resp
+}
+
+func postForm(
+	c context.Context,
+	st *suite.Suite,
+	path string,
+	form url.Values,
+	cookies ...*http.Cookie,
+) *http.Response {
+	st.Helper()
+
+	req, err := http.NewRequestWithContext(
+		c,
+		http.MethodPost,
+		st.HTTPURL(path),
+		strings.NewReader(form.Encode()),
+	)
+	require.NoError(st, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := noRedirectClient.Do(req)
+	require.NoError(st, err)
+
+	return resp
+}
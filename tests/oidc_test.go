@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestOIDC_Discovery(t *testing.T) {
+	c, st := suite.New(t)
+
+	resp := get(c, st, "/.well-known/openid-configuration")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var doc map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+
+	issuer, _ := doc["issuer"].(string)
+	require.NotEmpty(t, issuer)
+	assert.Equal(t, issuer+"/authorize", doc["authorization_endpoint"])
+	assert.Equal(t, issuer+"/token", doc["token_endpoint"])
+	assert.Equal(t, issuer+"/userinfo", doc["userinfo_endpoint"])
+	assert.Equal(t, issuer+"/.well-known/jwks.json", doc["jwks_uri"])
+	assert.Contains(t, doc["id_token_signing_alg_values_supported"], "RS256")
+	assert.Contains(t, doc["code_challenge_methods_supported"], "S256")
+}
+
+func TestOIDC_IDTokenAndUserInfo(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	verifier := gofakeit.LetterN(64)
+	nonce := gofakeit.LetterN(16)
+
+	q := authorizeQuery(verifier)
+	q.Set("scope", "openid email")
+	q.Set("nonce", nonce)
+
+	code := authorizeWith(c, st, email, password, q)
+
+	tokens, status := exchangeCode(c, st, code, verifier)
+	require.Equal(t, http.StatusOK, status)
+
+	idToken, _ := tokens["id_token"].(string)
+	require.NotEmpty(t, idToken)
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, jwksKeyFunc(c, st))
+	require.NoError(t, err)
+
+	assert.Equal(t, nonce, claims["nonce"])
+	assert.Equal(t, email, claims["email"])
+	assert.NotEmpty(t, claims["iss"])
+	assert.NotEmpty(t, claims["sub"])
+	assert.NotEmpty(t, claims["auth_time"])
+
+	aud, err := claims.GetAudience()
+	require.NoError(t, err)
+	assert.Equal(t, jwt.ClaimStrings{strconv.Itoa(appID)}, aud)
+
+	req, err := http.NewRequestWithContext(
+		c,
+		http.MethodGet,
+		st.HTTPURL("/userinfo"),
+		nil,
+	)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+tokens["access_token"].(string))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var info map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.Equal(t, claims["sub"], info["sub"])
+	assert.Equal(t, email, info["email"])
+}
+
+func TestOIDC_NoIDTokenWithoutOpenIDScope(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	verifier := gofakeit.LetterN(64)
+
+	code := authorize(c, st, email, password, verifier)
+
+	tokens, status := exchangeCode(c, st, code, verifier)
+	require.Equal(t, http.StatusOK, status)
+	assert.NotContains(t, tokens, "id_token")
+}
+
+func TestOIDC_UserInfoUnauthorized(t *testing.T) {
+	c, st := suite.New(t)
+
+	resp := get(c, st, "/userinfo")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+}
@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPasswordHash_ConfiguredAlgorithm(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, _ := registerUser(c, st)
+
+	assert.True(
+		t,
+		strings.HasPrefix(st.PasswordHash(email), "$"+st.Cfg.PasswordHash.Algorithm+"$"),
+	)
+}
+
+func TestPasswordHash_RehashOnLogin(t *testing.T) {
+	c, st := suite.New(t)
+
+	// Пользователь из тестовых миграций с хэшем bcrypt.
+	const (
+		email    = "legacy-bcrypt@example.com"
+		password = "LegacyPassw0rd"
+	)
+
+	login(c, st, email, password)
+
+	hash := st.PasswordHash(email)
+	assert.True(
+		t,
+		strings.HasPrefix(hash, "$"+st.Cfg.PasswordHash.Algorithm+"$"),
+	)
+
+	// Пересчитанный хэш подходит к тому же паролю.
+	login(c, st, email, password)
+	assert.Equal(t, hash, st.PasswordHash(email))
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicy_Register(t *testing.T) {
+	c, st := suite.New(t)
+
+	tests := []struct {
+		name          string
+		email         string
+		password      string
+		expectedRules []string
+	}{
+		{
+			name:          "Короткий пароль",
+			email:         gofakeit.Email(),
+			password:      "aA1aA1",
+			expectedRules: []string{"min_length"},
+		},
+		{
+			name:          "Без заглавных и цифр",
+			email:         gofakeit.Email(),
+			password:      "onlylowercaseletters",
+			expectedRules: []string{"upper", "digit"},
+		},
+		{
+			name:          "Длиннее 72 байт",
+			email:         gofakeit.Email(),
+			password:      strings.Repeat("пП1", 20),
+			expectedRules: []string{"max_bytes"},
+		},
+		{
+			name:          "Содержит имя из email",
+			email:         "johnsmith@example.com",
+			password:      "JohnSmith2000",
+			expectedRules: []string{"email"},
+		},
+		{
+			name:          "Распространенный пароль",
+			email:         gofakeit.Email(),
+			password:      "Password123!",
+			expectedRules: []string{"common"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.Register(c, &ssov1.RegisterRequest{
+				Email:    tt.email,
+				Password: tt.password,
+			})
+			require.Error(t, err)
+
+			field, rules := policyViolations(st, err)
+			assert.Equal(t, "password", field)
+			assert.Equal(t, tt.expectedRules, rules)
+		})
+	}
+}
+
+func TestPasswordPolicy_ChangeAndReset(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	respLogin := login(c, st, email, password)
+
+	_, err := st.AuthClient.ChangePassword(
+		suite.WithAccessToken(c, respLogin.GetToken()),
+		&ssov1.ChangePasswordRequest{
+			OldPassword: password,
+			NewPassword: "short",
+		},
+	)
+	require.Error(t, err)
+
+	field, rules := policyViolations(st, err)
+	assert.Equal(t, "new_password", field)
+	assert.Contains(t, rules, "min_length")
+
+	token := requestPasswordReset(c, st, email)
+
+	_, err = st.AuthClient.ConfirmPasswordReset(c, &ssov1.ConfirmPasswordResetRequest{
+		Token:       token,
+		NewPassword: "qwerty123",
+	})
+	require.Error(t, err)
+
+	field, rules = policyViolations(st, err)
+	assert.Equal(t, "new_password", field)
+	assert.Contains(t, rules, "common")
+
+	// Отклоненный пароль не расходует токен сброса.
+	_, err = st.AuthClient.ConfirmPasswordReset(c, &ssov1.ConfirmPasswordResetRequest{
+		Token:       token,
+		NewPassword: randomPassword(),
+	})
+	require.NoError(t, err)
+}
+
+// policyViolations проверяет, что err — нарушение политики паролей,
+// и возвращает поле из BadRequest и коды нарушенных правил.
+func policyViolations(st *suite.Suite, err error) (string, []string) {
+	st.Helper()
+
+	s := status.Convert(err)
+	require.Equal(st, codes.InvalidArgument, s.Code())
+
+	var (
+		field string
+		rules []string
+	)
+	for _, d := range s.Details() {
+		switch d := d.(type) {
+		case *errdetails.BadRequest:
+			require.NotEmpty(st, d.GetFieldViolations())
+			field = d.GetFieldViolations()[0].GetField()
+		case *errdetails.ErrorInfo:
+			assert.Equal(st, "PASSWORD_POLICY_VIOLATION", d.GetReason())
+			rules = strings.Split(d.GetMetadata()["rules"], ",")
+		}
+	}
+	require.NotEmpty(st, field)
+	require.NotEmpty(st, rules)
+
+	return field, rules
+}
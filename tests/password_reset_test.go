@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"context"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var mailLink = regexp.MustCompile(`https?://\S+`)
+
+func TestPasswordReset_HappyPath(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	respLogin := login(c, st, email, password)
+
+	token := requestPasswordReset(c, st, email)
+	newPassword := randomPassword()
+
+	_, err := st.AuthClient.ConfirmPasswordReset(c, &ssov1.ConfirmPasswordResetRequest{
+		Token:       token,
+		NewPassword: newPassword,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.Login(c, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.Error(t, err)
+
+	// Вход сразу после сброса дает действующий токен.
+	info, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: login(c, st, email, newPassword).GetToken(),
+	})
+	require.NoError(t, err)
+	assert.True(t, info.GetActive())
+
+	// Сеансы, открытые со старым паролем, завершены.
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: respLogin.GetRefreshToken(),
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	info, err = st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: respLogin.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.False(t, info.GetActive())
+
+	// Токен одноразовый.
+	_, err = st.AuthClient.ConfirmPasswordReset(c, &ssov1.ConfirmPasswordResetRequest{
+		Token:       token,
+		NewPassword: randomPassword(),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPasswordReset_NewPasswordVoidsOtherTokens(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+
+	stale := requestPasswordReset(c, st, email)
+	token := requestPasswordReset(c, st, email)
+
+	_, err := st.AuthClient.ConfirmPasswordReset(c, &ssov1.ConfirmPasswordResetRequest{
+		Token:       token,
+		NewPassword: password,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ConfirmPasswordReset(c, &ssov1.ConfirmPasswordResetRequest{
+		Token:       stale,
+		NewPassword: randomPassword(),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Смена пароля тоже отменяет выданные ссылки сброса.
+	stale = requestPasswordReset(c, st, email)
+
+	ctx := suite.WithAccessToken(c, login(c, st, email, password).GetToken())
+	_, err = st.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		OldPassword: password,
+		NewPassword: randomPassword(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ConfirmPasswordReset(c, &ssov1.ConfirmPasswordResetRequest{
+		Token:       stale,
+		NewPassword: randomPassword(),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPasswordReset_UnknownEmail(t *testing.T) {
+	c, st := suite.New(t)
+
+	email := gofakeit.Email()
+
+	_, err := st.AuthClient.RequestPasswordReset(c, &ssov1.RequestPasswordResetRequest{
+		Email: email,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, st.Outbox(email))
+}
+
+func TestPasswordReset_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	tests := []struct {
+		name        string
+		token       string
+		password    string
+		expectedErr string
+	}{
+		{
+			name:        "Без токена",
+			token:       "",
+			password:    randomPassword(),
+			expectedErr: "токен не указан",
+		},
+		{
+			name:        "Без пароля",
+			token:       "token",
+			password:    "",
+			expectedErr: "пароль не указан",
+		},
+		{
+			name:        "Неизвестный токен",
+			token:       "token",
+			password:    randomPassword(),
+			expectedErr: "недействительна",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.ConfirmPasswordReset(
+				c,
+				&ssov1.ConfirmPasswordResetRequest{
+					Token:       tt.token,
+					NewPassword: tt.password,
+				},
+			)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
+
+// requestPasswordReset запрашивает сброс пароля и возвращает
+// токен из ссылки в последнем письме.
+func requestPasswordReset(
+	c context.Context,
+	st *suite.Suite,
+	email string,
+) string {
+	st.Helper()
+
+	sent := len(st.Outbox(email))
+
+	_, err := st.AuthClient.RequestPasswordReset(c, &ssov1.RequestPasswordResetRequest{
+		Email: email,
+	})
+	require.NoError(st, err)
+
+	// Письмо отправляется в фоне, после ответа.
+	require.Eventually(st, func() bool {
+		return len(st.Outbox(email)) > sent
+	}, 5*time.Second, 20*time.Millisecond)
+
+	return outboxToken(st, email)
+}
+
+// outboxToken возвращает параметр token ссылки
+// из последнего письма на email.
+func outboxToken(st *suite.Suite, email string) string {
+	st.Helper()
+
+	messages := st.Outbox(email)
+	require.NotEmpty(st, messages)
+
+	link, err := url.Parse(mailLink.FindString(messages[len(messages)-1].Body))
+	require.NoError(st, err)
+
+	token := link.Query().Get("token")
+	require.NotEmpty(st, token)
+
+	return token
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"testing"
+)
+
+func TestProfile_UpdateAndGet(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	token := login(c, st, email, password).GetToken()
+	userCtx := suite.WithAccessToken(c, token)
+
+	// Незаполненный профиль пустой.
+	respGet, err := st.AuthClient.GetUser(userCtx, &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, email, respGet.GetUser().GetEmail())
+	assert.Empty(t, respGet.GetUser().GetProfile().GetUpdatedAt())
+
+	attributes := map[string]string{
+		"department":  "Продажи",
+		"employee_id": "42",
+		"theme":       "dark",
+	}
+
+	respUpd, err := st.AuthClient.UpdateProfile(userCtx, &ssov1.UpdateProfileRequest{
+		DisplayName: "Иван Петров",
+		Locale:      "ru-RU",
+		Timezone:    "Europe/Moscow",
+		AvatarUrl:   "https://example.com/avatar.png",
+		Attributes:  attributes,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respUpd.GetProfile().GetUpdatedAt())
+
+	respGet, err = st.AuthClient.GetUser(userCtx, &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+	uid := respGet.GetUser().GetId()
+
+	profile := respGet.GetUser().GetProfile()
+	assert.Equal(t, "Иван Петров", profile.GetDisplayName())
+	assert.Equal(t, "ru-RU", profile.GetLocale())
+	assert.Equal(t, "Europe/Moscow", profile.GetTimezone())
+	assert.Equal(t, "https://example.com/avatar.png", profile.GetAvatarUrl())
+	assert.Equal(t, attributes, profile.GetAttributes())
+
+	// Администратор получает пользователя по id и по email.
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+
+	respGet, err = st.AuthClient.GetUser(adminCtx, &ssov1.GetUserRequest{UserId: uid})
+	require.NoError(t, err)
+	assert.Equal(t, "Иван Петров", respGet.GetUser().GetProfile().GetDisplayName())
+
+	respGet, err = st.AuthClient.GetUser(adminCtx, &ssov1.GetUserRequest{Email: email})
+	require.NoError(t, err)
+	assert.Equal(t, uid, respGet.GetUser().GetId())
+
+	// Стандартные claims профиля в userinfo.
+	req, err := http.NewRequestWithContext(
+		c,
+		http.MethodGet,
+		st.HTTPURL("/userinfo"),
+		nil,
+	)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var info map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.Equal(t, "Иван Петров", info["name"])
+	assert.Equal(t, "ru-RU", info["locale"])
+	assert.Equal(t, "Europe/Moscow", info["zoneinfo"])
+	assert.Equal(t, "https://example.com/avatar.png", info["picture"])
+	assert.NotEmpty(t, info["updated_at"])
+}
+
+func TestProfile_Schema(t *testing.T) {
+	c, st := suite.New(t)
+
+	userCtx := suite.WithAccessToken(c, registerLogin(c, st).GetToken())
+
+	tests := []struct {
+		name           string
+		req            *ssov1.UpdateProfileRequest
+		expectedFields []string
+	}{
+		{
+			name:           "Неизвестный часовой пояс",
+			req:            &ssov1.UpdateProfileRequest{Timezone: "Mars/Olympus"},
+			expectedFields: []string{"timezone"},
+		},
+		{
+			name:           "Неверная локаль и адрес аватара",
+			req:            &ssov1.UpdateProfileRequest{Locale: "ru RU", AvatarUrl: "avatar.png"},
+			expectedFields: []string{"locale", "avatar_url"},
+		},
+		{
+			name: "Атрибут вне схемы",
+			req: &ssov1.UpdateProfileRequest{
+				Attributes: map[string]string{"salary": "100"},
+			},
+			expectedFields: []string{"attributes.salary"},
+		},
+		{
+			name: "Неверные типы атрибутов",
+			req: &ssov1.UpdateProfileRequest{
+				Attributes: map[string]string{
+					"employee_id": "сорок два",
+					"theme":       "blue",
+				},
+			},
+			expectedFields: []string{"attributes.employee_id", "attributes.theme"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.UpdateProfile(userCtx, tt.req)
+			require.Error(t, err)
+
+			s, ok := status.FromError(err)
+			require.True(t, ok)
+			assert.Equal(t, codes.InvalidArgument, s.Code())
+
+			var fields []string
+			for _, d := range s.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					for _, v := range br.GetFieldViolations() {
+						fields = append(fields, v.GetField())
+					}
+				}
+			}
+			assert.Equal(t, tt.expectedFields, fields)
+		})
+	}
+}
+
+func TestProfile_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+	userCtx := suite.WithAccessToken(c, registerLogin(c, st).GetToken())
+
+	other := introspectUserID(c, st, registerLogin(c, st).GetToken())
+
+	t.Run("Чужой пользователь", func(t *testing.T) {
+		_, err := st.AuthClient.GetUser(userCtx, &ssov1.GetUserRequest{UserId: other})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Поиск по email без прав", func(t *testing.T) {
+		_, err := st.AuthClient.GetUser(userCtx, &ssov1.GetUserRequest{
+			Email: gofakeit.Email(),
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Чужой профиль", func(t *testing.T) {
+		_, err := st.AuthClient.UpdateProfile(userCtx, &ssov1.UpdateProfileRequest{
+			UserId:      other,
+			DisplayName: "Чужое имя",
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("id и email одновременно", func(t *testing.T) {
+		_, err := st.AuthClient.GetUser(adminCtx, &ssov1.GetUserRequest{
+			UserId: other,
+			Email:  gofakeit.Email(),
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Несуществующий пользователь", func(t *testing.T) {
+		_, err := st.AuthClient.GetUser(adminCtx, &ssov1.GetUserRequest{
+			Email: gofakeit.Email(),
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Без токена", func(t *testing.T) {
+		_, err := st.AuthClient.UpdateProfile(c, &ssov1.UpdateProfileRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"context"
+	"github.com/h1lton/sso-grpc-ntc/internal/domain/models"
+	"github.com/h1lton/sso-grpc-ntc/internal/grpc/ratelimit"
+	"github.com/h1lton/sso-grpc-ntc/internal/storage"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// rateLimitedAppID приложение из тестовых миграций, в которое
+// больше никто не входит: тест исчерпывает его бюджет вызовов,
+// не мешая остальным.
+const rateLimitedAppID = 4
+
+func TestRateLimit_Login(t *testing.T) {
+	c, st := suite.New(t)
+
+	limit := st.Cfg.RateLimit.Methods["Login"]
+	require.Positive(t, limit.Requests)
+
+	// Пустой запрос отклоняется валидацией уже после проверки лимита,
+	// поэтому бюджет можно исчерпать без хэширования паролей.
+	loginEmpty := func(appID int32) error {
+		_, err := st.AuthClient.Login(c, &ssov1.LoginRequest{AppId: appID})
+
+		return err
+	}
+
+	// Пока идут вызовы, бюджет немного пополняется.
+	var (
+		allowed int
+		err     error
+	)
+	for range limit.Requests * 2 {
+		err = loginEmpty(rateLimitedAppID)
+		if status.Code(err) != codes.InvalidArgument {
+			break
+		}
+		allowed++
+	}
+
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.GreaterOrEqual(t, allowed, limit.Requests)
+	assert.Positive(t, retryDelay(st, err))
+
+	// Бюджет другого приложения не затронут.
+	err = loginEmpty(appID)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestRateLimit_UnknownAppSharesIPBudget(t *testing.T) {
+	t.Parallel()
+
+	limiter := ratelimit.New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ratelimit.Config{
+			Default:   ratelimit.Limit{Requests: 3, Per: time.Hour},
+			AppsPerIP: 2,
+		},
+		knownApps{appID: true},
+	)
+	call := loginThrough(limiter.UnaryInterceptor())
+
+	// Каждый выдуманный app_id расходует общий бюджет адреса:
+	// 3 вызова на приложение, умноженные на AppsPerIP.
+	for i := range 6 {
+		require.Equal(t, codes.OK, call("192.0.2.1", int32(1000+i)))
+	}
+	assert.Equal(t, codes.ResourceExhausted, call("192.0.2.1", 2000))
+	assert.Equal(t, codes.ResourceExhausted, call("192.0.2.1", appID))
+
+	// Бюджет существующего приложения не больше лимита метода.
+	for range 3 {
+		require.Equal(t, codes.OK, call("192.0.2.2", appID))
+	}
+	assert.Equal(t, codes.ResourceExhausted, call("192.0.2.2", appID))
+	assert.Equal(t, codes.OK, call("192.0.2.2", 0))
+
+	// У другого адреса свой бюджет.
+	assert.Equal(t, codes.OK, call("192.0.2.3", 1000))
+}
+
+func TestRateLimit_ThrottledIPSkipsAppLookup(t *testing.T) {
+	t.Parallel()
+
+	apps := &countedApps{apps: knownApps{appID: true}}
+	limiter := ratelimit.New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ratelimit.Config{
+			Default: ratelimit.Limit{Requests: 2, Per: time.Hour},
+		},
+		apps,
+	)
+	call := loginThrough(limiter.UnaryInterceptor())
+
+	for range 2 {
+		require.Equal(t, codes.OK, call("192.0.2.10", appID))
+	}
+	require.Equal(t, int32(2), apps.calls.Load())
+
+	// Исчерпанный бюджет адреса проверяется до поиска приложения.
+	for range 10 {
+		require.Equal(t, codes.ResourceExhausted, call("192.0.2.10", appID))
+	}
+	assert.Equal(t, int32(2), apps.calls.Load())
+}
+
+// loginThrough возвращает функцию, которая пропускает пустой Login
+// с адреса ip и app_id через interceptor и возвращает код ответа.
+func loginThrough(
+	interceptor grpc.UnaryServerInterceptor,
+) func(ip string, appID int32) codes.Code {
+	return func(ip string, appID int32) codes.Code {
+		c := peer.NewContext(context.Background(), &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 50000},
+		})
+
+		_, err := interceptor(
+			c,
+			&ssov1.LoginRequest{AppId: appID},
+			&grpc.UnaryServerInfo{FullMethod: "/auth.Auth/Login"},
+			func(context.Context, any) (any, error) { return nil, nil },
+		)
+
+		return status.Code(err)
+	}
+}
+
+// countedApps считает обращения к приложениям.
+type countedApps struct {
+	apps  knownApps
+	calls atomic.Int32
+}
+
+func (a *countedApps) App(c context.Context, appID int32) (models.App, error) {
+	a.calls.Add(1)
+
+	return a.apps.App(c, appID)
+}
+
+// knownApps приложения, которые существуют для ratelimit.Limiter.
+type knownApps map[int32]bool
+
+func (a knownApps) App(_ context.Context, appID int32) (models.App, error) {
+	if !a[appID] {
+		return models.App{}, storage.ErrAppNotFound
+	}
+
+	return models.App{ID: int(appID)}, nil
+}
@@ -0,0 +1,256 @@
+package tests
+
+import (
+	"context"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestRoles_HappyPath(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+
+	respRole, err := st.AuthClient.CreateRole(adminCtx, &ssov1.CreateRoleRequest{
+		AppId:       appID,
+		Name:        "editor-" + gofakeit.UUID(),
+		Permissions: []string{"articles:write", "articles:read"},
+	})
+	require.NoError(t, err)
+	role := respRole.GetRole()
+	require.NotEmpty(t, role.GetId())
+
+	email, password := registerUser(c, st)
+	uid := introspectUserID(c, st, login(c, st, email, password).GetToken())
+
+	assert.False(t, checkPermission(c, st, uid, appID, "articles:write"))
+
+	_, err = st.AuthClient.AssignRole(adminCtx, &ssov1.AssignRoleRequest{
+		UserId: uid,
+		RoleId: role.GetId(),
+	})
+	require.NoError(t, err)
+
+	assert.True(t, checkPermission(c, st, uid, appID, "articles:write"))
+	// Роль действует только в своем приложении.
+	assert.False(t, checkPermission(c, st, uid, verifiedAppID, "articles:write"))
+
+	// Роли и разрешения попадают в токен при входе.
+	token := login(c, st, email, password).GetToken()
+
+	claims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+	assert.Equal(t, []any{role.GetName()}, claims["roles"])
+	assert.Equal(t, []any{"articles:read", "articles:write"}, claims["permissions"])
+
+	respList, err := st.AuthClient.ListRoles(adminCtx, &ssov1.ListRolesRequest{
+		AppId: appID,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, roleNames(respList.GetRoles()), role.GetName())
+
+	_, err = st.AuthClient.UnassignRole(adminCtx, &ssov1.UnassignRoleRequest{
+		UserId: uid,
+		RoleId: role.GetId(),
+	})
+	require.NoError(t, err)
+
+	assert.False(t, checkPermission(c, st, uid, appID, "articles:write"))
+
+	// Интроспекция видит текущие роли, а не записанные в токен.
+	info, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{Token: token})
+	require.NoError(t, err)
+	assert.Empty(t, info.GetRoles())
+
+	_, err = st.AuthClient.DeleteRole(adminCtx, &ssov1.DeleteRoleRequest{
+		Id: role.GetId(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.DeleteRole(adminCtx, &ssov1.DeleteRoleRequest{
+		Id: role.GetId(),
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestIsAdmin_CompatibilityShim(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminID := introspectUserID(c, st, adminToken(c, st))
+
+	resp, err := st.AuthClient.IsAdmin(c, &ssov1.IsAdminRequest{UserId: adminID})
+	require.NoError(t, err)
+	assert.True(t, resp.GetIsAdmin())
+
+	assert.True(t, checkPermission(c, st, adminID, appID, "sso.admin"))
+
+	uid := introspectUserID(c, st, registerLogin(c, st).GetToken())
+
+	resp, err = st.AuthClient.IsAdmin(c, &ssov1.IsAdminRequest{UserId: uid})
+	require.NoError(t, err)
+	assert.False(t, resp.GetIsAdmin())
+}
+
+func TestRoles_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+	userToken := registerLogin(c, st).GetToken()
+	userCtx := suite.WithAccessToken(c, userToken)
+
+	name := "viewer-" + gofakeit.UUID()
+	_, err := st.AuthClient.CreateRole(adminCtx, &ssov1.CreateRoleRequest{
+		AppId: appID,
+		Name:  name,
+	})
+	require.NoError(t, err)
+
+	tenantRole, err := st.AuthClient.CreateRole(adminCtx, &ssov1.CreateRoleRequest{
+		AppId: tenantAppID,
+		Name:  "viewer-" + gofakeit.UUID(),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		call         func() error
+		expectedCode codes.Code
+	}{
+		{
+			name: "Не администратор",
+			call: func() error {
+				_, err := st.AuthClient.CreateRole(userCtx, &ssov1.CreateRoleRequest{
+					AppId: appID,
+					Name:  gofakeit.UUID(),
+				})
+
+				return err
+			},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name: "Роль с таким именем уже есть",
+			call: func() error {
+				_, err := st.AuthClient.CreateRole(adminCtx, &ssov1.CreateRoleRequest{
+					AppId: appID,
+					Name:  name,
+				})
+
+				return err
+			},
+			expectedCode: codes.AlreadyExists,
+		},
+		{
+			name: "Неизвестное приложение",
+			call: func() error {
+				_, err := st.AuthClient.CreateRole(adminCtx, &ssov1.CreateRoleRequest{
+					AppId: 999,
+					Name:  gofakeit.UUID(),
+				})
+
+				return err
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "Неизвестная роль",
+			call: func() error {
+				_, err := st.AuthClient.AssignRole(adminCtx, &ssov1.AssignRoleRequest{
+					UserId: 1,
+					RoleId: 1 << 40,
+				})
+
+				return err
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "Роль приложения другой организации",
+			call: func() error {
+				_, err := st.AuthClient.AssignRole(adminCtx, &ssov1.AssignRoleRequest{
+					UserId: introspectUserID(c, st, userToken),
+					RoleId: tenantRole.GetRole().GetId(),
+				})
+
+				return err
+			},
+			expectedCode: codes.FailedPrecondition,
+		},
+		{
+			name: "Роль не назначена",
+			call: func() error {
+				_, err := st.AuthClient.UnassignRole(adminCtx, &ssov1.UnassignRoleRequest{
+					UserId: 1,
+					RoleId: 1 << 40,
+				})
+
+				return err
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "Проверка без разрешения",
+			call: func() error {
+				_, err := st.AuthClient.CheckPermission(c, &ssov1.CheckPermissionRequest{
+					UserId: 1,
+					AppId:  appID,
+				})
+
+				return err
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedCode, status.Code(tt.call()))
+		})
+	}
+}
+
+func checkPermission(
+	c context.Context,
+	st *suite.Suite,
+	userID int64,
+	appID int32,
+	permission string,
+) bool {
+	st.Helper()
+
+	resp, err := st.AuthClient.CheckPermission(c, &ssov1.CheckPermissionRequest{
+		UserId:     userID,
+		AppId:      appID,
+		Permission: permission,
+	})
+	require.NoError(st, err)
+
+	return resp.GetAllowed()
+}
+
+func introspectUserID(c context.Context, st *suite.Suite, token string) int64 {
+	st.Helper()
+
+	info, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{Token: token})
+	require.NoError(st, err)
+	require.True(st, info.GetActive())
+
+	return info.GetUid()
+}
+
+func roleNames(roles []*ssov1.Role) []string {
+	names := make([]string, 0, len(roles))
+	for _, r := range roles {
+		names = append(names, r.GetName())
+	}
+
+	return names
+}
@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"context"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/pkg/totp"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecondFactor_HappyPath(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	secret, step, _ := enableTOTP(c, st, email, password)
+
+	respLogin := login(c, st, email, password)
+	assert.Empty(t, respLogin.GetToken())
+	assert.Empty(t, respLogin.GetRefreshToken())
+	require.NotEmpty(t, respLogin.GetSecondFactorChallenge())
+
+	// Код, которым подтверждали 2FA, повторно не принимается.
+	_, err := st.AuthClient.VerifySecondFactor(c, &ssov1.VerifySecondFactorRequest{
+		Challenge: respLogin.GetSecondFactorChallenge(),
+		Code:      totpCode(t, secret, step),
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	resp, err := st.AuthClient.VerifySecondFactor(c, &ssov1.VerifySecondFactorRequest{
+		Challenge: respLogin.GetSecondFactorChallenge(),
+		Code:      totpCode(t, secret, step+1),
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.GetToken())
+	assert.NotEmpty(t, resp.GetRefreshToken())
+
+	// Вызов одноразовый.
+	_, err = st.AuthClient.VerifySecondFactor(c, &ssov1.VerifySecondFactorRequest{
+		Challenge: respLogin.GetSecondFactorChallenge(),
+		Code:      totpCode(t, secret, step+1),
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestSecondFactor_RecoveryCode(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	_, _, recoveryCodes := enableTOTP(c, st, email, password)
+	require.Len(t, recoveryCodes, 10)
+
+	challenge := login(c, st, email, password).GetSecondFactorChallenge()
+
+	_, err := st.AuthClient.VerifySecondFactor(c, &ssov1.VerifySecondFactorRequest{
+		Challenge: challenge,
+		Code:      strings.ToUpper(recoveryCodes[0]),
+	})
+	require.NoError(t, err)
+
+	challenge = login(c, st, email, password).GetSecondFactorChallenge()
+
+	_, err = st.AuthClient.VerifySecondFactor(c, &ssov1.VerifySecondFactorRequest{
+		Challenge: challenge,
+		Code:      recoveryCodes[0],
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestSecondFactor_ChallengeAttemptsLimited(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	_, _, recoveryCodes := enableTOTP(c, st, email, password)
+
+	challenge := login(c, st, email, password).GetSecondFactorChallenge()
+
+	// После нескольких неудач вход еще и откладывается,
+	// но попытки вызова засчитываются и тогда.
+	for range 5 {
+		_, err := st.AuthClient.VerifySecondFactor(c, &ssov1.VerifySecondFactorRequest{
+			Challenge: challenge,
+			Code:      "000000",
+		})
+		require.Contains(t, []codes.Code{
+			codes.Unauthenticated,
+			codes.ResourceExhausted,
+		}, status.Code(err))
+	}
+
+	_, err := st.AuthClient.VerifySecondFactor(c, &ssov1.VerifySecondFactorRequest{
+		Challenge: challenge,
+		Code:      recoveryCodes[0],
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "войдите заново")
+}
+
+func TestSecondFactor_FailuresCountTowardsLockout(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	secret, step, _ := enableTOTP(c, st, email, password)
+
+	verify := func(challenge string, code string) error {
+		_, err := st.AuthClient.VerifySecondFactor(c, &ssov1.VerifySecondFactorRequest{
+			Challenge: challenge,
+			Code:      code,
+		})
+
+		return err
+	}
+
+	challenge := login(c, st, email, password).GetSecondFactorChallenge()
+	for range st.Cfg.Lockout.UserDelayAfter {
+		err := verify(challenge, "000000")
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+
+	// Новый вход не дает новых попыток.
+	_, err := st.AuthClient.Login(c, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	time.Sleep(retryDelay(st, err))
+
+	challenge = login(c, st, email, password).GetSecondFactorChallenge()
+	err = verify(challenge, "000000")
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Порог блокировки достигнут: не подходит даже верный код.
+	err = verify(challenge, totpCode(t, secret, step+1))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestSecondFactor_EnrollFailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	ctx := suite.WithAccessToken(c, registerLogin(c, st).GetToken())
+
+	t.Run("По токену API-ключа", func(t *testing.T) {
+		exchanged, err := st.AuthClient.ExchangeAPIKey(c, &ssov1.ExchangeAPIKeyRequest{
+			ApiKey: createAPIKey(ctx, st).GetKey(),
+		})
+		require.NoError(t, err)
+
+		keyCtx := suite.WithAccessToken(c, exchanged.GetToken())
+
+		_, err = st.AuthClient.EnrollTOTP(keyCtx, &ssov1.EnrollTOTPRequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = st.AuthClient.ConfirmTOTP(keyCtx, &ssov1.ConfirmTOTPRequest{
+			Code: "123456",
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	_, err := st.AuthClient.ConfirmTOTP(ctx, &ssov1.ConfirmTOTPRequest{
+		Code: "123456",
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	enrolled, err := st.AuthClient.EnrollTOTP(ctx, &ssov1.EnrollTOTPRequest{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrolled.GetOtpauthUri(), "otpauth://totp/"))
+	assert.Contains(t, enrolled.GetOtpauthUri(), "secret="+enrolled.GetSecret())
+
+	wrong := totpCode(t, enrolled.GetSecret(), totp.Step(time.Now())+10)
+	_, err = st.AuthClient.ConfirmTOTP(ctx, &ssov1.ConfirmTOTPRequest{Code: wrong})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestSecondFactor_OAuthAuthorize(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	secret, step, _ := enableTOTP(c, st, email, password)
+	verifier := gofakeit.LetterN(64)
+
+	form := authorizeQuery(verifier)
+	form.Set("email", email)
+	form.Set("password", password)
+
+	resp := postAuthorize(c, st, form)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	q := authorizeQuery(verifier)
+	q.Set("otp", totpCode(t, secret, step+1))
+
+	code := authorizeWith(c, st, email, password, q)
+
+	_, httpStatus := exchangeCode(c, st, code, verifier)
+	assert.Equal(t, http.StatusOK, httpStatus)
+}
+
+// enableTOTP включает пользователю второй фактор и возвращает секрет,
+// шаг кода, которым 2FA подтверждена, и коды восстановления.
+func enableTOTP(
+	c context.Context,
+	st *suite.Suite,
+	email string,
+	password string,
+) (secret string, step int64, recoveryCodes []string) {
+	st.Helper()
+
+	ctx := suite.WithAccessToken(c, login(c, st, email, password).GetToken())
+
+	enrolled, err := st.AuthClient.EnrollTOTP(ctx, &ssov1.EnrollTOTPRequest{})
+	require.NoError(st, err)
+
+	step = totp.Step(time.Now())
+
+	confirmed, err := st.AuthClient.ConfirmTOTP(ctx, &ssov1.ConfirmTOTPRequest{
+		Code: totpCode(st.T, enrolled.GetSecret(), step),
+	})
+	require.NoError(st, err)
+
+	return enrolled.GetSecret(), step, confirmed.GetRecoveryCodes()
+}
+
+func totpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+
+	return code
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestSessions_ListAndRevoke(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+
+	phone := login(
+		metadata.AppendToOutgoingContext(c, "x-device-name", "Phone"),
+		st, email, password,
+	)
+	laptop := login(
+		metadata.AppendToOutgoingContext(c, "x-device-name", "Laptop"),
+		st, email, password,
+	)
+
+	phoneCtx := suite.WithAccessToken(c, phone.GetToken())
+
+	resp, err := st.AuthClient.ListSessions(phoneCtx, &ssov1.ListSessionsRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.GetSessions(), 2)
+
+	byDevice := make(map[string]*ssov1.Session)
+	for _, s := range resp.GetSessions() {
+		byDevice[s.GetDevice()] = s
+
+		assert.Equal(t, int32(appID), s.GetAppId())
+		assert.NotEmpty(t, s.GetIp())
+		assert.NotEmpty(t, s.GetUserAgent())
+		assert.NotZero(t, s.GetLastSeenAt())
+		assert.Greater(t, s.GetExpiresAt(), s.GetCreatedAt())
+	}
+	require.Contains(t, byDevice, "Phone")
+	require.Contains(t, byDevice, "Laptop")
+
+	assert.True(t, byDevice["Phone"].GetCurrent())
+	assert.False(t, byDevice["Laptop"].GetCurrent())
+	assert.Equal(t, byDevice["Phone"].GetId(), sessionIDClaim(st, phone.GetToken()))
+	assert.Equal(t, byDevice["Laptop"].GetId(), sessionIDClaim(st, laptop.GetToken()))
+
+	_, err = st.AuthClient.RevokeSession(phoneCtx, &ssov1.RevokeSessionRequest{
+		Id: byDevice["Laptop"].GetId(),
+	})
+	require.NoError(t, err)
+
+	// Токены завершенного сеанса перестают действовать сразу.
+	info, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: laptop.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.False(t, info.GetActive())
+
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: laptop.GetRefreshToken(),
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	resp, err = st.AuthClient.ListSessions(phoneCtx, &ssov1.ListSessionsRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.GetSessions(), 1)
+	assert.Equal(t, "Phone", resp.GetSessions()[0].GetDevice())
+
+	// Обновление продолжает тот же сеанс.
+	refreshed, err := st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: phone.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		sessionIDClaim(st, phone.GetToken()),
+		sessionIDClaim(st, refreshed.GetToken()),
+	)
+}
+
+func TestRevokeSession_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	authCtx := suite.WithAccessToken(c, registerLogin(c, st).GetToken())
+	other := registerLogin(c, st)
+
+	tests := []struct {
+		name         string
+		id           string
+		expectedCode codes.Code
+	}{
+		{
+			name:         "Без id",
+			id:           "",
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Неизвестный сеанс",
+			id:           "session",
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "Чужой сеанс",
+			id:           sessionIDClaim(st, other.GetToken()),
+			expectedCode: codes.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.AuthClient.RevokeSession(authCtx, &ssov1.RevokeSessionRequest{
+				Id: tt.id,
+			})
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+
+	t.Run("Без access-токена", func(t *testing.T) {
+		_, err := st.AuthClient.ListSessions(c, &ssov1.ListSessionsRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func sessionIDClaim(st *suite.Suite, token string) string {
+	st.Helper()
+
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(st, err)
+
+	sid, ok := claims["sid"].(string)
+	require.True(st, ok)
+
+	return sid
+}
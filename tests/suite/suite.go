@@ -1,17 +1,27 @@
 package suite
 
 import (
+	"bufio"
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"github.com/h1lton/sso-grpc-ntc/internal/config"
+	"github.com/h1lton/sso-grpc-ntc/internal/notifier"
 	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	_ "github.com/mattn/go-sqlite3"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"io/fs"
 	"net"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 )
 
-const grpcHost = "localhost"
+const host = "localhost"
 
 type Suite struct {
 	*testing.T
@@ -48,6 +58,77 @@ func New(t *testing.T) (context.Context, *Suite) {
 	}
 }
 
+// WithAccessToken добавляет access-токен в исходящие метаданные запроса.
+func WithAccessToken(c context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(c, "authorization", "Bearer "+token)
+}
+
+// HTTPURL возвращает адрес HTTP-эндпоинта сервиса.
+func (s *Suite) HTTPURL(path string) string {
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(s.Cfg.HTTP.Port)) + path
+}
+
 func grpcAddress(cfg *config.Config) string {
-	return net.JoinHostPort(grpcHost, strconv.Itoa(cfg.GRPC.Port))
+	return net.JoinHostPort(host, strconv.Itoa(cfg.GRPC.Port))
+}
+
+// Outbox возвращает письма из outbox сервиса, отправленные на адрес to,
+// в порядке отправки.
+//
+// Сервис запускается из корня репозитория, поэтому путь
+// из конфига считается от него.
+func (s *Suite) Outbox(to string) []notifier.Message {
+	s.Helper()
+
+	f, err := os.Open(filepath.Join("..", s.Cfg.Notifier.OutboxPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		s.Fatalf("не удалось открыть outbox: %v", err)
+	}
+	defer f.Close()
+
+	var messages []notifier.Message
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg notifier.Message
+		if err = json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			s.Fatalf("не удалось разобрать письмо: %v", err)
+		}
+
+		if msg.To == to {
+			messages = append(messages, msg)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		s.Fatalf("не удалось прочитать outbox: %v", err)
+	}
+
+	return messages
+}
+
+// PasswordHash возвращает хэш пароля пользователя email из базы сервиса.
+// Путь к базе считается от корня репозитория, как и в Outbox.
+func (s *Suite) PasswordHash(email string) string {
+	s.Helper()
+
+	db, err := sql.Open(
+		"sqlite3",
+		"file:"+filepath.Join("..", s.Cfg.StoragePath)+"?mode=ro",
+	)
+	if err != nil {
+		s.Fatalf("не удалось открыть базу: %v", err)
+	}
+	defer db.Close()
+
+	var hash string
+	err = db.QueryRow("SELECT pass_hash FROM users WHERE email = ?", email).
+		Scan(&hash)
+	if err != nil {
+		s.Fatalf("не удалось получить хэш пароля: %v", err)
+	}
+
+	return hash
 }
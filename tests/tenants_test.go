@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+// tenantID вторая организация из тестовых миграций,
+// tenantAppID — ее приложение.
+const (
+	tenantID    = 2
+	tenantAppID = 3
+)
+
+func TestTenants_EmailUniquePerTenant(t *testing.T) {
+	c, st := suite.New(t)
+
+	email, password := registerUser(c, st)
+	tenantPassword := randomPassword()
+
+	respReg, err := st.AuthClient.Register(c, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: tenantPassword,
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respReg.GetUserId())
+
+	// В одной организации email по-прежнему уникален.
+	_, err = st.AuthClient.Register(c, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: randomPassword(),
+		AppId:    tenantAppID,
+	})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// Вход ищет пользователя в организации приложения.
+	_, err = st.AuthClient.Login(c, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    tenantAppID,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	respLogin, err := st.AuthClient.Login(c, &ssov1.LoginRequest{
+		Email:    email,
+		Password: tenantPassword,
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, tenantID, tenantIDClaim(st, respLogin.GetToken()))
+
+	info, err := st.AuthClient.Introspect(c, &ssov1.IntrospectRequest{
+		Token: respLogin.GetToken(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, respReg.GetUserId(), info.GetUid())
+	assert.EqualValues(t, tenantID, info.GetTenantId())
+
+	defaultToken := login(c, st, email, password).GetToken()
+	assert.EqualValues(t, 1, tenantIDClaim(st, defaultToken))
+}
+
+func TestTenants_Admin(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+
+	name := "tenant-" + gofakeit.UUID()
+
+	respCreate, err := st.AuthClient.CreateTenant(adminCtx, &ssov1.CreateTenantRequest{
+		Name: name,
+	})
+	require.NoError(t, err)
+	tenant := respCreate.GetTenant()
+	require.NotEmpty(t, tenant.GetId())
+	assert.Equal(t, name, tenant.GetName())
+
+	_, err = st.AuthClient.CreateTenant(adminCtx, &ssov1.CreateTenantRequest{
+		Name: name,
+	})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	respList, err := st.AuthClient.ListTenants(adminCtx, &ssov1.ListTenantsRequest{})
+	require.NoError(t, err)
+
+	var names []string
+	for _, tn := range respList.GetTenants() {
+		names = append(names, tn.GetName())
+	}
+	assert.Contains(t, names, name)
+
+	respGet, err := st.AuthClient.GetTenant(adminCtx, &ssov1.GetTenantRequest{
+		Id: tenant.GetId(),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, respGet.GetApps())
+	assert.Zero(t, respGet.GetUserCount())
+
+	_, err = st.AuthClient.Register(c, &ssov1.RegisterRequest{
+		Email:    gofakeit.Email(),
+		Password: randomPassword(),
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+
+	respGet, err = st.AuthClient.GetTenant(adminCtx, &ssov1.GetTenantRequest{
+		Id: tenantID,
+	})
+	require.NoError(t, err)
+	require.Len(t, respGet.GetApps(), 1)
+	assert.EqualValues(t, tenantAppID, respGet.GetApps()[0].GetId())
+	assert.Positive(t, respGet.GetUserCount())
+}
+
+func TestTenants_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+	userCtx := suite.WithAccessToken(c, registerLogin(c, st).GetToken())
+
+	t.Run("Не администратор", func(t *testing.T) {
+		_, err := st.AuthClient.CreateTenant(userCtx, &ssov1.CreateTenantRequest{
+			Name: "tenant-" + gofakeit.UUID(),
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = st.AuthClient.ListTenants(userCtx, &ssov1.ListTenantsRequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Без имени", func(t *testing.T) {
+		_, err := st.AuthClient.CreateTenant(adminCtx, &ssov1.CreateTenantRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Несуществующая организация", func(t *testing.T) {
+		_, err := st.AuthClient.GetTenant(adminCtx, &ssov1.GetTenantRequest{
+			Id: 1 << 40,
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Регистрация в несуществующем приложении", func(t *testing.T) {
+		_, err := st.AuthClient.Register(c, &ssov1.RegisterRequest{
+			Email:    gofakeit.Email(),
+			Password: randomPassword(),
+			AppId:    1 << 20,
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+// tenantIDClaim возвращает утверждение tenant_id токена без проверки подписи.
+func tenantIDClaim(st *suite.Suite, token string) float64 {
+	st.Helper()
+
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(st, err)
+
+	id, ok := claims["tenant_id"].(float64)
+	require.True(st, ok)
+
+	return id
+}
@@ -0,0 +1,320 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/brianvoe/gofakeit/v6"
+	ssov1 "github.com/h1lton/sso-grpc-ntc/pkg/api"
+	"github.com/h1lton/sso-grpc-ntc/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListUsers_Pagination(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+
+	prefix := "dir-" + gofakeit.LetterN(10)
+	emails := registerDirectoryUsers(c, st, prefix, 3)
+	// Без учета регистра -a идет раньше -B, с учетом — наоборот.
+	emails = append(emails, registerDirectoryUsers(c, st, prefix+"-B", 1)...)
+	emails = append(emails, registerDirectoryUsers(c, st, prefix+"-a", 1)...)
+
+	for _, desc := range []bool{false, true} {
+		expected := slices.Clone(emails)
+		slices.SortFunc(expected, func(a, b string) int {
+			return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+		})
+		if desc {
+			slices.Reverse(expected)
+		}
+
+		var got []string
+		pageToken := ""
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, len(emails), "список не заканчивается")
+
+			resp, err := st.AuthClient.ListUsers(adminCtx, &ssov1.ListUsersRequest{
+				EmailPrefix: strings.ToUpper(prefix),
+				Sort:        "email",
+				Desc:        desc,
+				PageSize:    2,
+				PageToken:   pageToken,
+			})
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(resp.GetUsers()), 2)
+
+			for _, u := range resp.GetUsers() {
+				got = append(got, u.GetEmail())
+				assert.NotEmpty(t, u.GetCreatedAt())
+			}
+
+			pageToken = resp.GetNextPageToken()
+			if pageToken == "" {
+				break
+			}
+		}
+
+		assert.Equal(t, expected, got)
+	}
+}
+
+func TestListUsers_Filters(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+
+	prefix := "dir-" + gofakeit.LetterN(10)
+	from := time.Now().Add(-time.Second).Unix()
+	emails := registerDirectoryUsers(c, st, prefix, 3)
+
+	list := func(r *ssov1.ListUsersRequest) []string {
+		t.Helper()
+
+		r.EmailPrefix = prefix
+		resp, err := st.AuthClient.ListUsers(adminCtx, r)
+		require.NoError(t, err)
+
+		var got []string
+		for _, u := range resp.GetUsers() {
+			got = append(got, u.GetEmail())
+		}
+
+		return got
+	}
+
+	assert.Equal(t, emails, list(&ssov1.ListUsersRequest{CreatedFrom: from}))
+	assert.Empty(t, list(&ssov1.ListUsersRequest{CreatedTo: from}))
+
+	// Роль напрямую у первого и через группу у второго.
+	role := createRole(adminCtx, st, "directory:read")
+	group := createGroup(adminCtx, st, 0)
+
+	ids := make([]int64, len(emails))
+	for i, email := range emails {
+		resp, err := st.AuthClient.GetUser(adminCtx, &ssov1.GetUserRequest{Email: email})
+		require.NoError(t, err)
+		ids[i] = resp.GetUser().GetId()
+	}
+
+	_, err := st.AuthClient.AssignRole(adminCtx, &ssov1.AssignRoleRequest{
+		UserId: ids[0],
+		RoleId: role.GetId(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+		GroupId: group.GetId(),
+		UserId:  ids[1],
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.AssignGroupRole(adminCtx, &ssov1.AssignGroupRoleRequest{
+		GroupId: group.GetId(),
+		RoleId:  role.GetId(),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, emails[:2], list(&ssov1.ListUsersRequest{RoleId: role.GetId()}))
+
+	// Подтвержден email только у последнего.
+	_, err = st.AuthClient.VerifyEmail(c, &ssov1.VerifyEmailRequest{
+		Token: outboxToken(st, emails[2]),
+	})
+	require.NoError(t, err)
+
+	verified, unverified := true, false
+	assert.Equal(t, emails[2:], list(&ssov1.ListUsersRequest{Verified: &verified}))
+	assert.Equal(t, emails[:2], list(&ssov1.ListUsersRequest{Verified: &unverified}))
+
+	// Все входили только в тестовое приложение.
+	assert.Equal(t, emails, list(&ssov1.ListUsersRequest{AppId: appID}))
+	assert.Empty(t, list(&ssov1.ListUsersRequest{AppId: verifiedAppID}))
+
+	// Отключен только первый.
+	_, err = st.AuthClient.SetUserDisabled(adminCtx, &ssov1.SetUserDisabledRequest{
+		UserId:   ids[0],
+		Disabled: true,
+	})
+	require.NoError(t, err)
+
+	disabled, enabled := true, false
+	assert.Equal(t, emails[:1], list(&ssov1.ListUsersRequest{Disabled: &disabled}))
+	assert.Equal(t, emails[1:], list(&ssov1.ListUsersRequest{Disabled: &enabled}))
+}
+
+func TestListUsers_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+	userCtx := suite.WithAccessToken(c, registerLogin(c, st).GetToken())
+
+	t.Run("Не администратор", func(t *testing.T) {
+		_, err := st.AuthClient.ListUsers(userCtx, &ssov1.ListUsersRequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Неизвестная сортировка", func(t *testing.T) {
+		_, err := st.AuthClient.ListUsers(adminCtx, &ssov1.ListUsersRequest{
+			Sort: "password",
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Испорченный токен страницы", func(t *testing.T) {
+		_, err := st.AuthClient.ListUsers(adminCtx, &ssov1.ListUsersRequest{
+			PageToken: "не-токен",
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Токен страницы другого порядка", func(t *testing.T) {
+		resp, err := st.AuthClient.ListUsers(adminCtx, &ssov1.ListUsersRequest{
+			PageSize: 1,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.GetNextPageToken())
+
+		_, err = st.AuthClient.ListUsers(adminCtx, &ssov1.ListUsersRequest{
+			Sort:      "email",
+			PageToken: resp.GetNextPageToken(),
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestSetUserDisabled(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+
+	email, password := registerUser(c, st)
+	loginResp := login(c, st, email, password)
+	userCtx := suite.WithAccessToken(c, loginResp.GetToken())
+	key := createAPIKey(userCtx, st)
+
+	uid := introspectUserID(c, st, loginResp.GetToken())
+
+	setDisabled := func(disabled bool) {
+		t.Helper()
+
+		_, err := st.AuthClient.SetUserDisabled(adminCtx, &ssov1.SetUserDisabledRequest{
+			UserId:   uid,
+			Disabled: disabled,
+		})
+		require.NoError(t, err)
+	}
+
+	setDisabled(true)
+
+	resp, err := st.AuthClient.GetUser(adminCtx, &ssov1.GetUserRequest{UserId: uid})
+	require.NoError(t, err)
+	assert.True(t, resp.GetUser().GetDisabled())
+
+	// Верный пароль не пускает в отключенную учетную запись.
+	_, err = st.AuthClient.Login(c, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// А неверный отвечает как обычно, не раскрывая состояния.
+	_, err = st.AuthClient.Login(c, &ssov1.LoginRequest{
+		Email:    email,
+		Password: randomPassword(),
+		AppId:    appID,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Выданные до отключения токены отозваны.
+	_, err = st.AuthClient.Refresh(c, &ssov1.RefreshRequest{
+		RefreshToken: loginResp.GetRefreshToken(),
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.ListSessions(userCtx, &ssov1.ListSessionsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.ExchangeAPIKey(c, &ssov1.ExchangeAPIKeyRequest{
+		ApiKey: key.GetKey(),
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// После включения вход и API-ключ снова работают.
+	setDisabled(false)
+
+	login(c, st, email, password)
+
+	_, err = st.AuthClient.ExchangeAPIKey(c, &ssov1.ExchangeAPIKeyRequest{
+		ApiKey: key.GetKey(),
+	})
+	assert.NoError(t, err)
+}
+
+func TestSetUserDisabled_FailCases(t *testing.T) {
+	c, st := suite.New(t)
+
+	adminCtx := suite.WithAccessToken(c, adminToken(c, st))
+	userCtx := suite.WithAccessToken(c, registerLogin(c, st).GetToken())
+
+	t.Run("Не администратор", func(t *testing.T) {
+		_, err := st.AuthClient.SetUserDisabled(userCtx, &ssov1.SetUserDisabledRequest{
+			UserId:   1,
+			Disabled: true,
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Без пользователя", func(t *testing.T) {
+		_, err := st.AuthClient.SetUserDisabled(adminCtx, &ssov1.SetUserDisabledRequest{
+			Disabled: true,
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Несуществующий пользователь", func(t *testing.T) {
+		_, err := st.AuthClient.SetUserDisabled(adminCtx, &ssov1.SetUserDisabledRequest{
+			UserId:   1 << 40,
+			Disabled: true,
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+// registerDirectoryUsers регистрирует n пользователей с email,
+// начинающимся с prefix, входит каждым в тестовое приложение
+// и возвращает их email в порядке регистрации.
+func registerDirectoryUsers(
+	c context.Context,
+	st *suite.Suite,
+	prefix string,
+	n int,
+) []string {
+	st.Helper()
+
+	emails := make([]string, 0, n)
+	for i := range n {
+		email := fmt.Sprintf("%s-%d@example.com", prefix, i)
+		password := randomPassword()
+
+		_, err := st.AuthClient.Register(c, &ssov1.RegisterRequest{
+			Email:    email,
+			Password: password,
+		})
+		require.NoError(st, err)
+
+		login(c, st, email, password)
+
+		emails = append(emails, email)
+	}
+
+	return emails
+}